@@ -0,0 +1,71 @@
+// Package capture implements the file formats of recorded telemetry feeds.
+//
+// The legacy format is a plain sequence of records, each a 4 bytes big-endian length
+// followed by the payload. The versioned format starts with a file header and keeps the
+// metadata of every Feed next to its payload:
+//
+//	file header: | magic "MDTCAP" (6) | version (2) | metadata length (4) | metadata JSON |
+//	record:      | record length (4) | received at, unix nanoseconds (8) | flags (1) |
+//	             | network | address | transport | encoding | framing | source | peer subject | error |
+//	             | payload length (4) | payload |
+//
+// Strings are prefixed with a 2 bytes length, all integers are big-endian. Both formats may be
+// gzip compressed, NewReader detects compression and format on its own.
+package capture
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Format identifies the layout of a capture file.
+type Format string
+
+const (
+	FormatLegacy Format = "legacy"
+	FormatV1     Format = "v1"
+)
+
+const (
+	// Magic opens every file in the versioned format. Read as the length of a legacy record it
+	// would be over 1 GB, so the formats cannot be confused.
+	Magic = "MDTCAP"
+	// Version is the version of the versioned format written by this package.
+	Version uint16 = 1
+	// MaxRecordLength limits the size of a record accepted by Reader.
+	MaxRecordLength = 1024 * 1024 * 256
+
+	fileHeaderLength = len(Magic) + 2 + 4
+	maxMetadataLen   = 1024 * 1024
+	maxStringLen     = 1<<16 - 1
+)
+
+const (
+	flagErr byte = 1 << iota
+	flagAddr
+)
+
+var (
+	ErrBadMagic           = errors.New("not a versioned capture file")
+	ErrUnsupportedVersion = errors.New("unsupported capture format version")
+	ErrRecordTooLong      = errors.New("capture record exceeds maximum length")
+	// ErrCorruptRecord is returned for a record which cannot be decoded although its length
+	// is intact, reading may continue with the next record.
+	ErrCorruptRecord = errors.New("corrupted capture record")
+)
+
+// Header is the file header of the versioned format.
+type Header struct {
+	Version  uint16            `json:"-"`
+	Created  time.Time         `json:"created"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+func (f Format) Validate() error {
+	switch f {
+	case FormatLegacy, FormatV1:
+		return nil
+	}
+	return fmt.Errorf("unknown capture format %q", f)
+}
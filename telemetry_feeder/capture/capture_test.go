@@ -0,0 +1,156 @@
+package capture
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+)
+
+func TestRoundTripV1(t *testing.T) {
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	in := []*feeder.Feed{
+		{
+			ProducerAddr: &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 57500},
+			TelemetryMsg: []byte("payload"),
+			Transport:    feeder.TransportGRPC,
+			Encoding:     feeder.EncodingGPB,
+			Framing:      feeder.FramingNone,
+			Source:       "grpc",
+			PeerSubject:  "CN=router1",
+			ReceivedAt:   created.Add(time.Second),
+		},
+		{
+			Err:       errors.New("connection reset"),
+			Transport: feeder.TransportUDP,
+		},
+	}
+	for _, compress := range []bool{false, true} {
+		var buf bytes.Buffer
+		var w io.Writer = &buf
+		var gz *gzip.Writer
+		if compress {
+			gz = gzip.NewWriter(&buf)
+			w = gz
+		}
+		cw, err := NewWriter(w, FormatV1, Header{Created: created, Metadata: map[string]string{"site": "lab"}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, f := range in {
+			if _, err := cw.Write(f); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		if gz != nil {
+			gz.Close()
+		}
+
+		r, err := NewReader(&buf)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if r.Format() != FormatV1 || r.Compressed() != compress {
+			t.Fatalf("format detection mismatch: %s compressed %t", r.Format(), r.Compressed())
+		}
+		if h := r.Header(); h.Version != Version || !h.Created.Equal(created) || h.Metadata["site"] != "lab" {
+			t.Fatalf("unexpected header %+v", h)
+		}
+		got, err := r.Next()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := in[0]
+		if got.ProducerAddr.Network() != "tcp" || got.ProducerAddr.String() != "192.0.2.1:57500" ||
+			string(got.TelemetryMsg) != "payload" || got.Transport != want.Transport || got.Encoding != want.Encoding ||
+			got.Framing != want.Framing || got.Source != want.Source || got.PeerSubject != want.PeerSubject ||
+			!got.ReceivedAt.Equal(want.ReceivedAt) || got.Err != nil {
+			t.Fatalf("record mismatch: %+v", got)
+		}
+		got, err = r.Next()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.Err == nil || got.Err.Error() != "connection reset" || got.ProducerAddr != nil || got.TelemetryMsg != nil || !got.ReceivedAt.IsZero() {
+			t.Fatalf("error record mismatch: %+v", got)
+		}
+		if _, err := r.Next(); err != io.EOF {
+			t.Fatalf("expected io.EOF, got %v", err)
+		}
+	}
+}
+
+func TestLegacyFormat(t *testing.T) {
+	var buf bytes.Buffer
+	cw, err := NewWriter(&buf, FormatLegacy, Header{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cw.Write(&feeder.Feed{TelemetryMsg: []byte("one"), Transport: feeder.TransportUDP})
+	cw.Write(&feeder.Feed{TelemetryMsg: []byte("two")})
+	if want := []byte{0, 0, 0, 3, 'o', 'n', 'e', 0, 0, 0, 3, 't', 'w', 'o'}; !bytes.Equal(buf.Bytes(), want) {
+		t.Fatalf("legacy encoding mismatch: %v", buf.Bytes())
+	}
+
+	r, err := NewReader(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.Format() != FormatLegacy {
+		t.Fatalf("expected legacy format, got %s", r.Format())
+	}
+	for _, want := range []string{"one", "two"} {
+		f, err := r.Next()
+		if err != nil || string(f.TelemetryMsg) != want {
+			t.Fatalf("expected %q, got %+v %v", want, f, err)
+		}
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	// Truncated record.
+	r, _ := NewReader(bytes.NewReader([]byte{0, 0, 0, 5, 'a'}))
+	if _, err := r.Next(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+	// Record length over the limit.
+	b := binary.BigEndian.AppendUint32(nil, MaxRecordLength+1)
+	r, _ = NewReader(bytes.NewReader(b))
+	if _, err := r.Next(); !errors.Is(err, ErrRecordTooLong) {
+		t.Fatalf("expected ErrRecordTooLong, got %v", err)
+	}
+	// Future version.
+	b = append([]byte(Magic), 0, 9, 0, 0, 0, 0)
+	if _, err := NewReader(bytes.NewReader(b)); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("expected ErrUnsupportedVersion, got %v", err)
+	}
+	// Corrupted record of the versioned format is skipped.
+	var buf bytes.Buffer
+	w, _ := NewWriter(&buf, FormatV1, Header{})
+	buf.Write([]byte{0, 0, 0, 3, 'a', 'b', 'c'})
+	w.Write(&feeder.Feed{TelemetryMsg: []byte("next")})
+	r, _ = NewReader(&buf)
+	if _, err := r.Next(); !errors.Is(err, ErrCorruptRecord) {
+		t.Fatalf("expected ErrCorruptRecord, got %v", err)
+	}
+	if f, err := r.Next(); err != nil || string(f.TelemetryMsg) != "next" {
+		t.Fatalf("expected record after the corrupted one, got %+v %v", f, err)
+	}
+	// Empty file is an empty legacy capture.
+	r, err := NewReader(bytes.NewReader(nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+}
@@ -0,0 +1,188 @@
+package capture
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+)
+
+const readBufSize = 1024 * 64
+
+// Reader decodes feeds from a capture in either format, optionally gzip compressed.
+type Reader struct {
+	r          *bufio.Reader
+	gz         *gzip.Reader
+	format     Format
+	header     Header
+	compressed bool
+}
+
+// NewReader detects compression and format of the capture and reads the file header of the
+// versioned format.
+func NewReader(r io.Reader) (*Reader, error) {
+	cr := &Reader{
+		r:      bufio.NewReaderSize(r, readBufSize),
+		format: FormatLegacy,
+	}
+	magic, err := cr.r.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(cr.r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open gzip compressed capture: %w", err)
+		}
+		cr.gz = gz
+		cr.compressed = true
+		cr.r = bufio.NewReaderSize(gz, readBufSize)
+	}
+	magic, err = cr.r.Peek(len(Magic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if string(magic) == Magic {
+		cr.format = FormatV1
+		if err := cr.readHeader(); err != nil {
+			return nil, err
+		}
+	}
+
+	return cr, nil
+}
+
+func (r *Reader) readHeader() error {
+	b := make([]byte, fileHeaderLength)
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		return fmt.Errorf("failed to read capture file header: %w", err)
+	}
+	version := binary.BigEndian.Uint16(b[len(Magic):])
+	if version == 0 || version > Version {
+		return fmt.Errorf("%w %d", ErrUnsupportedVersion, version)
+	}
+	l := binary.BigEndian.Uint32(b[len(Magic)+2:])
+	if l > maxMetadataLen {
+		return fmt.Errorf("capture file metadata of %d bytes exceeds maximum %d", l, maxMetadataLen)
+	}
+	metadata := make([]byte, l)
+	if _, err := io.ReadFull(r.r, metadata); err != nil {
+		return fmt.Errorf("failed to read capture file metadata: %w", noEOF(err))
+	}
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &r.header); err != nil {
+			return fmt.Errorf("failed to decode capture file metadata: %w", err)
+		}
+	}
+	r.header.Version = version
+
+	return nil
+}
+
+func (r *Reader) Format() Format {
+	return r.format
+}
+
+// Header returns the file header, it is empty for the legacy format.
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+func (r *Reader) Compressed() bool {
+	return r.compressed
+}
+
+// noEOF turns io.EOF in the middle of a record into io.ErrUnexpectedEOF.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Next returns the next feed, io.EOF is returned at the end of the capture and
+// io.ErrUnexpectedEOF when the capture ends in the middle of a record. ErrCorruptRecord is
+// returned for a record of the versioned format which cannot be decoded, the next call reads
+// the following record.
+func (r *Reader) Next() (*feeder.Feed, error) {
+	lb := make([]byte, 4)
+	if _, err := io.ReadFull(r.r, lb); err != nil {
+		return nil, err
+	}
+	l := binary.BigEndian.Uint32(lb)
+	if l > MaxRecordLength {
+		return nil, fmt.Errorf("record of %d bytes: %w", l, ErrRecordTooLong)
+	}
+	b := make([]byte, l)
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		return nil, noEOF(err)
+	}
+	if r.format == FormatLegacy {
+		return &feeder.Feed{TelemetryMsg: b}, nil
+	}
+	return decodeRecord(b)
+}
+
+// Close releases the decompressor, the underlying reader is not closed.
+func (r *Reader) Close() error {
+	if r.gz != nil {
+		return r.gz.Close()
+	}
+	return nil
+}
+
+func decodeRecord(b []byte) (*feeder.Feed, error) {
+	corrupted := ErrCorruptRecord
+	if len(b) < 9 {
+		return nil, corrupted
+	}
+	f := &feeder.Feed{}
+	if ts := int64(binary.BigEndian.Uint64(b)); ts != 0 {
+		f.ReceivedAt = time.Unix(0, ts)
+	}
+	flags := b[8]
+	b = b[9:]
+	strs := make([]string, 8)
+	for i := range strs {
+		if len(b) < 2 {
+			return nil, corrupted
+		}
+		l := int(binary.BigEndian.Uint16(b))
+		b = b[2:]
+		if l > len(b) {
+			return nil, corrupted
+		}
+		strs[i] = string(b[:l])
+		b = b[l:]
+	}
+	if len(b) < 4 {
+		return nil, corrupted
+	}
+	l := binary.BigEndian.Uint32(b)
+	b = b[4:]
+	if uint64(l) != uint64(len(b)) {
+		return nil, corrupted
+	}
+	if flags&flagAddr != 0 {
+		f.ProducerAddr = &feeder.StoredAddr{Net: strs[0], Address: strs[1]}
+	}
+	f.Transport = feeder.Transport(strs[2])
+	f.Encoding = feeder.PayloadEncoding(strs[3])
+	f.Framing = feeder.Framing(strs[4])
+	f.Source = strs[5]
+	f.PeerSubject = strs[6]
+	if flags&flagErr != 0 {
+		f.Err = errors.New(strs[7])
+	}
+	if l > 0 {
+		f.TelemetryMsg = b
+	}
+
+	return f, nil
+}
@@ -0,0 +1,98 @@
+package capture
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+)
+
+// Writer encodes feeds into one of the capture formats.
+type Writer struct {
+	w      io.Writer
+	format Format
+	buf    []byte
+}
+
+// NewWriter returns Writer of the given format, the versioned format header is written
+// immediately.
+func NewWriter(w io.Writer, format Format, h Header) (*Writer, error) {
+	if err := format.Validate(); err != nil {
+		return nil, err
+	}
+	cw := &Writer{
+		w:      w,
+		format: format,
+	}
+	if format == FormatV1 {
+		if err := cw.writeHeader(h); err != nil {
+			return nil, err
+		}
+	}
+
+	return cw, nil
+}
+
+func (w *Writer) writeHeader(h Header) error {
+	metadata, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	b := make([]byte, 0, fileHeaderLength+len(metadata))
+	b = append(b, Magic...)
+	b = binary.BigEndian.AppendUint16(b, Version)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(metadata)))
+	b = append(b, metadata...)
+	_, err = w.w.Write(b)
+
+	return err
+}
+
+// Write appends the feed, only the payload is kept in the legacy format. It returns the
+// number of bytes written.
+func (w *Writer) Write(f *feeder.Feed) (int, error) {
+	if len(f.TelemetryMsg) > MaxRecordLength {
+		return 0, fmt.Errorf("record of %d bytes: %w", len(f.TelemetryMsg), ErrRecordTooLong)
+	}
+	if w.format == FormatLegacy {
+		w.buf = binary.BigEndian.AppendUint32(w.buf[:0], uint32(len(f.TelemetryMsg)))
+		w.buf = append(w.buf, f.TelemetryMsg...)
+		return w.w.Write(w.buf)
+	}
+	var flags byte
+	var network, address, errStr string
+	if f.ProducerAddr != nil {
+		flags |= flagAddr
+		network, address = f.ProducerAddr.Network(), f.ProducerAddr.String()
+	}
+	if f.Err != nil {
+		flags |= flagErr
+		errStr = f.Err.Error()
+	}
+	var receivedAt int64
+	if !f.ReceivedAt.IsZero() {
+		receivedAt = f.ReceivedAt.UnixNano()
+	}
+	// Reserve the record length, it is set once the record is complete.
+	b := append(w.buf[:0], 0, 0, 0, 0)
+	b = binary.BigEndian.AppendUint64(b, uint64(receivedAt))
+	b = append(b, flags)
+	for _, s := range []string{
+		network, address, string(f.Transport), string(f.Encoding), string(f.Framing),
+		f.Source, f.PeerSubject, errStr,
+	} {
+		if len(s) > maxStringLen {
+			s = s[:maxStringLen]
+		}
+		b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+		b = append(b, s...)
+	}
+	b = binary.BigEndian.AppendUint32(b, uint32(len(f.TelemetryMsg)))
+	b = append(b, f.TelemetryMsg...)
+	binary.BigEndian.PutUint32(b, uint32(len(b)-4))
+	w.buf = b
+
+	return w.w.Write(b)
+}
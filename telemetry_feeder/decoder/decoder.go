@@ -0,0 +1,214 @@
+package decoder
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+	"github.com/sbezverk/tools/telemetry_feeder/proto/telemetry"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	ErrUnsupportedEncoding = errors.New("unsupported payload encoding")
+	ErrNoData              = errors.New("telemetry message carries neither data_gpbkv nor data_gpb")
+)
+
+// Format identifies which of the two MDT GPB data forms a message carried.
+type Format string
+
+const (
+	// FormatGPBKV is the self-describing form, rows are TelemetryField trees.
+	FormatGPBKV Format = "gpbkv"
+	// FormatGPB is the compact form, rows carry serialized keys and content messages
+	// which require the schema of the encoding path to be decoded.
+	FormatGPB Format = "gpb"
+)
+
+// Row is a single entry of the telemetry message. For FormatGPB Keys and Content hold the
+// serialized schema messages, for FormatGPBKV Fields holds the row tree, usually with "keys"
+// and "content" children.
+type Row struct {
+	Timestamp time.Time
+	Delete    bool
+	Keys      []byte
+	Content   []byte
+	Fields    *telemetry.TelemetryField
+}
+
+type Record struct {
+	ProducerAddr        net.Addr
+	Transport           feeder.Transport
+	NodeID              string
+	Subscription        string
+	EncodingPath        string
+	CollectionID        uint64
+	CollectionStartTime time.Time
+	CollectionEndTime   time.Time
+	MsgTimestamp        time.Time
+	ReceivedAt          time.Time
+	Format              Format
+	Rows                []Row
+	// Telemetry is the unmarshalled message the record was built from.
+	Telemetry *telemetry.Telemetry
+	Err       error
+}
+
+// Decode unmarshals the GPB payload of the feed into a Record. Feeds carrying an error, or
+// a payload in an encoding other than GPB are rejected.
+func Decode(f *feeder.Feed) (*Record, error) {
+	if f == nil {
+		return nil, fmt.Errorf("nil feed")
+	}
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	if f.Encoding != feeder.EncodingGPB {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedEncoding, f.Encoding)
+	}
+	msg := &telemetry.Telemetry{}
+	if err := proto.Unmarshal(f.TelemetryMsg, msg); err != nil {
+		return nil, fmt.Errorf("%w: %v", feeder.ErrUnmarshalTelemetryMsg, err)
+	}
+	r, err := FromTelemetry(msg)
+	if err != nil {
+		return nil, err
+	}
+	r.ProducerAddr = f.ProducerAddr
+	r.Transport = f.Transport
+	r.ReceivedAt = f.ReceivedAt
+
+	return r, nil
+}
+
+// FromTelemetry builds a Record from an already unmarshalled telemetry message.
+func FromTelemetry(msg *telemetry.Telemetry) (*Record, error) {
+	r := &Record{
+		NodeID:              msg.GetNodeIdStr(),
+		Subscription:        msg.GetSubscriptionIdStr(),
+		EncodingPath:        msg.GetEncodingPath(),
+		CollectionID:        msg.GetCollectionId(),
+		CollectionStartTime: MillisToTime(msg.GetCollectionStartTime()),
+		CollectionEndTime:   MillisToTime(msg.GetCollectionEndTime()),
+		MsgTimestamp:        MillisToTime(msg.GetMsgTimestamp()),
+		Telemetry:           msg,
+	}
+	switch {
+	case len(msg.GetDataGpbkv()) != 0:
+		r.Format = FormatGPBKV
+		r.Rows = make([]Row, 0, len(msg.GetDataGpbkv()))
+		for _, field := range msg.GetDataGpbkv() {
+			r.Rows = append(r.Rows, Row{
+				Timestamp: MillisToTime(field.GetTimestamp()),
+				Fields:    field,
+			})
+		}
+	case msg.GetDataGpb() != nil:
+		r.Format = FormatGPB
+		r.Rows = make([]Row, 0, len(msg.GetDataGpb().GetRow()))
+		for _, row := range msg.GetDataGpb().GetRow() {
+			r.Rows = append(r.Rows, Row{
+				Timestamp: MillisToTime(row.GetTimestamp()),
+				Delete:    row.GetDelete(),
+				Keys:      row.GetKeys(),
+				Content:   row.GetContent(),
+			})
+		}
+	default:
+		// Messages closing a collection may legitimately carry no rows, only the
+		// collection end time.
+		if msg.GetCollectionEndTime() == 0 {
+			return nil, ErrNoData
+		}
+	}
+
+	return r, nil
+}
+
+// MillisToTime converts milliseconds since the epoch, as used by MDT, to time.Time, 0 maps to
+// the zero time.
+func MillisToTime(ms uint64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(int64(ms)).UTC()
+}
+
+// Decoder is a pipeline stage turning the feed of any feeder.Feeder into a stream of records.
+// Feeds which carry errors or fail to decode are passed on as records with Err set. The decoder
+// consumes the feeds, it releases them once decoded since records do not refer to the payload.
+// Feeds of a feeder using a buffer pool must therefore not be shared with other consumers, as
+// by mux_feeder.Fanout.
+type Decoder interface {
+	GetRecords() chan *Record
+	Stop()
+}
+
+var _ Decoder = &decoder{}
+
+const recordQueueCapacity = 1024
+
+type decoder struct {
+	in      chan *feeder.Feed
+	records chan *Record
+	stopCh  chan struct{}
+	once    sync.Once
+}
+
+func (d *decoder) GetRecords() chan *Record {
+	return d.records
+}
+
+func (d *decoder) Stop() {
+	d.once.Do(func() {
+		close(d.stopCh)
+	})
+}
+
+// New starts decoding feeds read from the in channel, the records channel is closed when in
+// is closed or Stop is called.
+func New(in chan *feeder.Feed) Decoder {
+	d := &decoder{
+		in:      in,
+		records: make(chan *Record, recordQueueCapacity),
+		stopCh:  make(chan struct{}),
+	}
+	go d.worker()
+
+	return d
+}
+
+func (d *decoder) worker() {
+	defer close(d.records)
+	for {
+		select {
+		case <-d.stopCh:
+			return
+		case f, ok := <-d.in:
+			if !ok {
+				return
+			}
+			if f == nil {
+				continue
+			}
+			r, err := Decode(f)
+			if err != nil {
+				r = &Record{
+					ProducerAddr: f.ProducerAddr,
+					Transport:    f.Transport,
+					ReceivedAt:   f.ReceivedAt,
+					Err:          err,
+				}
+			}
+			f.Release()
+			select {
+			case <-d.stopCh:
+				return
+			case d.records <- r:
+			}
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package decoder
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+	"github.com/sbezverk/tools/telemetry_feeder/proto/telemetry"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestDecodeGPBKV(t *testing.T) {
+	msg := &telemetry.Telemetry{
+		NodeId:              &telemetry.Telemetry_NodeIdStr{NodeIdStr: "xrv9k-1"},
+		Subscription:        &telemetry.Telemetry_SubscriptionIdStr{SubscriptionIdStr: "rib"},
+		EncodingPath:        "Cisco-IOS-XR-ip-rib-ipv4-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/routes/route",
+		CollectionId:        42,
+		CollectionStartTime: 1700000000000,
+		MsgTimestamp:        1700000000123,
+		DataGpbkv: []*telemetry.TelemetryField{
+			{
+				Timestamp: 1700000000100,
+				Fields: []*telemetry.TelemetryField{
+					{Name: "keys", Fields: []*telemetry.TelemetryField{
+						{Name: "prefix", ValueByType: &telemetry.TelemetryField_StringValue{StringValue: "10.0.0.0"}},
+					}},
+					{Name: "content"},
+				},
+			},
+		},
+	}
+	r := decodeTelemetry(t, msg)
+
+	if r.NodeID != "xrv9k-1" || r.Subscription != "rib" || r.EncodingPath != msg.EncodingPath {
+		t.Fatalf("unexpected record header %+v", r)
+	}
+	if r.CollectionID != 42 {
+		t.Fatalf("collection id mismatch, want 42 got %d", r.CollectionID)
+	}
+	if !r.CollectionStartTime.Equal(time.UnixMilli(1700000000000)) || !r.MsgTimestamp.Equal(time.UnixMilli(1700000000123)) {
+		t.Fatalf("unexpected timestamps start %v msg %v", r.CollectionStartTime, r.MsgTimestamp)
+	}
+	if !r.CollectionEndTime.IsZero() {
+		t.Fatalf("expected zero collection end time, got %v", r.CollectionEndTime)
+	}
+	if r.Format != FormatGPBKV {
+		t.Fatalf("format mismatch, want %q got %q", FormatGPBKV, r.Format)
+	}
+	if len(r.Rows) != 1 {
+		t.Fatalf("expected 1 row, got %d", len(r.Rows))
+	}
+	if r.Rows[0].Fields == nil || len(r.Rows[0].Fields.GetFields()) != 2 {
+		t.Fatalf("unexpected row fields %+v", r.Rows[0].Fields)
+	}
+	if !r.Rows[0].Timestamp.Equal(time.UnixMilli(1700000000100)) {
+		t.Fatalf("row timestamp mismatch, got %v", r.Rows[0].Timestamp)
+	}
+}
+
+func TestDecodeGPBCompact(t *testing.T) {
+	msg := &telemetry.Telemetry{
+		NodeId:       &telemetry.Telemetry_NodeIdStr{NodeIdStr: "xrv9k-1"},
+		EncodingPath: "Cisco-IOS-XR-ip-rib-ipv4-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/routes/route",
+		DataGpb: &telemetry.TelemetryGPBTable{
+			Row: []*telemetry.TelemetryRowGPB{
+				{Timestamp: 1700000000100, Keys: []byte{0x0a, 0x01, 'a'}, Content: []byte{0x10, 0x01}},
+				{Timestamp: 1700000000200, Delete: true, Keys: []byte{0x0a, 0x01, 'b'}},
+			},
+		},
+	}
+	r := decodeTelemetry(t, msg)
+
+	if r.Format != FormatGPB {
+		t.Fatalf("format mismatch, want %q got %q", FormatGPB, r.Format)
+	}
+	if len(r.Rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(r.Rows))
+	}
+	if !bytes.Equal(r.Rows[0].Keys, []byte{0x0a, 0x01, 'a'}) || !bytes.Equal(r.Rows[0].Content, []byte{0x10, 0x01}) || r.Rows[0].Delete {
+		t.Fatalf("unexpected first row %+v", r.Rows[0])
+	}
+	if !r.Rows[1].Delete {
+		t.Fatal("expected second row to be a delete")
+	}
+}
+
+func TestDecodeRejectsFeeds(t *testing.T) {
+	feedErr := errors.New("connection reset")
+	if _, err := Decode(&feeder.Feed{Err: feedErr}); !errors.Is(err, feedErr) {
+		t.Fatalf("expected feed error, got %v", err)
+	}
+	if _, err := Decode(&feeder.Feed{Encoding: feeder.EncodingJSON, TelemetryMsg: []byte(`{}`)}); !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Fatalf("expected ErrUnsupportedEncoding, got %v", err)
+	}
+	if _, err := Decode(&feeder.Feed{Encoding: feeder.EncodingGPB, TelemetryMsg: []byte{0xff, 0xff}}); !errors.Is(err, feeder.ErrUnmarshalTelemetryMsg) {
+		t.Fatalf("expected ErrUnmarshalTelemetryMsg, got %v", err)
+	}
+	b, _ := proto.Marshal(&telemetry.Telemetry{EncodingPath: "empty"})
+	if _, err := Decode(&feeder.Feed{Encoding: feeder.EncodingGPB, TelemetryMsg: b}); !errors.Is(err, ErrNoData) {
+		t.Fatalf("expected ErrNoData, got %v", err)
+	}
+}
+
+func TestDecoderStage(t *testing.T) {
+	in := make(chan *feeder.Feed, 2)
+	d := New(in)
+	defer d.Stop()
+
+	producer := &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 5000}
+	b, err := proto.Marshal(&telemetry.Telemetry{
+		EncodingPath: "path",
+		DataGpb:      &telemetry.TelemetryGPBTable{Row: []*telemetry.TelemetryRowGPB{{Content: []byte{0x08, 0x01}}}},
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal telemetry: %v", err)
+	}
+	pool := feeder.NewBufferPool()
+	pooled := &feeder.Feed{ProducerAddr: producer, Transport: feeder.TransportUDP, Encoding: feeder.EncodingGPB}
+	pooled.CopyTelemetryMsg(b, pool)
+	in <- pooled
+	in <- &feeder.Feed{ProducerAddr: producer, Err: errors.New("boom")}
+	close(in)
+
+	var got []*Record
+	for r := range d.GetRecords() {
+		got = append(got, r)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(got))
+	}
+	if got[0].Err != nil || got[0].EncodingPath != "path" || got[0].ProducerAddr != producer || got[0].Transport != feeder.TransportUDP {
+		t.Fatalf("unexpected first record %+v", got[0])
+	}
+	if got[1].Err == nil || got[1].ProducerAddr != producer {
+		t.Fatalf("expected error record, got %+v", got[1])
+	}
+	// The payload buffer went back to the pool, the record does not depend on it.
+	if s := pool.Stats(); s.Puts != 1 || got[0].Rows[0].Content[1] != 0x01 {
+		t.Fatalf("expected the decoded feed to be released, got %+v", s)
+	}
+}
+
+func decodeTelemetry(t *testing.T, msg *telemetry.Telemetry) *Record {
+	t.Helper()
+
+	b, err := proto.Marshal(msg)
+	if err != nil {
+		t.Fatalf("failed to marshal telemetry: %v", err)
+	}
+	r, err := Decode(&feeder.Feed{Encoding: feeder.EncodingGPB, TelemetryMsg: b})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return r
+}
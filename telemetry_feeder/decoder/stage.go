@@ -0,0 +1,110 @@
+package decoder
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+const eventQueueCapacity = 1024
+
+// StageConfig customizes the Stage created by NewStage.
+type StageConfig struct {
+	// Lossless holds back the records while the events channel is full, by default events
+	// which do not fit are dropped so a slow events consumer never stalls the records.
+	Lossless bool
+	// Dropped counts the dropped events when set.
+	Dropped *atomic.Int64
+}
+
+// Stage is a pipeline stage passing through every record read from its input while apply
+// turns it into events of type E.
+type Stage[E any] struct {
+	apply   func(*Record) []E
+	cfg     StageConfig
+	in      chan *Record
+	records chan *Record
+	events  chan E
+	stopCh  chan struct{}
+	once    sync.Once
+}
+
+// NewStage starts applying the records read from the in channel, nil records are skipped. The
+// records and events channels are closed when in is closed or Stop is called.
+func NewStage[E any](in chan *Record, apply func(*Record) []E, cfg StageConfig) *Stage[E] {
+	s := &Stage[E]{
+		apply:   apply,
+		cfg:     cfg,
+		in:      in,
+		records: make(chan *Record, recordQueueCapacity),
+		events:  make(chan E, eventQueueCapacity),
+		stopCh:  make(chan struct{}),
+	}
+	go s.worker()
+
+	return s
+}
+
+func (s *Stage[E]) GetRecords() chan *Record {
+	return s.records
+}
+
+func (s *Stage[E]) Events() <-chan E {
+	return s.events
+}
+
+func (s *Stage[E]) Stop() {
+	s.once.Do(func() {
+		close(s.stopCh)
+	})
+}
+
+func (s *Stage[E]) worker() {
+	defer func() {
+		close(s.records)
+		close(s.events)
+	}()
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case r, ok := <-s.in:
+			if !ok {
+				return
+			}
+			if r == nil {
+				continue
+			}
+			for _, e := range s.apply(r) {
+				if !s.emit(e) {
+					return
+				}
+			}
+			select {
+			case <-s.stopCh:
+				return
+			case s.records <- r:
+			}
+		}
+	}
+}
+
+// emit sends the event, false is returned when the stage is stopped while waiting for room.
+func (s *Stage[E]) emit(e E) bool {
+	if s.cfg.Lossless {
+		select {
+		case s.events <- e:
+			return true
+		case <-s.stopCh:
+			return false
+		}
+	}
+	select {
+	case s.events <- e:
+	default:
+		if s.cfg.Dropped != nil {
+			s.cfg.Dropped.Add(1)
+		}
+	}
+
+	return true
+}
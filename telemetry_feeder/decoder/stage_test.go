@@ -0,0 +1,94 @@
+package decoder
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// collections reports the collection id of every record, twice for ended collections.
+func collections(r *Record) []uint64 {
+	if !r.CollectionEndTime.IsZero() {
+		return []uint64{r.CollectionID, r.CollectionID}
+	}
+	return []uint64{r.CollectionID}
+}
+
+func TestStage(t *testing.T) {
+	in := make(chan *Record, 3)
+	var dropped atomic.Int64
+	s := NewStage(in, collections, StageConfig{Dropped: &dropped})
+	defer s.Stop()
+	in <- &Record{CollectionID: 1}
+	in <- nil
+	in <- &Record{CollectionID: 2, CollectionEndTime: time.Now()}
+	close(in)
+
+	n := 0
+	for range s.GetRecords() {
+		n++
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 records passed through, got %d", n)
+	}
+	var events []uint64
+	for e := range s.Events() {
+		events = append(events, e)
+	}
+	if got := fmt.Sprint(events); got != "[1 2 2]" || dropped.Load() != 0 {
+		t.Fatalf("unexpected events %s, %d dropped", got, dropped.Load())
+	}
+}
+
+func TestStageEventsOverflow(t *testing.T) {
+	for _, lossless := range []bool{false, true} {
+		t.Run(fmt.Sprintf("lossless=%t", lossless), func(t *testing.T) {
+			in := make(chan *Record)
+			var dropped atomic.Int64
+			s := NewStage(in, collections, StageConfig{Lossless: lossless, Dropped: &dropped})
+			defer s.Stop()
+			// The events are read only after all records went through.
+			total := eventQueueCapacity + 10
+			go func() {
+				for i := 0; i < total; i++ {
+					in <- &Record{CollectionID: uint64(i)}
+				}
+				close(in)
+			}()
+			records := make(chan int)
+			go func() {
+				n := 0
+				for range s.GetRecords() {
+					n++
+				}
+				records <- n
+			}()
+			var n int
+			if lossless {
+				// The records wait for the events to be read.
+				select {
+				case n = <-records:
+					t.Fatalf("records went through while the events channel was full")
+				case <-time.After(50 * time.Millisecond):
+				}
+			} else {
+				n = <-records
+			}
+			events := 0
+			for range s.Events() {
+				events++
+			}
+			if lossless {
+				n = <-records
+			}
+			want := int64(0)
+			if !lossless {
+				want = 10
+			}
+			if n != total || events != total-int(want) || dropped.Load() != want {
+				t.Fatalf("expected %d records and %d events, got %d records, %d events and %d dropped", total, total-int(want), n, events, dropped.Load())
+			}
+		})
+	}
+}
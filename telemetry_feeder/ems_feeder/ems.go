@@ -0,0 +1,401 @@
+package ems_feeder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+	emsgetproto "github.com/sbezverk/tools/xr_getproto/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	MaxRcvMsgSize     = 1024 * 1024 * 4
+	feedQueueCapacity = 1024 * 10
+)
+
+// ErrSubscription is wrapped by the error items of the errors reported by a router in
+// CreateSubsReply, such as a subscription name which is not configured.
+var ErrSubscription = errors.New("router reported subscription error")
+
+// routerAddr is the producer address of a router, the router as it is dialed.
+type routerAddr string
+
+func (a routerAddr) Network() string { return "tcp" }
+func (a routerAddr) String() string  { return string(a) }
+
+// passCredential sends the username and password metadata EMS authenticates the RPC with.
+type passCredential struct {
+	username string
+	password string
+	insecure bool
+}
+
+func (p passCredential) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{
+		"username": p.username,
+		"password": p.password,
+	}, nil
+}
+
+func (p passCredential) RequireTransportSecurity() bool {
+	return !p.insecure
+}
+
+// Stats are the EMS feeder counters returned by GetStatsJson.
+type Stats struct {
+	feeder.StatsSnapshot
+	RoutersTotal            int64 `json:"routers_total"`
+	RoutersConnected        int64 `json:"routers_connected"`
+	SubscribeAttemptsTotal  int64 `json:"subscribe_attempts_total"`
+	SubscriptionErrorsTotal int64 `json:"subscription_errors_total"`
+}
+
+type emsFeeder struct {
+	routers                     []string
+	subscriptions               []string
+	encoding                    feeder.PayloadEncoding
+	dialOpts                    []grpc.DialOption
+	opts                        options
+	ctx                         context.Context
+	cancel                      context.CancelFunc
+	stopCh                      chan struct{}
+	stopOnce                    sync.Once
+	wg                          sync.WaitGroup
+	reqID                       atomic.Int64
+	queue                       *feeder.FeedQueue
+	events                      *feeder.EventQueue
+	producers                   *feeder.ProducerTracker
+	logger                      feeder.Logger
+	startTime                   time.Time
+	routersConnected            atomic.Int64
+	subscribeAttemptsTotal      atomic.Int64
+	subscriptionErrorsTotal     atomic.Int64
+	messagesReceivedTotal       atomic.Int64
+	payloadBytesReceivedTotal   atomic.Int64
+	transportBytesReceivedTotal atomic.Int64
+	receiveErrorsTotal          atomic.Int64
+	receiveTimeoutErrorsTotal   atomic.Int64
+	receiveClosedTotal          atomic.Int64
+	receiveOtherErrorsTotal     atomic.Int64
+}
+
+func (f *emsFeeder) GetFeed() chan *feeder.Feed {
+	return f.queue.Feed()
+}
+
+func (f *emsFeeder) Events() <-chan *feeder.Event {
+	return f.events.Events()
+}
+
+// Stopped is closed by Stop, the router workers reconnect until then.
+func (f *emsFeeder) Stopped() <-chan struct{} {
+	return f.stopCh
+}
+
+// Stop cancels the subscriptions and waits for the router workers to exit.
+func (f *emsFeeder) Stop() {
+	f.stopOnce.Do(func() {
+		close(f.stopCh)
+		f.cancel()
+		f.wg.Wait()
+	})
+}
+
+// StopGracefully cancels the subscriptions, waits for the consumer to take the queued items and
+// closes the feed channel.
+func (f *emsFeeder) StopGracefully(ctx context.Context) error {
+	f.cancel()
+	err := feeder.WaitContext(ctx, &f.wg)
+	if err == nil {
+		err = f.queue.Drain(ctx)
+	}
+	f.Stop()
+	f.queue.Close()
+	f.events.Close()
+
+	return err
+}
+
+func (f *emsFeeder) stats() Stats {
+	s := Stats{
+		StatsSnapshot: feeder.StatsSnapshot{
+			Transport:                   "ems",
+			StartTime:                   f.startTime.UTC(),
+			UptimeSeconds:               int64(time.Since(f.startTime).Seconds()),
+			MessagesReceivedTotal:       f.messagesReceivedTotal.Load(),
+			PayloadBytesReceivedTotal:   f.payloadBytesReceivedTotal.Load(),
+			TransportBytesReceivedTotal: f.transportBytesReceivedTotal.Load(),
+			ReceiveErrorsTotal:          f.receiveErrorsTotal.Load(),
+			ReceiveTimeoutErrorsTotal:   f.receiveTimeoutErrorsTotal.Load(),
+			ReceiveClosedTotal:          f.receiveClosedTotal.Load(),
+			ReceiveOtherErrorsTotal:     f.receiveOtherErrorsTotal.Load(),
+		},
+		RoutersTotal:            int64(len(f.routers)),
+		RoutersConnected:        f.routersConnected.Load(),
+		SubscribeAttemptsTotal:  f.subscribeAttemptsTotal.Load(),
+		SubscriptionErrorsTotal: f.subscriptionErrorsTotal.Load(),
+	}
+	f.queue.FillStats(&s.StatsSnapshot)
+	f.producers.FillStats(&s.StatsSnapshot)
+	f.events.FillStats(&s.StatsSnapshot)
+
+	return s
+}
+
+func (f *emsFeeder) GetStatsJson() ([]byte, error) {
+	return json.Marshal(f.stats())
+}
+
+func classifyReceiveError(err error) string {
+	if err == nil {
+		return "none"
+	}
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return "timeout"
+	}
+	if status.Code(err) == codes.DeadlineExceeded {
+		return "timeout"
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, io.EOF) || status.Code(err) == codes.Canceled {
+		return "closed"
+	}
+	return "other"
+}
+
+// failed checks if the RPC failed, rather than being ended by the router or canceled.
+func failed(err error) bool {
+	return err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, context.Canceled) && status.Code(err) != codes.Canceled
+}
+
+func (f *emsFeeder) countReceiveError(err error) {
+	switch classifyReceiveError(err) {
+	case "timeout":
+		f.receiveErrorsTotal.Add(1)
+		f.receiveTimeoutErrorsTotal.Add(1)
+	case "closed":
+		f.receiveErrorsTotal.Add(1)
+		f.receiveClosedTotal.Add(1)
+	case "other":
+		f.receiveErrorsTotal.Add(1)
+		f.receiveOtherErrorsTotal.Add(1)
+	}
+}
+
+func New(routers []string, subscriptions []string) (feeder.Feeder, error) {
+	return NewWithOptions(routers, subscriptions)
+}
+
+// NewWithOptions creates IOS XR EMS dial-in feeder which requests the telemetry subscriptions
+// configured on every router under the given names with the CreateSubs RPC. Every streamed
+// message is published as a feed item and a failed stream is published as an error item, a
+// stream ended by the router is reported by the ProducerDisconnected event only. Either way the
+// router is dialed again after the backoff delay.
+func NewWithOptions(routers []string, subscriptions []string, opts ...Option) (feeder.Feeder, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	if len(routers) == 0 {
+		return nil, fmt.Errorf("no EMS routers")
+	}
+	for _, router := range routers {
+		if router == "" {
+			return nil, fmt.Errorf("empty EMS router")
+		}
+	}
+	if len(subscriptions) == 0 {
+		return nil, fmt.Errorf("no subscriptions")
+	}
+	for _, sub := range subscriptions {
+		if sub == "" {
+			return nil, fmt.Errorf("empty subscription name")
+		}
+	}
+	encoding, _ := o.encoding.payloadEncoding()
+	dialOpts := []grpc.DialOption{
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(o.maxMsgSize)),
+	}
+	if o.tls != nil {
+		tlsConfig, err := o.tls.load()
+		if err != nil {
+			return nil, err
+		}
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	} else {
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+	if o.username != "" || o.password != "" {
+		if o.tls == nil {
+			o.logger.Warningf("EMS credentials are sent in plaintext to %v", routers)
+		}
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(passCredential{
+			username: o.username,
+			password: o.password,
+			insecure: o.insecureCredentials,
+		}))
+	}
+	stopCh := make(chan struct{})
+	queue, err := feeder.NewFeedQueue(o.queueCapacity, stopCh, o.overflow)
+	if err != nil {
+		return nil, err
+	}
+	events := feeder.NewEventQueue(feeder.TransportGRPC)
+	queue.SetEvents(events)
+	ctx, cancel := context.WithCancel(context.Background())
+	f := &emsFeeder{
+		routers:       routers,
+		subscriptions: subscriptions,
+		encoding:      encoding,
+		dialOpts:      dialOpts,
+		opts:          o,
+		ctx:           ctx,
+		cancel:        cancel,
+		stopCh:        stopCh,
+		queue:         queue,
+		events:        events,
+		producers:     feeder.NewProducerTracker(o.producers),
+		logger:        o.logger,
+		startTime:     time.Now(),
+	}
+	for _, router := range routers {
+		f.wg.Add(1)
+		go f.run(router)
+	}
+
+	return f, nil
+}
+
+// run keeps the subscriptions of router, reconnecting with exponential backoff until stopped.
+func (f *emsFeeder) run(router string) {
+	defer f.wg.Done()
+	addr := routerAddr(router)
+	backoff := f.opts.backoffMin
+	for {
+		established, err := f.subscribe(router, addr)
+		if established {
+			reason := err
+			if f.ctx.Err() != nil {
+				reason = feeder.ErrFeederStopped
+			}
+			f.events.ProducerDisconnected(addr, reason)
+		}
+		select {
+		case <-f.ctx.Done():
+			return
+		default:
+		}
+		if established {
+			backoff = f.opts.backoffMin
+		}
+		if failed(err) {
+			f.logger.Warningf("EMS subscriptions %v on %s failed, resubscribing in %s: %v", f.subscriptions, router, backoff, err)
+			f.producers.Error(addr)
+			if !f.queue.Publish(&feeder.Feed{
+				ProducerAddr: addr,
+				Err:          fmt.Errorf("EMS subscriptions on %s failed: %w", router, err),
+				Transport:    feeder.TransportGRPC,
+				Encoding:     f.encoding,
+				Framing:      feeder.FramingNone,
+			}) {
+				return
+			}
+		} else {
+			// The disconnected event reports a stream ended by the router.
+			f.logger.Infof("EMS subscriptions %v on %s ended, resubscribing in %s", f.subscriptions, router, backoff)
+		}
+		t := time.NewTimer(backoff)
+		select {
+		case <-f.ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		backoff = min(backoff*2, f.opts.backoffMax)
+	}
+}
+
+// subscribe runs a single CreateSubs RPC against router until the stream ends, established
+// reports whether the router streamed any data.
+func (f *emsFeeder) subscribe(router string, addr net.Addr) (established bool, err error) {
+	f.subscribeAttemptsTotal.Add(1)
+	ctx, cancel := context.WithCancel(f.ctx)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, router, f.dialOpts...)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	stream, err := emsgetproto.NewGRPCConfigOperClient(conn).CreateSubs(ctx, &emsgetproto.CreateSubsArgs{
+		ReqId:         f.reqID.Add(1),
+		Encode:        int64(f.opts.encoding),
+		Subscriptions: f.subscriptions,
+	})
+	if err != nil {
+		return false, err
+	}
+	for {
+		reply, err := stream.Recv()
+		if err != nil {
+			f.countReceiveError(err)
+			return established, err
+		}
+		if reply.GetErrors() != "" {
+			f.subscriptionErrorsTotal.Add(1)
+			f.producers.Error(addr)
+			if !f.queue.Publish(&feeder.Feed{
+				ProducerAddr: addr,
+				Err:          fmt.Errorf("%w on %s: %s", ErrSubscription, router, reply.GetErrors()),
+				Transport:    feeder.TransportGRPC,
+				Encoding:     f.encoding,
+				Framing:      feeder.FramingNone,
+			}) {
+				return established, context.Canceled
+			}
+		}
+		data := reply.GetData()
+		if len(data) == 0 {
+			continue
+		}
+		if !established {
+			established = true
+			f.logger.Infof("EMS subscriptions %v on %s established", f.subscriptions, router)
+			f.routersConnected.Add(1)
+			defer f.routersConnected.Add(-1)
+			f.producers.SessionStarted(addr)
+			defer f.producers.SessionEnded(addr)
+			f.events.ProducerConnected(addr)
+		}
+		item := &feeder.Feed{
+			ProducerAddr: addr,
+			Transport:    feeder.TransportGRPC,
+			Encoding:     f.encoding,
+			Framing:      feeder.FramingNone,
+			ReceivedAt:   time.Now(),
+		}
+		item.CopyTelemetryMsg(data, f.opts.pool)
+		size := proto.Size(reply)
+		f.messagesReceivedTotal.Add(1)
+		f.payloadBytesReceivedTotal.Add(int64(len(data)))
+		f.transportBytesReceivedTotal.Add(int64(size))
+		f.producers.Received(addr, len(data), size)
+		if !f.queue.Publish(item) {
+			return established, context.Canceled
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package ems_feeder
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+	emsgetproto "github.com/sbezverk/tools/xr_getproto/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// fakeRouter is an EMS server which records the CreateSubs requests. The first stream sends a
+// message and fails, later streams report an error, send a message and stay open.
+type fakeRouter struct {
+	emsgetproto.UnimplementedGRPCConfigOperServer
+	mu       sync.Mutex
+	requests []*emsgetproto.CreateSubsArgs
+	username []string
+}
+
+func (r *fakeRouter) CreateSubs(args *emsgetproto.CreateSubsArgs, stream emsgetproto.GRPCConfigOper_CreateSubsServer) error {
+	r.mu.Lock()
+	r.requests = append(r.requests, args)
+	n := len(r.requests)
+	if md, ok := metadata.FromIncomingContext(stream.Context()); ok {
+		r.username = append(r.username, md.Get("username")...)
+	}
+	r.mu.Unlock()
+	if n == 1 {
+		if err := stream.Send(&emsgetproto.CreateSubsReply{ResReqId: args.GetReqId(), Data: []byte("first")}); err != nil {
+			return err
+		}
+		return status.Error(codes.Unavailable, "restarting")
+	}
+	if err := stream.Send(&emsgetproto.CreateSubsReply{ResReqId: args.GetReqId(), Errors: "subscription bogus not found"}); err != nil {
+		return err
+	}
+	if err := stream.Send(&emsgetproto.CreateSubsReply{ResReqId: args.GetReqId(), Data: []byte("second")}); err != nil {
+		return err
+	}
+	<-stream.Context().Done()
+	return nil
+}
+
+// closingRouter sends a message on every stream and ends it cleanly.
+type closingRouter struct {
+	emsgetproto.UnimplementedGRPCConfigOperServer
+}
+
+func (r *closingRouter) CreateSubs(args *emsgetproto.CreateSubsArgs, stream emsgetproto.GRPCConfigOper_CreateSubsServer) error {
+	return stream.Send(&emsgetproto.CreateSubsReply{ResReqId: args.GetReqId(), Data: []byte("data")})
+}
+
+func serve(t *testing.T, router emsgetproto.GRPCConfigOperServer) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	srv := grpc.NewServer()
+	emsgetproto.RegisterGRPCConfigOperServer(srv, router)
+	go srv.Serve(l)
+	t.Cleanup(srv.Stop)
+
+	return l.Addr().String()
+}
+
+func TestCreateSubsAndReconnect(t *testing.T) {
+	router := &fakeRouter{}
+	addr := serve(t, router)
+
+	f, err := NewWithOptions([]string{addr}, []string{"rib", "bogus"},
+		WithBackoff(10*time.Millisecond, 20*time.Millisecond),
+		WithCredentials("cisco", "cisco123"),
+		WithInsecureCredentials(),
+		WithLogger(feeder.NopLogger()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.Stop()
+
+	var items []*feeder.Feed
+	for len(items) < 4 {
+		select {
+		case item := <-f.GetFeed():
+			items = append(items, item)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out after %d items", len(items))
+		}
+	}
+	for i, item := range items {
+		if item.Encoding != feeder.EncodingGPB || item.ProducerAddr.String() != addr {
+			t.Fatalf("unexpected item %d: %+v", i, item)
+		}
+	}
+	if string(items[0].TelemetryMsg) != "first" || string(items[3].TelemetryMsg) != "second" {
+		t.Fatalf("unexpected messages %q and %q", items[0].TelemetryMsg, items[3].TelemetryMsg)
+	}
+	if status.Code(errors.Unwrap(items[1].Err)) != codes.Unavailable {
+		t.Fatalf("expected the stream error, got %v", items[1].Err)
+	}
+	if !errors.Is(items[2].Err, ErrSubscription) {
+		t.Fatalf("expected the subscription error, got %v", items[2].Err)
+	}
+
+	router.mu.Lock()
+	requests, username := router.requests, router.username
+	router.mu.Unlock()
+	if len(requests) != 2 || requests[0].GetEncode() != int64(EncodingGPBKV) ||
+		len(requests[0].GetSubscriptions()) != 2 || requests[0].GetReqId() == requests[1].GetReqId() {
+		t.Fatalf("unexpected requests %v", requests)
+	}
+	if len(username) != 2 || username[1] != "cisco" {
+		t.Fatalf("expected credentials on every request, got %v", username)
+	}
+
+	b, err := f.GetStatsJson()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var stats Stats
+	if err := json.Unmarshal(b, &stats); err != nil {
+		t.Fatalf("failed to decode stats: %v", err)
+	}
+	if stats.Transport != "ems" || stats.RoutersConnected != 1 || stats.SubscribeAttemptsTotal != 2 ||
+		stats.SubscriptionErrorsTotal != 1 || stats.MessagesReceivedTotal != 2 || stats.PayloadBytesReceivedTotal != 11 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestStreamEndedByRouter(t *testing.T) {
+	addr := serve(t, &closingRouter{})
+	f, err := NewWithOptions([]string{addr}, []string{"rib"},
+		WithBackoff(10*time.Millisecond, 20*time.Millisecond),
+		WithLogger(feeder.NopLogger()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.Stop()
+	events := feeder.EventsOf(f)
+	// Every stream ends cleanly, the end is reported by the disconnected event only.
+	for disconnected := 0; disconnected < 2; {
+		select {
+		case item := <-f.GetFeed():
+			if item.Err != nil {
+				t.Fatalf("unexpected error item %v", item.Err)
+			}
+		case e := <-events:
+			if e.Type == feeder.EventProducerDisconnected {
+				disconnected++
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out after %d ended streams", disconnected)
+		}
+	}
+	if p := f.(*emsFeeder).stats().Producers[addr]; p.ReceiveErrorsTotal != 0 {
+		t.Fatalf("unexpected producer stats %+v", p)
+	}
+}
+
+func TestOptionValidation(t *testing.T) {
+	for name, args := range map[string]struct {
+		routers []string
+		subs    []string
+		opts    []Option
+	}{
+		"no routers":       {subs: []string{"rib"}},
+		"no subscriptions": {routers: []string{"localhost:57400"}},
+		"empty name":       {routers: []string{"localhost:57400"}, subs: []string{""}},
+		"bad encoding":     {routers: []string{"localhost:57400"}, subs: []string{"rib"}, opts: []Option{WithEncoding(1)}},
+		"bad backoff":      {routers: []string{"localhost:57400"}, subs: []string{"rib"}, opts: []Option{WithBackoff(0, time.Second)}},
+		"plaintext creds":  {routers: []string{"localhost:57400"}, subs: []string{"rib"}, opts: []Option{WithCredentials("cisco", "cisco123")}},
+	} {
+		if f, err := NewWithOptions(args.routers, args.subs, args.opts...); err == nil {
+			f.Stop()
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
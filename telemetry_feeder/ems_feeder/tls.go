@@ -0,0 +1,43 @@
+package ems_feeder
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// DefaultServerName is the name in the certificate IOS XR generates for the EMS server.
+const DefaultServerName = "ems.cisco.com"
+
+// TLSConfig defines TLS client credentials of the EMS feeder. Routers are verified against
+// CAFile, which is typically the ems.pem certificate copied from the router, or the system
+// roots when it is not set. ServerName defaults to DefaultServerName.
+type TLSConfig struct {
+	CAFile             string
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
+func (c TLSConfig) load() (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = DefaultServerName
+	}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file %s: %w", c.CAFile, err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", c.CAFile)
+		}
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,128 @@
+package telemetry_feeder
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// eventQueueCapacity is the number of events waiting for the consumer, events beyond it are
+// dropped rather than holding back the receivers.
+const eventQueueCapacity = 1024
+
+// EventType is the kind of a lifecycle event.
+type EventType string
+
+const (
+	// EventProducerConnected is emitted when a producer session starts, a dial-out connection
+	// is accepted or a dial-in subscription is established.
+	EventProducerConnected EventType = "producer-connected"
+	// EventProducerDisconnected is emitted when a producer session ends, Err is the reason.
+	// Sessions ended by the collector report ErrFeederStopped.
+	EventProducerDisconnected EventType = "producer-disconnected"
+	// EventFramingError is emitted for a message which cannot be delimited or decoded, Err is
+	// the error also carried by the error item of the feed.
+	EventFramingError EventType = "framing-error"
+	// EventQueueOverflow is emitted when the feed queue starts dropping or spilling items, it
+	// is emitted again only after an item was queued without overflowing. Err wraps
+	// ErrQueueOverflow, ProducerAddr is the producer of the first overflowing item.
+	EventQueueOverflow EventType = "queue-overflow"
+)
+
+// Event is a lifecycle event of a feeder, delivered separately from the telemetry feed.
+type Event struct {
+	Type         EventType
+	Transport    Transport
+	ProducerAddr net.Addr
+	Err          error
+	// Source is the name of the source feeder when merged by mux_feeder.
+	Source string
+	Time   time.Time
+}
+
+// EventSource is implemented by the feeders delivering lifecycle events. The events channel is
+// closed by StopGracefully once the feed channel is closed.
+type EventSource interface {
+	Events() <-chan *Event
+}
+
+// EventsOf returns the events channel of f, or a closed channel when f delivers no events.
+func EventsOf(f Feeder) <-chan *Event {
+	if es, ok := f.(EventSource); ok {
+		return es.Events()
+	}
+	ch := make(chan *Event)
+	close(ch)
+
+	return ch
+}
+
+// EventQueue is the events channel of a feeder. Emitting never blocks, events which do not fit
+// are dropped and counted, events emitted after Close are discarded.
+type EventQueue struct {
+	transport          Transport
+	events             chan *Event
+	mu                 sync.RWMutex
+	closed             bool
+	eventsDroppedTotal atomic.Int64
+}
+
+// NewEventQueue creates the events channel of a feeder, transport is set on the events which
+// do not carry one.
+func NewEventQueue(transport Transport) *EventQueue {
+	return &EventQueue{
+		transport: transport,
+		events:    make(chan *Event, eventQueueCapacity),
+	}
+}
+
+func (q *EventQueue) Events() <-chan *Event {
+	return q.events
+}
+
+func (q *EventQueue) Emit(e *Event) {
+	if e.Transport == "" {
+		e.Transport = q.transport
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return
+	}
+	select {
+	case q.events <- e:
+	default:
+		q.eventsDroppedTotal.Add(1)
+	}
+}
+
+func (q *EventQueue) ProducerConnected(addr net.Addr) {
+	q.Emit(&Event{Type: EventProducerConnected, ProducerAddr: addr})
+}
+
+func (q *EventQueue) ProducerDisconnected(addr net.Addr, reason error) {
+	q.Emit(&Event{Type: EventProducerDisconnected, ProducerAddr: addr, Err: reason})
+}
+
+func (q *EventQueue) FramingError(addr net.Addr, err error) {
+	q.Emit(&Event{Type: EventFramingError, ProducerAddr: addr, Err: err})
+}
+
+// Close closes the events channel, it is idempotent.
+func (q *EventQueue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.closed {
+		q.closed = true
+		close(q.events)
+	}
+}
+
+// FillStats sets the event related fields of the snapshot.
+func (q *EventQueue) FillStats(s *StatsSnapshot) {
+	s.EventsDroppedTotal = q.eventsDroppedTotal.Load()
+}
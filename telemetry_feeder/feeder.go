@@ -1,16 +1,22 @@
 package telemetry_feeder
 
 import (
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"net"
+	"sync"
 	"time"
 )
 
 var (
 	ErrUnmarshalTelemetryMsg = errors.New("failed to unmarshal telemetry message")
 	ErrReceiveTelemetryMsg   = errors.New("failed to receive telemetry message")
+	// ErrFeederStopped is the reason of the sessions ended by the collector when it stops.
+	ErrFeederStopped = errors.New("feeder stopped")
+	// ErrQueueOverflow is wrapped by the reason of EventQueueOverflow.
+	ErrQueueOverflow = errors.New("feed queue overflow")
 )
 
 type Transport string
@@ -24,12 +30,89 @@ const (
 
 	EncodingGPB  PayloadEncoding = "gpb"
 	EncodingJSON PayloadEncoding = "json"
+	// EncodingGNMI is a marshaled gnmi.SubscribeResponse as received by gnmi_feeder.
+	EncodingGNMI PayloadEncoding = "gnmi"
 
 	FramingNone         Framing = "none"
 	FramingCiscoXRST    Framing = "cisco-xr-st"
 	FramingCiscoNXOSUDP Framing = "cisco-nxos-udp"
 )
 
+// XRSTHeaderLength is the size of the Cisco XR streaming telemetry header which precedes
+// every message sent over the TCP and UDP dial-out transports.
+const XRSTHeaderLength = 12
+
+type XRSTMsgType uint16
+type XRSTEncoding uint16
+
+const (
+	XRSTMsgTypeUnset XRSTMsgType = iota
+	XRSTMsgTypeTelemetryData
+	XRSTMsgTypeHeartbeat
+)
+
+const (
+	XRSTEncodingUnset XRSTEncoding = iota
+	XRSTEncodingGPB
+	XRSTEncodingJSON
+	XRSTEncodingGPBCompact
+	XRSTEncodingGPBKV
+)
+
+// XRSTHeader is the decoded Cisco XR streaming telemetry header:
+// | msg type (2) | encoding (2) | version (2) | flags (2) | payload length (4) |
+type XRSTHeader struct {
+	MsgType  XRSTMsgType
+	Encoding XRSTEncoding
+	Version  uint16
+	Flags    uint16
+	Length   uint32
+}
+
+func ParseXRSTHeader(b []byte) (XRSTHeader, error) {
+	if len(b) < XRSTHeaderLength {
+		return XRSTHeader{}, fmt.Errorf("buffer too short to contain Cisco XR ST framing header: length %d", len(b))
+	}
+	return XRSTHeader{
+		MsgType:  XRSTMsgType(binary.BigEndian.Uint16(b[0:2])),
+		Encoding: XRSTEncoding(binary.BigEndian.Uint16(b[2:4])),
+		Version:  binary.BigEndian.Uint16(b[4:6]),
+		Flags:    binary.BigEndian.Uint16(b[6:8]),
+		Length:   binary.BigEndian.Uint32(b[8:12]),
+	}, nil
+}
+
+// PayloadEncoding maps the header encoding to the feed encoding, for the unset value the payload
+// is inspected, anything which does not look like a JSON value is treated as GPB.
+func (h XRSTHeader) PayloadEncoding(payload []byte) (PayloadEncoding, error) {
+	switch h.Encoding {
+	case XRSTEncodingGPB, XRSTEncodingGPBCompact, XRSTEncodingGPBKV:
+		return EncodingGPB, nil
+	case XRSTEncodingJSON:
+		return EncodingJSON, nil
+	case XRSTEncodingUnset:
+		if startsWithJSON(payload) {
+			return EncodingJSON, nil
+		}
+		return EncodingGPB, nil
+	}
+	return "", fmt.Errorf("unknown Cisco XR ST encoding type %d", h.Encoding)
+}
+
+// startsWithJSON checks if the first non-whitespace byte of b opens a JSON object or array.
+func startsWithJSON(b []byte) bool {
+	for _, c := range b {
+		switch c {
+		case ' ', '\t', '\n', '\r':
+			continue
+		case '{', '[':
+			return true
+		}
+		return false
+	}
+	return false
+}
+
 type Feed struct {
 	ProducerAddr net.Addr
 	TelemetryMsg []byte
@@ -37,9 +120,29 @@ type Feed struct {
 	Transport    Transport
 	Encoding     PayloadEncoding
 	Framing      Framing
+	// Source is the name of the feeder the item came from when several feeders are merged,
+	// empty otherwise.
+	Source string
+	// PeerSubject is the subject of the certificate the producer authenticated with, set by
+	// transports using mutual TLS, empty otherwise.
+	PeerSubject string
+	// ReceivedAt is the time the message was received by the transport, zero when unknown.
+	ReceivedAt time.Time
+	// pool and pooled are set when TelemetryMsg is backed by a BufferPool buffer.
+	pool   *BufferPool
+	pooled *[]byte
 }
 
+// MakeFeederMsgFromJson builds the Feed of a message received over a datagram transport. The
+// message is either a bare JSON value, a Cisco NX-OS UDP framed JSON value or a Cisco XR ST
+// framed message, the latter may carry a GPB payload as indicated by the header encoding.
 func MakeFeederMsgFromJson(b []byte, n int, transport Transport) (Feed, error) {
+	return MakeFeederMsgWithPool(b, n, transport, nil)
+}
+
+// MakeFeederMsgWithPool is MakeFeederMsgFromJson copying the payload into a buffer of pool,
+// the returned Feed is to be released with Feed.Release. A nil pool allocates the payload.
+func MakeFeederMsgWithPool(b []byte, n int, transport Transport, pool *BufferPool) (Feed, error) {
 	m := Feed{}
 	if n < 0 {
 		return m, fmt.Errorf("invalid message length: %d", n)
@@ -67,8 +170,7 @@ func MakeFeederMsgFromJson(b []byte, n int, transport Transport) (Feed, error) {
 		m.Transport = transport
 		m.Encoding = EncodingJSON
 		m.Framing = FramingNone
-		m.TelemetryMsg = make([]byte, n)
-		copy(m.TelemetryMsg, msg)
+		m.CopyTelemetryMsg(msg, pool)
 		return m, nil
 	}
 	// Three possibilities:
@@ -91,8 +193,7 @@ func MakeFeederMsgFromJson(b []byte, n int, transport Transport) (Feed, error) {
 		m.Transport = transport
 		m.Encoding = EncodingJSON
 		m.Framing = FramingCiscoNXOSUDP
-		m.TelemetryMsg = make([]byte, payloadLength)
-		copy(m.TelemetryMsg, msg[6:])
+		m.CopyTelemetryMsg(msg[6:], pool)
 		// JSON may legally start with whitespace; look for the first non-whitespace byte.
 		j := 0
 		for j < len(m.TelemetryMsg) {
@@ -104,6 +205,7 @@ func MakeFeederMsgFromJson(b []byte, n int, transport Transport) (Feed, error) {
 			break
 		}
 		if j >= len(m.TelemetryMsg) || (m.TelemetryMsg[j] != '{' && m.TelemetryMsg[j] != '[') {
+			m.Release()
 			return Feed{}, fmt.Errorf("Cisco NX-OS UDP framing payload does not start with JSON value")
 		}
 		return m, nil
@@ -112,16 +214,27 @@ func MakeFeederMsgFromJson(b []byte, n int, transport Transport) (Feed, error) {
 	if n <= 12 {
 		return m, fmt.Errorf("message too short to contain Cisco XR ST framing header: length %d", n)
 	}
-	payloadLength := binary.BigEndian.Uint32(msg[8:12])
+	h, err := ParseXRSTHeader(msg)
+	if err != nil {
+		return m, err
+	}
+	payloadLength := h.Length
 	expectedLength := uint64(payloadLength) + 12
 	if expectedLength != uint64(n) {
 		return m, fmt.Errorf("Cisco XR ST framing length mismatch: payload length %d plus header 12 does not match message length %d", payloadLength, n)
 	}
+	// The header encoding tells GPB compact and GPB-KV payloads from JSON ones.
+	encoding, err := h.PayloadEncoding(msg[12:])
+	if err != nil {
+		return m, err
+	}
 	m.Transport = transport
-	m.Encoding = EncodingJSON
+	m.Encoding = encoding
 	m.Framing = FramingCiscoXRST
-	m.TelemetryMsg = make([]byte, int(payloadLength))
-	copy(m.TelemetryMsg, msg[12:])
+	m.CopyTelemetryMsg(msg[12:], pool)
+	if encoding == EncodingGPB {
+		return m, nil
+	}
 	j := 0
 	for j < len(m.TelemetryMsg) {
 		switch m.TelemetryMsg[j] {
@@ -132,6 +245,7 @@ func MakeFeederMsgFromJson(b []byte, n int, transport Transport) (Feed, error) {
 		break
 	}
 	if j >= len(m.TelemetryMsg) || (m.TelemetryMsg[j] != '{' && m.TelemetryMsg[j] != '[') {
+		m.Release()
 		return Feed{}, fmt.Errorf("Cisco XR ST framing payload does not start with JSON value")
 	}
 	return m, nil
@@ -155,10 +269,68 @@ type StatsSnapshot struct {
 	ReceiveTimeoutErrorsTotal   int64     `json:"receive_timeout_errors_total"`
 	ReceiveClosedTotal          int64     `json:"receive_closed_total"`
 	ReceiveOtherErrorsTotal     int64     `json:"receive_other_errors_total"`
+	OverflowPolicy              string    `json:"overflow_policy,omitempty"`
+	FeedDroppedNewestTotal      int64     `json:"feed_dropped_newest_total"`
+	FeedDroppedOldestTotal      int64     `json:"feed_dropped_oldest_total"`
+	FeedSpilledTotal            int64     `json:"feed_spilled_total"`
+	FeedSpillDepth              int64     `json:"feed_spill_depth"`
+	FeedSpillErrorsTotal        int64     `json:"feed_spill_errors_total"`
+	// Producers holds per-producer counters keyed by ProducerKey of the producer address.
+	Producers             map[string]ProducerStats `json:"producers,omitempty"`
+	ProducersEvictedTotal int64                    `json:"producers_evicted_total"`
+	EventsDroppedTotal    int64                    `json:"events_dropped_total"`
 }
 
 type Feeder interface {
 	GetStatsJson() ([]byte, error)
 	GetFeed() chan *Feed
+	// Stop stops the feeder immediately, queued items may be lost and the feed channel is not
+	// necessarily closed.
 	Stop()
+	// StopGracefully stops accepting new producers and messages, waits until the consumer has
+	// taken the queued items, then stops the feeder and closes the feed channel. When ctx is
+	// done first the feeder is stopped right away, items not taken yet may be lost and the ctx
+	// error is returned. The feed channel is closed in either case.
+	StopGracefully(ctx context.Context) error
+}
+
+// Terminator is implemented by the feeders which can stop without StopGracefully being called.
+// The stopped channel is closed once the feeder stopped, by Stop or on its own, for example
+// after a fatal listener error or at the end of an offline replay.
+type Terminator interface {
+	Stopped() <-chan struct{}
+}
+
+// StoppedOf returns the stopped channel of f, or nil when f does not stop on its own, a receive
+// from nil blocks forever.
+func StoppedOf(f Feeder) <-chan struct{} {
+	if t, ok := f.(Terminator); ok {
+		return t.Stopped()
+	}
+
+	return nil
+}
+
+// WaitContext waits for wg or until ctx is done, in which case the ctx error is returned and
+// wg is left to finish on its own.
+func WaitContext(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WaitDrained waits until the consumer has taken every item buffered in feed or ctx is done,
+// for feeders queueing items without FeedQueue.
+func WaitDrained(ctx context.Context, feed chan *Feed) error {
+	return waitUntil(ctx, func() bool {
+		return len(feed) == 0
+	})
 }
@@ -69,6 +69,33 @@ func TestMakeFeederMsgFromJsonXRSTFraming(t *testing.T) {
 	}
 }
 
+func TestMakeFeederMsgFromJsonXRSTGPB(t *testing.T) {
+	payload := []byte{0x0a, 0x02, 'r', '1'}
+	for _, encoding := range []XRSTEncoding{XRSTEncodingGPB, XRSTEncodingGPBCompact, XRSTEncodingGPBKV, XRSTEncodingUnset} {
+		msg := make([]byte, 12+len(payload))
+		binary.BigEndian.PutUint16(msg[0:2], uint16(XRSTMsgTypeTelemetryData))
+		binary.BigEndian.PutUint16(msg[2:4], uint16(encoding))
+		binary.BigEndian.PutUint32(msg[8:12], uint32(len(payload)))
+		copy(msg[12:], payload)
+
+		got, err := MakeFeederMsgFromJson(msg, len(msg), TransportUDP)
+		if err != nil {
+			t.Fatalf("encoding %d: unexpected error: %v", encoding, err)
+		}
+		if got.Encoding != EncodingGPB || got.Framing != FramingCiscoXRST || string(got.TelemetryMsg) != string(payload) {
+			t.Fatalf("encoding %d: unexpected feed %+v", encoding, got)
+		}
+	}
+	// JSON encoding in the header still requires a JSON payload.
+	msg := make([]byte, 12+len(payload))
+	binary.BigEndian.PutUint16(msg[2:4], uint16(XRSTEncodingJSON))
+	binary.BigEndian.PutUint32(msg[8:12], uint32(len(payload)))
+	copy(msg[12:], payload)
+	if _, err := MakeFeederMsgFromJson(msg, len(msg), TransportUDP); err == nil {
+		t.Fatal("expected error for JSON encoding with GPB payload, got nil")
+	}
+}
+
 func TestMakeFeederMsgFromJsonRejectsLengthMismatch(t *testing.T) {
 	payload := []byte(`{"encoding_path":"rib"}`)
 	msg := make([]byte, 6+len(payload))
@@ -90,3 +117,50 @@ func TestMakeFeederMsgFromJsonUsesReceivedLengthOnly(t *testing.T) {
 		t.Fatal("expected short message error, got nil")
 	}
 }
+
+func TestParseXRSTHeader(t *testing.T) {
+	b := []byte{0x00, 0x01, 0x00, 0x04, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x01, 0x02}
+
+	h, err := ParseXRSTHeader(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if h.MsgType != XRSTMsgTypeTelemetryData || h.Encoding != XRSTEncodingGPBKV || h.Version != 1 || h.Flags != 0 || h.Length != 0x0102 {
+		t.Fatalf("unexpected header %+v", h)
+	}
+	if _, err := ParseXRSTHeader(b[:XRSTHeaderLength-1]); err == nil {
+		t.Fatal("expected short header error, got nil")
+	}
+}
+
+func TestXRSTHeaderPayloadEncoding(t *testing.T) {
+	tests := []struct {
+		encoding XRSTEncoding
+		payload  []byte
+		want     PayloadEncoding
+		wantErr  bool
+	}{
+		{encoding: XRSTEncodingGPB, want: EncodingGPB},
+		{encoding: XRSTEncodingGPBCompact, want: EncodingGPB},
+		{encoding: XRSTEncodingGPBKV, want: EncodingGPB},
+		{encoding: XRSTEncodingJSON, want: EncodingJSON},
+		{encoding: XRSTEncodingUnset, payload: []byte(" {}"), want: EncodingJSON},
+		{encoding: XRSTEncodingUnset, payload: []byte{0x0a, 0x01}, want: EncodingGPB},
+		{encoding: 42, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := XRSTHeader{Encoding: tt.encoding}.PayloadEncoding(tt.payload)
+		if tt.wantErr {
+			if err == nil {
+				t.Fatalf("encoding %d: expected error, got nil", tt.encoding)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("encoding %d: unexpected error: %v", tt.encoding, err)
+		}
+		if got != tt.want {
+			t.Fatalf("encoding %d: want %q got %q", tt.encoding, tt.want, got)
+		}
+	}
+}
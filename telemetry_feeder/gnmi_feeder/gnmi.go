@@ -0,0 +1,358 @@
+package gnmi_feeder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+	"github.com/sbezverk/tools/telemetry_feeder/proto/gnmi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	MaxRcvMsgSize     = 1024 * 1024 * 4
+	feedQueueCapacity = 1024 * 10
+)
+
+// targetAddr is the producer address of a target, the target as it is dialed.
+type targetAddr string
+
+func (a targetAddr) Network() string { return "tcp" }
+func (a targetAddr) String() string  { return string(a) }
+
+// Stats are the gNMI feeder counters returned by GetStatsJson.
+type Stats struct {
+	feeder.StatsSnapshot
+	TargetsTotal           int64 `json:"targets_total"`
+	TargetsConnected       int64 `json:"targets_connected"`
+	SubscribeAttemptsTotal int64 `json:"subscribe_attempts_total"`
+	SyncResponsesTotal     int64 `json:"sync_responses_total"`
+}
+
+type gnmiFeeder struct {
+	targets                     []string
+	request                     *gnmi.SubscribeRequest
+	dialOpts                    []grpc.DialOption
+	opts                        options
+	ctx                         context.Context
+	cancel                      context.CancelFunc
+	stopCh                      chan struct{}
+	stopOnce                    sync.Once
+	wg                          sync.WaitGroup
+	queue                       *feeder.FeedQueue
+	events                      *feeder.EventQueue
+	producers                   *feeder.ProducerTracker
+	logger                      feeder.Logger
+	startTime                   time.Time
+	targetsConnected            atomic.Int64
+	subscribeAttemptsTotal      atomic.Int64
+	syncResponsesTotal          atomic.Int64
+	messagesReceivedTotal       atomic.Int64
+	payloadBytesReceivedTotal   atomic.Int64
+	transportBytesReceivedTotal atomic.Int64
+	receiveErrorsTotal          atomic.Int64
+	receiveTimeoutErrorsTotal   atomic.Int64
+	receiveClosedTotal          atomic.Int64
+	receiveOtherErrorsTotal     atomic.Int64
+}
+
+func (f *gnmiFeeder) GetFeed() chan *feeder.Feed {
+	return f.queue.Feed()
+}
+
+func (f *gnmiFeeder) Events() <-chan *feeder.Event {
+	return f.events.Events()
+}
+
+// Stopped is closed by Stop, the subscriptions reconnect until then.
+func (f *gnmiFeeder) Stopped() <-chan struct{} {
+	return f.stopCh
+}
+
+// Stop cancels the subscriptions and waits for the target workers to exit.
+func (f *gnmiFeeder) Stop() {
+	f.stopOnce.Do(func() {
+		close(f.stopCh)
+		f.cancel()
+		f.wg.Wait()
+	})
+}
+
+// StopGracefully cancels the subscriptions, waits for the consumer to take the queued items and
+// closes the feed channel.
+func (f *gnmiFeeder) StopGracefully(ctx context.Context) error {
+	f.cancel()
+	err := feeder.WaitContext(ctx, &f.wg)
+	if err == nil {
+		err = f.queue.Drain(ctx)
+	}
+	f.Stop()
+	f.queue.Close()
+	f.events.Close()
+
+	return err
+}
+
+func (f *gnmiFeeder) stats() Stats {
+	s := Stats{
+		StatsSnapshot: feeder.StatsSnapshot{
+			Transport:                   "gnmi",
+			StartTime:                   f.startTime.UTC(),
+			UptimeSeconds:               int64(time.Since(f.startTime).Seconds()),
+			MessagesReceivedTotal:       f.messagesReceivedTotal.Load(),
+			PayloadBytesReceivedTotal:   f.payloadBytesReceivedTotal.Load(),
+			TransportBytesReceivedTotal: f.transportBytesReceivedTotal.Load(),
+			ReceiveErrorsTotal:          f.receiveErrorsTotal.Load(),
+			ReceiveTimeoutErrorsTotal:   f.receiveTimeoutErrorsTotal.Load(),
+			ReceiveClosedTotal:          f.receiveClosedTotal.Load(),
+			ReceiveOtherErrorsTotal:     f.receiveOtherErrorsTotal.Load(),
+		},
+		TargetsTotal:           int64(len(f.targets)),
+		TargetsConnected:       f.targetsConnected.Load(),
+		SubscribeAttemptsTotal: f.subscribeAttemptsTotal.Load(),
+		SyncResponsesTotal:     f.syncResponsesTotal.Load(),
+	}
+	f.queue.FillStats(&s.StatsSnapshot)
+	f.producers.FillStats(&s.StatsSnapshot)
+	f.events.FillStats(&s.StatsSnapshot)
+
+	return s
+}
+
+func (f *gnmiFeeder) GetStatsJson() ([]byte, error) {
+	return json.Marshal(f.stats())
+}
+
+func classifyReceiveError(err error) string {
+	if err == nil {
+		return "none"
+	}
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return "timeout"
+	}
+	if status.Code(err) == codes.DeadlineExceeded {
+		return "timeout"
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, io.EOF) || status.Code(err) == codes.Canceled {
+		return "closed"
+	}
+	return "other"
+}
+
+// failed checks if the subscription ended with an error of the target, rather than being
+// closed by it or canceled.
+func failed(err error) bool {
+	return err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, context.Canceled) && status.Code(err) != codes.Canceled
+}
+
+func (f *gnmiFeeder) countReceiveError(err error) {
+	switch classifyReceiveError(err) {
+	case "timeout":
+		f.receiveErrorsTotal.Add(1)
+		f.receiveTimeoutErrorsTotal.Add(1)
+	case "closed":
+		f.receiveErrorsTotal.Add(1)
+		f.receiveClosedTotal.Add(1)
+	case "other":
+		f.receiveErrorsTotal.Add(1)
+		f.receiveOtherErrorsTotal.Add(1)
+	}
+}
+
+func New(targets []string, subs []Subscription) (feeder.Feeder, error) {
+	return NewWithOptions(targets, subs)
+}
+
+// NewWithOptions creates gNMI dial-in feeder which subscribes to subs on every target in
+// STREAM mode. Every SubscribeResponse is published as a feed item with the EncodingGNMI
+// encoding. A failed subscription is published as an error item, a subscription closed by the
+// target is reported by the ProducerDisconnected event only. Either way the target is subscribed
+// to again after the backoff delay.
+func NewWithOptions(targets []string, subs []Subscription, opts ...Option) (feeder.Feeder, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no gNMI targets")
+	}
+	for _, target := range targets {
+		if target == "" {
+			return nil, fmt.Errorf("empty gNMI target")
+		}
+	}
+	request, err := subscribeRequest(subs, o.encoding)
+	if err != nil {
+		return nil, err
+	}
+	dialOpts := []grpc.DialOption{
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(o.maxMsgSize)),
+	}
+	if (o.username != "" || o.password != "") && o.tls == nil {
+		o.logger.Warningf("gNMI credentials are sent in plaintext to %v", targets)
+	}
+	if o.tls != nil {
+		tlsConfig, err := o.tls.load()
+		if err != nil {
+			return nil, err
+		}
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	} else {
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+	stopCh := make(chan struct{})
+	queue, err := feeder.NewFeedQueue(o.queueCapacity, stopCh, o.overflow)
+	if err != nil {
+		return nil, err
+	}
+	events := feeder.NewEventQueue(feeder.TransportGRPC)
+	queue.SetEvents(events)
+	ctx, cancel := context.WithCancel(context.Background())
+	f := &gnmiFeeder{
+		targets:   targets,
+		request:   request,
+		dialOpts:  dialOpts,
+		opts:      o,
+		ctx:       ctx,
+		cancel:    cancel,
+		stopCh:    stopCh,
+		queue:     queue,
+		events:    events,
+		producers: feeder.NewProducerTracker(o.producers),
+		logger:    o.logger,
+		startTime: time.Now(),
+	}
+	for _, target := range targets {
+		f.wg.Add(1)
+		go f.run(target)
+	}
+
+	return f, nil
+}
+
+// run keeps the subscription to target, reconnecting with exponential backoff until stopped.
+func (f *gnmiFeeder) run(target string) {
+	defer f.wg.Done()
+	addr := targetAddr(target)
+	backoff := f.opts.backoffMin
+	for {
+		established, err := f.subscribe(target, addr)
+		if established {
+			reason := err
+			if f.ctx.Err() != nil {
+				reason = feeder.ErrFeederStopped
+			}
+			f.events.ProducerDisconnected(addr, reason)
+		}
+		select {
+		case <-f.ctx.Done():
+			return
+		default:
+		}
+		if established {
+			backoff = f.opts.backoffMin
+		}
+		if failed(err) {
+			f.logger.Warningf("gNMI subscription to %s failed, resubscribing in %s: %v", target, backoff, err)
+			f.producers.Error(addr)
+			if !f.queue.Publish(&feeder.Feed{
+				ProducerAddr: addr,
+				Err:          fmt.Errorf("gNMI subscription to %s failed: %w", target, err),
+				Transport:    feeder.TransportGRPC,
+				Encoding:     feeder.EncodingGNMI,
+				Framing:      feeder.FramingNone,
+			}) {
+				return
+			}
+		} else {
+			// The disconnected event reports a subscription closed by the target.
+			f.logger.Infof("gNMI subscription to %s ended, resubscribing in %s", target, backoff)
+		}
+		t := time.NewTimer(backoff)
+		select {
+		case <-f.ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		backoff = min(backoff*2, f.opts.backoffMax)
+	}
+}
+
+// subscribe runs a single Subscribe RPC against target until it fails, established reports
+// whether the target sent any response.
+func (f *gnmiFeeder) subscribe(target string, addr net.Addr) (established bool, err error) {
+	f.subscribeAttemptsTotal.Add(1)
+	ctx, cancel := context.WithCancel(f.ctx)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, target, f.dialOpts...)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if f.opts.username != "" || f.opts.password != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "username", f.opts.username, "password", f.opts.password)
+	}
+	stream, err := gnmi.NewGNMIClient(conn).Subscribe(ctx)
+	if err != nil {
+		return false, err
+	}
+	if err := stream.Send(f.request); err != nil {
+		return false, err
+	}
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			f.countReceiveError(err)
+			return established, err
+		}
+		if !established {
+			established = true
+			f.logger.Infof("gNMI subscription to %s established", target)
+			f.targetsConnected.Add(1)
+			defer f.targetsConnected.Add(-1)
+			f.producers.SessionStarted(addr)
+			defer f.producers.SessionEnded(addr)
+			f.events.ProducerConnected(addr)
+		}
+		if resp.GetSyncResponse() {
+			f.syncResponsesTotal.Add(1)
+		}
+		item := &feeder.Feed{
+			ProducerAddr: addr,
+			Transport:    feeder.TransportGRPC,
+			Encoding:     feeder.EncodingGNMI,
+			Framing:      feeder.FramingNone,
+			ReceivedAt:   time.Now(),
+		}
+		n := proto.Size(resp)
+		item.AllocTelemetryMsg(n, f.opts.pool)
+		if item.TelemetryMsg, err = (proto.MarshalOptions{}).MarshalAppend(item.TelemetryMsg[:0], resp); err != nil {
+			item.Release()
+			return established, fmt.Errorf("failed to marshal SubscribeResponse: %w", err)
+		}
+		f.messagesReceivedTotal.Add(1)
+		f.payloadBytesReceivedTotal.Add(int64(n))
+		f.transportBytesReceivedTotal.Add(int64(n))
+		f.producers.Received(addr, n, n)
+		if !f.queue.Publish(item) {
+			return established, context.Canceled
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package gnmi_feeder
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+	"github.com/sbezverk/tools/telemetry_feeder/proto/gnmi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeTarget is a gNMI server which records the subscribe requests, the first stream is ended
+// with an error after an update and a sync response, later streams send a single update.
+type fakeTarget struct {
+	gnmi.UnimplementedGNMIServer
+	mu       sync.Mutex
+	requests []*gnmi.SubscribeRequest
+	username []string
+}
+
+func (t *fakeTarget) Subscribe(stream gnmi.GNMI_SubscribeServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.requests = append(t.requests, req)
+	n := len(t.requests)
+	if md, ok := metadata.FromIncomingContext(stream.Context()); ok {
+		t.username = append(t.username, md.Get("username")...)
+	}
+	t.mu.Unlock()
+	update := &gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_Update{Update: &gnmi.Notification{
+		Timestamp: int64(n),
+		Update: []*gnmi.Update{{
+			Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "counter"}}},
+			Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: int64(n)}},
+		}},
+	}}}
+	if err := stream.Send(update); err != nil {
+		return err
+	}
+	if n > 1 {
+		<-stream.Context().Done()
+		return nil
+	}
+	if err := stream.Send(&gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_SyncResponse{SyncResponse: true}}); err != nil {
+		return err
+	}
+	return status.Error(codes.Unavailable, "restarting")
+}
+
+// closingTarget ends every stream cleanly after the subscribe request.
+type closingTarget struct {
+	gnmi.UnimplementedGNMIServer
+}
+
+func (t *closingTarget) Subscribe(stream gnmi.GNMI_SubscribeServer) error {
+	if _, err := stream.Recv(); err != nil {
+		return err
+	}
+	return stream.Send(&gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_SyncResponse{SyncResponse: true}})
+}
+
+func startTarget(t *testing.T) (*fakeTarget, string) {
+	t.Helper()
+	target := &fakeTarget{}
+	return target, serve(t, target)
+}
+
+func serve(t *testing.T, target gnmi.GNMIServer) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	srv := grpc.NewServer()
+	gnmi.RegisterGNMIServer(srv, target)
+	go srv.Serve(l)
+	t.Cleanup(srv.Stop)
+
+	return l.Addr().String()
+}
+
+func TestSubscribeAndReconnect(t *testing.T) {
+	target, addr := startTarget(t)
+	subs := []Subscription{
+		{Path: "/interfaces/interface[name=Ethernet1/1]/state/counters", Mode: ModeSample, SampleInterval: 10 * time.Second},
+		{Path: "/network-instances", Origin: "openconfig", Mode: ModeOnChange},
+	}
+	f, err := NewWithOptions([]string{addr}, subs,
+		WithBackoff(10*time.Millisecond, 20*time.Millisecond),
+		WithCredentials("admin", "secret"),
+		WithInsecureCredentials(),
+		WithBufferPool(feeder.NewBufferPool()),
+		WithLogger(feeder.NopLogger()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.Stop()
+
+	var items []*feeder.Feed
+	for len(items) < 4 {
+		select {
+		case item := <-f.GetFeed():
+			items = append(items, item)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out after %d items", len(items))
+		}
+	}
+	var counters []int64
+	for i, item := range items {
+		if item.Encoding != feeder.EncodingGNMI || item.ProducerAddr.String() != addr {
+			t.Fatalf("unexpected item %d: %+v", i, item)
+		}
+		if i == 2 {
+			if status.Code(errors.Unwrap(item.Err)) != codes.Unavailable {
+				t.Fatalf("expected the subscription error, got %v", item.Err)
+			}
+			continue
+		}
+		var resp gnmi.SubscribeResponse
+		if err := proto.Unmarshal(item.TelemetryMsg, &resp); err != nil {
+			t.Fatalf("failed to unmarshal item %d: %v", i, err)
+		}
+		item.Release()
+		if i == 1 {
+			if !resp.GetSyncResponse() {
+				t.Fatalf("expected sync response, got %v", &resp)
+			}
+			continue
+		}
+		counters = append(counters, resp.GetUpdate().GetUpdate()[0].GetVal().GetIntVal())
+	}
+	if len(counters) != 2 || counters[0] != 1 || counters[1] != 2 {
+		t.Fatalf("unexpected updates %v", counters)
+	}
+
+	target.mu.Lock()
+	req := target.requests[0].GetSubscribe()
+	username := target.username
+	target.mu.Unlock()
+	if req.GetMode() != gnmi.SubscriptionList_STREAM || req.GetEncoding() != gnmi.Encoding_JSON_IETF || len(req.GetSubscription()) != 2 {
+		t.Fatalf("unexpected subscription list %v", req)
+	}
+	sample, onChange := req.GetSubscription()[0], req.GetSubscription()[1]
+	if sample.GetMode() != gnmi.SubscriptionMode_SAMPLE || sample.GetSampleInterval() != uint64(10*time.Second) ||
+		sample.GetPath().GetElem()[1].GetKey()["name"] != "Ethernet1/1" {
+		t.Fatalf("unexpected sample subscription %v", sample)
+	}
+	if onChange.GetMode() != gnmi.SubscriptionMode_ON_CHANGE || onChange.GetPath().GetOrigin() != "openconfig" {
+		t.Fatalf("unexpected on-change subscription %v", onChange)
+	}
+	if len(username) != 2 || username[0] != "admin" {
+		t.Fatalf("expected credentials on every subscription, got %v", username)
+	}
+
+	b, err := f.GetStatsJson()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var stats Stats
+	if err := json.Unmarshal(b, &stats); err != nil {
+		t.Fatalf("failed to decode stats: %v", err)
+	}
+	if stats.Transport != "gnmi" || stats.TargetsTotal != 1 || stats.TargetsConnected != 1 ||
+		stats.SubscribeAttemptsTotal != 2 || stats.SyncResponsesTotal != 1 || stats.MessagesReceivedTotal != 3 ||
+		stats.Producers[addr].ReceiveErrorsTotal != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestSubscriptionClosedByTarget(t *testing.T) {
+	addr := serve(t, &closingTarget{})
+	f, err := NewWithOptions([]string{addr}, []Subscription{{Path: "/a"}},
+		WithBackoff(10*time.Millisecond, 20*time.Millisecond),
+		WithLogger(feeder.NopLogger()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.Stop()
+	events := feeder.EventsOf(f)
+	// Every subscription ends cleanly, the end is reported by the disconnected event only.
+	for disconnected := 0; disconnected < 2; {
+		select {
+		case item := <-f.GetFeed():
+			if item.Err != nil {
+				t.Fatalf("unexpected error item %v", item.Err)
+			}
+		case e := <-events:
+			if e.Type == feeder.EventProducerDisconnected {
+				disconnected++
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out after %d ended subscriptions", disconnected)
+		}
+	}
+	if p := f.(*gnmiFeeder).stats().Producers[addr]; p.ReceiveErrorsTotal != 0 || p.MessagesReceivedTotal < 2 {
+		t.Fatalf("unexpected producer stats %+v", p)
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	elem := func(name string, keys ...string) *gnmi.PathElem {
+		e := &gnmi.PathElem{Name: name}
+		for i := 0; i < len(keys); i += 2 {
+			if e.Key == nil {
+				e.Key = map[string]string{}
+			}
+			e.Key[keys[i]] = keys[i+1]
+		}
+		return e
+	}
+	tests := []struct {
+		path string
+		want []*gnmi.PathElem
+	}{
+		{path: "/"},
+		{path: "/a/b", want: []*gnmi.PathElem{elem("a"), elem("b")}},
+		{path: "a/b[k=v/1][x=y]/c", want: []*gnmi.PathElem{elem("a"), elem("b", "k", "v/1", "x", "y"), elem("c")}},
+		{path: `/a\/b`, want: []*gnmi.PathElem{elem("a/b")}},
+	}
+	for _, tt := range tests {
+		p, err := ParsePath(tt.path)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %v", tt.path, err)
+		}
+		if want := (&gnmi.Path{Elem: tt.want}); !proto.Equal(p, want) {
+			t.Fatalf("path %q: want %v got %v", tt.path, want, p)
+		}
+	}
+	for _, path := range []string{"/a//b", "/a[k]", "/a[k=v", "/a[k=v]b", "/[k=v]", `/a\`} {
+		if _, err := ParsePath(path); err == nil {
+			t.Fatalf("expected error for %q", path)
+		}
+	}
+}
+
+func TestOptionValidation(t *testing.T) {
+	subs := []Subscription{{Path: "/a"}}
+	for name, args := range map[string]struct {
+		targets []string
+		subs    []Subscription
+		opts    []Option
+	}{
+		"no targets":       {subs: subs},
+		"empty target":     {targets: []string{""}, subs: subs},
+		"no subscriptions": {targets: []string{"localhost:57400"}},
+		"bad mode":         {targets: []string{"localhost:57400"}, subs: []Subscription{{Path: "/a", Mode: "poll"}}},
+		"bad path":         {targets: []string{"localhost:57400"}, subs: []Subscription{{Path: "/a[k"}}},
+		"bad backoff":      {targets: []string{"localhost:57400"}, subs: subs, opts: []Option{WithBackoff(time.Second, time.Millisecond)}},
+		"bad encoding":     {targets: []string{"localhost:57400"}, subs: subs, opts: []Option{WithEncoding(42)}},
+		"plaintext creds":  {targets: []string{"localhost:57400"}, subs: subs, opts: []Option{WithCredentials("admin", "secret")}},
+	} {
+		if f, err := NewWithOptions(args.targets, args.subs, args.opts...); err == nil {
+			f.Stop()
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
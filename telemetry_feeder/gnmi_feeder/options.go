@@ -0,0 +1,147 @@
+package gnmi_feeder
+
+import (
+	"fmt"
+	"time"
+
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+	"github.com/sbezverk/tools/telemetry_feeder/proto/gnmi"
+)
+
+type options struct {
+	queueCapacity       int
+	maxMsgSize          int
+	encoding            gnmi.Encoding
+	tls                 *TLSConfig
+	username            string
+	password            string
+	insecureCredentials bool
+	backoffMin          time.Duration
+	backoffMax          time.Duration
+	overflow            feeder.OverflowConfig
+	producers           feeder.ProducerStatsConfig
+	pool                *feeder.BufferPool
+	logger              feeder.Logger
+}
+
+// Option customizes the gNMI feeder created by NewWithOptions.
+type Option func(*options)
+
+func defaultOptions() options {
+	return options{
+		queueCapacity: feedQueueCapacity,
+		maxMsgSize:    MaxRcvMsgSize,
+		encoding:      gnmi.Encoding_JSON_IETF,
+		backoffMin:    time.Second,
+		backoffMax:    time.Minute,
+		overflow:      feeder.OverflowConfig{Policy: feeder.OverflowBlock},
+		logger:        feeder.GlogLogger(),
+	}
+}
+
+// WithQueueCapacity sets the number of items the feed channel buffers.
+func WithQueueCapacity(n int) Option {
+	return func(o *options) {
+		o.queueCapacity = n
+	}
+}
+
+// WithMaxMsgSize sets the maximum size of a SubscribeResponse message the client accepts.
+func WithMaxMsgSize(n int) Option {
+	return func(o *options) {
+		o.maxMsgSize = n
+	}
+}
+
+// WithEncoding sets the encoding the targets are asked to use for the values, JSON_IETF by
+// default.
+func WithEncoding(encoding gnmi.Encoding) Option {
+	return func(o *options) {
+		o.encoding = encoding
+	}
+}
+
+// WithTLS enables TLS towards the targets, connections are plaintext without it.
+func WithTLS(cfg TLSConfig) Option {
+	return func(o *options) {
+		o.tls = &cfg
+	}
+}
+
+// WithCredentials sets the username and password sent to the targets as the "username" and
+// "password" metadata of the Subscribe RPC. They require WithTLS, or WithInsecureCredentials.
+func WithCredentials(username, password string) Option {
+	return func(o *options) {
+		o.username = username
+		o.password = password
+	}
+}
+
+// WithInsecureCredentials allows the credentials to be sent over plaintext connections, for lab
+// targets without TLS. A warning is logged when the feeder is created.
+func WithInsecureCredentials() Option {
+	return func(o *options) {
+		o.insecureCredentials = true
+	}
+}
+
+// WithBackoff sets the delay before reconnecting to a target, it starts at min and doubles
+// after every failed attempt up to max. The delay is reset once a subscription is established.
+func WithBackoff(min, max time.Duration) Option {
+	return func(o *options) {
+		o.backoffMin = min
+		o.backoffMax = max
+	}
+}
+
+// WithOverflow sets the feed queue overflow policy.
+func WithOverflow(cfg feeder.OverflowConfig) Option {
+	return func(o *options) {
+		o.overflow = cfg
+	}
+}
+
+// WithProducerStats sets the eviction policy of the per-producer stats.
+func WithProducerStats(cfg feeder.ProducerStatsConfig) Option {
+	return func(o *options) {
+		o.producers = cfg
+	}
+}
+
+// WithBufferPool backs the payload of every feed with a buffer of pool, consumers return it
+// with Feed.Release once done with the payload.
+func WithBufferPool(pool *feeder.BufferPool) Option {
+	return func(o *options) {
+		o.pool = pool
+	}
+}
+
+// WithLogger sets the logger, glog is used by default.
+func WithLogger(l feeder.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+func (o *options) validate() error {
+	if o.queueCapacity < 0 {
+		return fmt.Errorf("invalid feed queue capacity %d", o.queueCapacity)
+	}
+	if o.maxMsgSize <= 0 {
+		return fmt.Errorf("invalid maximum message size %d", o.maxMsgSize)
+	}
+	if _, ok := gnmi.Encoding_name[int32(o.encoding)]; !ok {
+		return fmt.Errorf("invalid gNMI encoding %d", o.encoding)
+	}
+	if o.backoffMin <= 0 || o.backoffMax < o.backoffMin {
+		return fmt.Errorf("invalid reconnect backoff %s-%s", o.backoffMin, o.backoffMax)
+	}
+	if (o.username != "" || o.password != "") && o.tls == nil && !o.insecureCredentials {
+		return fmt.Errorf("credentials cannot be sent without TLS unless insecure credentials are allowed")
+	}
+	if o.logger == nil {
+		o.logger = feeder.NopLogger()
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package gnmi_feeder
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sbezverk/tools/telemetry_feeder/proto/gnmi"
+)
+
+type SubscriptionMode string
+
+const (
+	ModeTargetDefined SubscriptionMode = "target-defined"
+	ModeOnChange      SubscriptionMode = "on-change"
+	ModeSample        SubscriptionMode = "sample"
+)
+
+// Subscription is a path subscribed to on every target. Path is in the gNMI path string
+// notation, as /interfaces/interface[name=Ethernet1]/state/counters, Origin is optional.
+// SampleInterval applies to the sample mode, zero leaves it to the target.
+type Subscription struct {
+	Path              string
+	Origin            string
+	Mode              SubscriptionMode
+	SampleInterval    time.Duration
+	SuppressRedundant bool
+	HeartbeatInterval time.Duration
+}
+
+func (s Subscription) toProto() (*gnmi.Subscription, error) {
+	path, err := ParsePath(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	path.Origin = s.Origin
+	sub := &gnmi.Subscription{
+		Path:              path,
+		SuppressRedundant: s.SuppressRedundant,
+		HeartbeatInterval: uint64(s.HeartbeatInterval),
+	}
+	switch s.Mode {
+	case ModeTargetDefined, "":
+		sub.Mode = gnmi.SubscriptionMode_TARGET_DEFINED
+	case ModeOnChange:
+		sub.Mode = gnmi.SubscriptionMode_ON_CHANGE
+	case ModeSample:
+		sub.Mode = gnmi.SubscriptionMode_SAMPLE
+		sub.SampleInterval = uint64(s.SampleInterval)
+	default:
+		return nil, fmt.Errorf("invalid subscription mode %q of path %s", s.Mode, s.Path)
+	}
+	if s.SampleInterval < 0 || s.HeartbeatInterval < 0 {
+		return nil, fmt.Errorf("invalid intervals of path %s", s.Path)
+	}
+
+	return sub, nil
+}
+
+// subscribeRequest builds the STREAM mode request sent to every target.
+func subscribeRequest(subs []Subscription, encoding gnmi.Encoding) (*gnmi.SubscribeRequest, error) {
+	if len(subs) == 0 {
+		return nil, fmt.Errorf("no subscriptions")
+	}
+	list := &gnmi.SubscriptionList{
+		Mode:     gnmi.SubscriptionList_STREAM,
+		Encoding: encoding,
+	}
+	for _, s := range subs {
+		sub, err := s.toProto()
+		if err != nil {
+			return nil, err
+		}
+		list.Subscription = append(list.Subscription, sub)
+	}
+
+	return &gnmi.SubscribeRequest{Request: &gnmi.SubscribeRequest_Subscribe{Subscribe: list}}, nil
+}
+
+// ParsePath parses a path in the gNMI path string notation, elements are separated by "/" and
+// keys follow the element name as [name=value]. A "\" escapes the next character.
+func ParsePath(s string) (*gnmi.Path, error) {
+	path := &gnmi.Path{}
+	s = strings.TrimPrefix(s, "/")
+	if s == "" {
+		return path, nil
+	}
+	var elem *gnmi.PathElem
+	var buf strings.Builder
+	var key string
+	inKey, inValue := false, false
+	endElem := func() error {
+		if buf.Len() == 0 && elem == nil {
+			return fmt.Errorf("empty element in path %q", s)
+		}
+		if elem == nil {
+			elem = &gnmi.PathElem{Name: buf.String()}
+		}
+		path.Elem = append(path.Elem, elem)
+		elem = nil
+		buf.Reset()
+		return nil
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			if i+1 == len(s) {
+				return nil, fmt.Errorf("trailing escape in path %q", s)
+			}
+			i++
+			buf.WriteByte(s[i])
+		case inValue:
+			if c == ']' {
+				elem.Key[key] = buf.String()
+				buf.Reset()
+				inValue = false
+				continue
+			}
+			buf.WriteByte(c)
+		case inKey:
+			if c == '=' {
+				key = buf.String()
+				if key == "" {
+					return nil, fmt.Errorf("empty key name in path %q", s)
+				}
+				buf.Reset()
+				inKey, inValue = false, true
+				continue
+			}
+			if c == ']' {
+				return nil, fmt.Errorf("key without value in path %q", s)
+			}
+			buf.WriteByte(c)
+		case c == '[':
+			if elem == nil {
+				if buf.Len() == 0 {
+					return nil, fmt.Errorf("key without element name in path %q", s)
+				}
+				elem = &gnmi.PathElem{Name: buf.String(), Key: map[string]string{}}
+				buf.Reset()
+			} else if buf.Len() != 0 {
+				return nil, fmt.Errorf("unexpected %q after key in path %q", buf.String(), s)
+			}
+			inKey = true
+		case c == '/':
+			if err := endElem(); err != nil {
+				return nil, err
+			}
+		default:
+			if elem != nil {
+				return nil, fmt.Errorf("unexpected %q after key in path %q", c, s)
+			}
+			buf.WriteByte(c)
+		}
+	}
+	if inKey || inValue {
+		return nil, fmt.Errorf("unterminated key in path %q", s)
+	}
+	if err := endElem(); err != nil {
+		return nil, err
+	}
+
+	return path, nil
+}
@@ -0,0 +1,324 @@
+package gpbkv
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+	"github.com/sbezverk/tools/telemetry_feeder/proto/telemetry"
+	"google.golang.org/protobuf/proto"
+)
+
+// Keys of the document produced for a telemetry message, they follow the layout of the
+// JSON encoding routers use, so both encodings can be handled by the same code.
+const (
+	KeyNodeID              = "node_id_str"
+	KeySubscription        = "subscription_id_str"
+	KeyEncodingPath        = "encoding_path"
+	KeyCollectionID        = "collection_id"
+	KeyCollectionStartTime = "collection_start_time"
+	KeyCollectionEndTime   = "collection_end_time"
+	KeyMsgTimestamp        = "msg_timestamp"
+	KeyData                = "data_json"
+	KeyTimestamp           = "timestamp"
+	KeyKeys                = "keys"
+	KeyContent             = "content"
+)
+
+// TelemetryToMap converts a GPB-KV telemetry message into the map form of the JSON encoding,
+// every data_gpbkv row becomes a data_json entry with its timestamp, keys and content.
+func TelemetryToMap(msg *telemetry.Telemetry) map[string]any {
+	doc := map[string]any{
+		KeyNodeID:              msg.GetNodeIdStr(),
+		KeySubscription:        msg.GetSubscriptionIdStr(),
+		KeyEncodingPath:        msg.GetEncodingPath(),
+		KeyCollectionID:        msg.GetCollectionId(),
+		KeyCollectionStartTime: msg.GetCollectionStartTime(),
+		KeyCollectionEndTime:   msg.GetCollectionEndTime(),
+		KeyMsgTimestamp:        msg.GetMsgTimestamp(),
+	}
+	rows := make([]any, 0, len(msg.GetDataGpbkv()))
+	for _, row := range msg.GetDataGpbkv() {
+		rows = append(rows, RowToMap(row))
+	}
+	doc[KeyData] = rows
+
+	return doc
+}
+
+// RowToMap converts a single data_gpbkv row, the keys and content subtrees are kept apart
+// and the row timestamp is preserved.
+func RowToMap(row *telemetry.TelemetryField) map[string]any {
+	m := map[string]any{
+		KeyTimestamp: row.GetTimestamp(),
+		KeyKeys:      map[string]any{},
+		KeyContent:   map[string]any{},
+	}
+	for _, f := range row.GetFields() {
+		switch f.GetName() {
+		case KeyKeys, KeyContent:
+			m[f.GetName()] = FieldsToMap(f.GetFields())
+		default:
+			// Not a keys/content split row, keep whatever is there.
+			addValue(m, f.GetName(), fieldValue(f))
+		}
+	}
+
+	return m
+}
+
+// FieldsToMap converts a list of sibling fields into a map, fields sharing a name, i.e. list
+// entries, are collected into a []any in the order they were received.
+func FieldsToMap(fields []*telemetry.TelemetryField) map[string]any {
+	m := make(map[string]any, len(fields))
+	for _, f := range fields {
+		addValue(m, f.GetName(), fieldValue(f))
+	}
+
+	return m
+}
+
+// TelemetryToJSON returns the JSON encoding of TelemetryToMap, map keys are sorted and
+// bytes values are base64 encoded.
+func TelemetryToJSON(msg *telemetry.Telemetry) ([]byte, error) {
+	return json.Marshal(TelemetryToMap(msg))
+}
+
+// ConvertFeed unmarshals the GPB-KV payload of the feed and returns a copy of the feed
+// carrying the JSON encoding of the message instead.
+func ConvertFeed(f *feeder.Feed) (*feeder.Feed, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	if f.Encoding != feeder.EncodingGPB {
+		return nil, fmt.Errorf("feed encoding is %q, expected %q", f.Encoding, feeder.EncodingGPB)
+	}
+	msg := &telemetry.Telemetry{}
+	if err := proto.Unmarshal(f.TelemetryMsg, msg); err != nil {
+		return nil, fmt.Errorf("%w: %v", feeder.ErrUnmarshalTelemetryMsg, err)
+	}
+	if msg.GetDataGpb() != nil {
+		return nil, fmt.Errorf("telemetry message for %s is compact GPB, not GPB-KV", msg.GetEncodingPath())
+	}
+	b, err := TelemetryToJSON(msg)
+	if err != nil {
+		return nil, err
+	}
+	converted := *f
+	converted.TelemetryMsg = b
+	converted.Encoding = feeder.EncodingJSON
+
+	return &converted, nil
+}
+
+func fieldValue(f *telemetry.TelemetryField) any {
+	switch v := f.GetValueByType().(type) {
+	case *telemetry.TelemetryField_BytesValue:
+		return v.BytesValue
+	case *telemetry.TelemetryField_StringValue:
+		return v.StringValue
+	case *telemetry.TelemetryField_BoolValue:
+		return v.BoolValue
+	case *telemetry.TelemetryField_Uint32Value:
+		return v.Uint32Value
+	case *telemetry.TelemetryField_Uint64Value:
+		return v.Uint64Value
+	case *telemetry.TelemetryField_Sint32Value:
+		return v.Sint32Value
+	case *telemetry.TelemetryField_Sint64Value:
+		return v.Sint64Value
+	case *telemetry.TelemetryField_DoubleValue:
+		return v.DoubleValue
+	case *telemetry.TelemetryField_FloatValue:
+		return v.FloatValue
+	}
+	// No value, the field is a container.
+	return FieldsToMap(f.GetFields())
+}
+
+func addValue(m map[string]any, name string, v any) {
+	existing, ok := m[name]
+	if !ok {
+		m[name] = v
+		return
+	}
+	if l, ok := existing.([]any); ok {
+		m[name] = append(l, v)
+		return
+	}
+	m[name] = []any{existing, v}
+}
+
+// MapToTelemetry is the reverse of TelemetryToMap, it is meant for building GPB-KV test
+// fixtures. Leaf types follow the Go type of the value, see MapToFields.
+func MapToTelemetry(doc map[string]any) (*telemetry.Telemetry, error) {
+	msg := &telemetry.Telemetry{}
+	var err error
+	if s, ok := doc[KeyNodeID].(string); ok && s != "" {
+		msg.NodeId = &telemetry.Telemetry_NodeIdStr{NodeIdStr: s}
+	}
+	if s, ok := doc[KeySubscription].(string); ok && s != "" {
+		msg.Subscription = &telemetry.Telemetry_SubscriptionIdStr{SubscriptionIdStr: s}
+	}
+	msg.EncodingPath, _ = doc[KeyEncodingPath].(string)
+	if msg.CollectionId, err = toUint64(doc[KeyCollectionID]); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", KeyCollectionID, err)
+	}
+	if msg.CollectionStartTime, err = toUint64(doc[KeyCollectionStartTime]); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", KeyCollectionStartTime, err)
+	}
+	if msg.CollectionEndTime, err = toUint64(doc[KeyCollectionEndTime]); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", KeyCollectionEndTime, err)
+	}
+	if msg.MsgTimestamp, err = toUint64(doc[KeyMsgTimestamp]); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", KeyMsgTimestamp, err)
+	}
+	rows, ok := doc[KeyData].([]any)
+	if !ok && doc[KeyData] != nil {
+		return nil, fmt.Errorf("%s must be a list, got %T", KeyData, doc[KeyData])
+	}
+	for i, r := range rows {
+		rm, ok := r.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s[%d] must be a map, got %T", KeyData, i, r)
+		}
+		row, err := MapToRow(rm)
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d]: %w", KeyData, i, err)
+		}
+		msg.DataGpbkv = append(msg.DataGpbkv, row)
+	}
+
+	return msg, nil
+}
+
+// MapToRow is the reverse of RowToMap.
+func MapToRow(m map[string]any) (*telemetry.TelemetryField, error) {
+	row := &telemetry.TelemetryField{}
+	var err error
+	if row.Timestamp, err = toUint64(m[KeyTimestamp]); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", KeyTimestamp, err)
+	}
+	rest := make(map[string]any, len(m))
+	for k, v := range m {
+		if k != KeyTimestamp {
+			rest[k] = v
+		}
+	}
+	if row.Fields, err = MapToFields(rest); err != nil {
+		return nil, err
+	}
+
+	return row, nil
+}
+
+// MapToFields converts a map into sibling fields sorted by name. Nested maps become
+// containers, []any becomes repeated fields with the same name, leaves are typed after the
+// Go value: []byte, string, bool, uint32, uint64, int32 (sint32), int and int64 (sint64),
+// float32 and float64 (double), json.Number is stored as sint64 when integral, double otherwise.
+func MapToFields(m map[string]any) ([]*telemetry.TelemetryField, error) {
+	names := make([]string, 0, len(m))
+	for k := range m {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	fields := make([]*telemetry.TelemetryField, 0, len(m))
+	for _, name := range names {
+		if l, ok := m[name].([]any); ok {
+			for _, v := range l {
+				f, err := valueToField(name, v)
+				if err != nil {
+					return nil, err
+				}
+				fields = append(fields, f)
+			}
+			continue
+		}
+		f, err := valueToField(name, m[name])
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+
+	return fields, nil
+}
+
+func valueToField(name string, v any) (*telemetry.TelemetryField, error) {
+	f := &telemetry.TelemetryField{Name: name}
+	switch v := v.(type) {
+	case map[string]any:
+		fields, err := MapToFields(v)
+		if err != nil {
+			return nil, err
+		}
+		f.Fields = fields
+	case []byte:
+		f.ValueByType = &telemetry.TelemetryField_BytesValue{BytesValue: v}
+	case string:
+		f.ValueByType = &telemetry.TelemetryField_StringValue{StringValue: v}
+	case bool:
+		f.ValueByType = &telemetry.TelemetryField_BoolValue{BoolValue: v}
+	case uint32:
+		f.ValueByType = &telemetry.TelemetryField_Uint32Value{Uint32Value: v}
+	case uint64:
+		f.ValueByType = &telemetry.TelemetryField_Uint64Value{Uint64Value: v}
+	case int32:
+		f.ValueByType = &telemetry.TelemetryField_Sint32Value{Sint32Value: v}
+	case int:
+		f.ValueByType = &telemetry.TelemetryField_Sint64Value{Sint64Value: int64(v)}
+	case int64:
+		f.ValueByType = &telemetry.TelemetryField_Sint64Value{Sint64Value: v}
+	case float32:
+		f.ValueByType = &telemetry.TelemetryField_FloatValue{FloatValue: v}
+	case float64:
+		f.ValueByType = &telemetry.TelemetryField_DoubleValue{DoubleValue: v}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			f.ValueByType = &telemetry.TelemetryField_Sint64Value{Sint64Value: i}
+			break
+		}
+		d, err := v.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("field %s: invalid number %q", name, v)
+		}
+		f.ValueByType = &telemetry.TelemetryField_DoubleValue{DoubleValue: d}
+	case nil:
+	default:
+		return nil, fmt.Errorf("field %s: unsupported value type %T", name, v)
+	}
+
+	return f, nil
+}
+
+func toUint64(v any) (uint64, error) {
+	switch v := v.(type) {
+	case nil:
+		return 0, nil
+	case uint64:
+		return v, nil
+	case uint32:
+		return uint64(v), nil
+	case int:
+		if v < 0 {
+			return 0, fmt.Errorf("negative value %d", v)
+		}
+		return uint64(v), nil
+	case int64:
+		if v < 0 {
+			return 0, fmt.Errorf("negative value %d", v)
+		}
+		return uint64(v), nil
+	case float64:
+		if v < 0 || v != math.Trunc(v) {
+			return 0, fmt.Errorf("value %v is not an unsigned integer", v)
+		}
+		return uint64(v), nil
+	case json.Number:
+		return strconv.ParseUint(v.String(), 10, 64)
+	}
+	return 0, fmt.Errorf("unsupported type %T", v)
+}
@@ -0,0 +1,166 @@
+package gpbkv
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/go-test/deep"
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+	"github.com/sbezverk/tools/telemetry_feeder/proto/telemetry"
+	"google.golang.org/protobuf/proto"
+)
+
+func testTelemetry() *telemetry.Telemetry {
+	return &telemetry.Telemetry{
+		NodeId:              &telemetry.Telemetry_NodeIdStr{NodeIdStr: "xrv9k-1"},
+		Subscription:        &telemetry.Telemetry_SubscriptionIdStr{SubscriptionIdStr: "ifstats"},
+		EncodingPath:        "Cisco-IOS-XR-infra-statsd-oper:infra-statistics/interfaces/interface/latest/generic-counters",
+		CollectionId:        11,
+		CollectionStartTime: 1700000000000,
+		MsgTimestamp:        1700000000050,
+		DataGpbkv: []*telemetry.TelemetryField{
+			{
+				Timestamp: 1700000000010,
+				Fields: []*telemetry.TelemetryField{
+					{Name: "keys", Fields: []*telemetry.TelemetryField{
+						{Name: "interface-name", ValueByType: &telemetry.TelemetryField_StringValue{StringValue: "Gi0/0/0/0"}},
+					}},
+					{Name: "content", Fields: []*telemetry.TelemetryField{
+						{Name: "packets-received", ValueByType: &telemetry.TelemetryField_Uint64Value{Uint64Value: 18446744073709551615}},
+						{Name: "crc-errors", ValueByType: &telemetry.TelemetryField_Uint32Value{Uint32Value: 3}},
+						{Name: "mac", ValueByType: &telemetry.TelemetryField_BytesValue{BytesValue: []byte{0xde, 0xad}}},
+						{Name: "up", ValueByType: &telemetry.TelemetryField_BoolValue{BoolValue: true}},
+						{Name: "rate", ValueByType: &telemetry.TelemetryField_DoubleValue{DoubleValue: 1.5}},
+						{Name: "address", Fields: []*telemetry.TelemetryField{
+							{Name: "ip", ValueByType: &telemetry.TelemetryField_StringValue{StringValue: "10.0.0.1"}},
+						}},
+						{Name: "address", Fields: []*telemetry.TelemetryField{
+							{Name: "ip", ValueByType: &telemetry.TelemetryField_StringValue{StringValue: "10.0.0.2"}},
+						}},
+					}},
+				},
+			},
+		},
+	}
+}
+
+func TestTelemetryToMap(t *testing.T) {
+	doc := TelemetryToMap(testTelemetry())
+
+	want := map[string]any{
+		KeyNodeID:              "xrv9k-1",
+		KeySubscription:        "ifstats",
+		KeyEncodingPath:        "Cisco-IOS-XR-infra-statsd-oper:infra-statistics/interfaces/interface/latest/generic-counters",
+		KeyCollectionID:        uint64(11),
+		KeyCollectionStartTime: uint64(1700000000000),
+		KeyCollectionEndTime:   uint64(0),
+		KeyMsgTimestamp:        uint64(1700000000050),
+		KeyData: []any{
+			map[string]any{
+				KeyTimestamp: uint64(1700000000010),
+				KeyKeys:      map[string]any{"interface-name": "Gi0/0/0/0"},
+				KeyContent: map[string]any{
+					"packets-received": uint64(18446744073709551615),
+					"crc-errors":       uint32(3),
+					"mac":              []byte{0xde, 0xad},
+					"up":               true,
+					"rate":             1.5,
+					"address": []any{
+						map[string]any{"ip": "10.0.0.1"},
+						map[string]any{"ip": "10.0.0.2"},
+					},
+				},
+			},
+		},
+	}
+	if diff := deep.Equal(doc, want); diff != nil {
+		t.Fatalf("map mismatch: %v", diff)
+	}
+}
+
+func TestTelemetryToJSON(t *testing.T) {
+	b, err := TelemetryToJSON(testTelemetry())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		`"packets-received":18446744073709551615`,
+		`"mac":"3q0="`,
+		`"timestamp":1700000000010`,
+		`"keys":{"interface-name":"Gi0/0/0/0"}`,
+	} {
+		if !bytes.Contains(b, []byte(want)) {
+			t.Fatalf("expected %s in %s", want, b)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	msg := testTelemetry()
+	back, err := MapToTelemetry(TelemetryToMap(msg))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The reverse direction sorts sibling fields by name, compare the map forms.
+	if diff := deep.Equal(TelemetryToMap(back), TelemetryToMap(msg)); diff != nil {
+		t.Fatalf("round trip mismatch: %v", diff)
+	}
+}
+
+func TestMapToTelemetryFromJSON(t *testing.T) {
+	d := json.NewDecoder(bytes.NewReader([]byte(`{"node_id_str":"r1","encoding_path":"p","collection_id":5,
+		"data_json":[{"timestamp":1700000000000,"keys":{"name":"eth0"},"content":{"mtu":1500,"ratio":0.5}}]}`)))
+	d.UseNumber()
+	var doc map[string]any
+	if err := d.Decode(&doc); err != nil {
+		t.Fatalf("failed to decode fixture: %v", err)
+	}
+	msg, err := MapToTelemetry(doc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msg.GetNodeIdStr() != "r1" || msg.GetCollectionId() != 5 || len(msg.GetDataGpbkv()) != 1 {
+		t.Fatalf("unexpected telemetry %+v", msg)
+	}
+	content := msg.GetDataGpbkv()[0].GetFields()[0]
+	if content.GetName() != KeyContent {
+		t.Fatalf("expected content first, got %s", content.GetName())
+	}
+	if content.GetFields()[0].GetSint64Value() != 1500 || content.GetFields()[1].GetDoubleValue() != 0.5 {
+		t.Fatalf("unexpected content fields %+v", content.GetFields())
+	}
+	if _, err := MapToTelemetry(map[string]any{KeyData: []any{map[string]any{"bad": struct{}{}}}}); err == nil {
+		t.Fatal("expected error for unsupported value type")
+	}
+}
+
+func TestConvertFeed(t *testing.T) {
+	b, err := proto.Marshal(testTelemetry())
+	if err != nil {
+		t.Fatalf("failed to marshal telemetry: %v", err)
+	}
+	f := &feeder.Feed{TelemetryMsg: b, Transport: feeder.TransportGRPC, Encoding: feeder.EncodingGPB, Framing: feeder.FramingNone}
+	got, err := ConvertFeed(f)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Encoding != feeder.EncodingJSON || got.Transport != feeder.TransportGRPC {
+		t.Fatalf("unexpected converted feed %+v", got)
+	}
+	if f.Encoding != feeder.EncodingGPB {
+		t.Fatal("original feed must not be modified")
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(got.TelemetryMsg, &doc); err != nil {
+		t.Fatalf("converted payload is not JSON: %v", err)
+	}
+	if doc[KeyNodeID] != "xrv9k-1" {
+		t.Fatalf("unexpected node id %v", doc[KeyNodeID])
+	}
+
+	compact, _ := proto.Marshal(&telemetry.Telemetry{DataGpb: &telemetry.TelemetryGPBTable{}})
+	if _, err := ConvertFeed(&feeder.Feed{TelemetryMsg: compact, Encoding: feeder.EncodingGPB}); err == nil {
+		t.Fatal("expected error for compact GPB feed")
+	}
+}
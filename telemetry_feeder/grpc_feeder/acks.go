@@ -0,0 +1,93 @@
+package grpc_feeder
+
+import (
+	"fmt"
+
+	"github.com/sbezverk/tools/telemetry_feeder/proto/mdtdialout"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ackQueueCapacity is the number of replies waiting to be sent to a producer, replies beyond
+// it are dropped rather than stalling the session.
+const ackQueueCapacity = 1024
+
+// AckMode selects which received MdtDialoutArgs are answered on the response half of the
+// MdtDialout stream. Replies echo the ReqId of the message, Errors is set for messages the
+// collector failed to process.
+type AckMode string
+
+const (
+	// AckNone never replies, the default.
+	AckNone AckMode = "none"
+	// AckErrors replies to the messages which were dropped or could not be decoded.
+	AckErrors AckMode = "errors"
+	// AckAll replies to every message, with empty Errors when it was accepted.
+	AckAll AckMode = "all"
+)
+
+func (m AckMode) validate() error {
+	switch m {
+	case AckNone, AckErrors, AckAll:
+		return nil
+	}
+	return fmt.Errorf("unknown ack mode %q", m)
+}
+
+// validatePayload checks that the payload is a well formed protobuf message, the fields are
+// not decoded.
+func validatePayload(b []byte) error {
+	if len(b) == 0 {
+		return fmt.Errorf("empty payload")
+	}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+// sendAcks sends the replies queued on acks until the channel is closed or sending fails, the
+// session handler waits for it before returning. Once the feeder is stopped the remaining
+// replies are dropped, and a send blocked by a producer not reading replies returns when Stop
+// closes the connection.
+func (srv *grpcSrv) sendAcks(session mdtdialout.GRPCMdtDialout_MdtDialoutServer, acks <-chan *mdtdialout.MdtDialoutArgs) {
+	for ack := range acks {
+		select {
+		case <-srv.stopCh:
+			srv.acksFailedTotal.Add(1)
+			continue
+		default:
+		}
+		if err := session.Send(ack); err != nil {
+			srv.acksFailedTotal.Add(1)
+			for range acks {
+				srv.acksFailedTotal.Add(1)
+			}
+			return
+		}
+		srv.acksSentTotal.Add(1)
+		if ack.GetErrors() != "" {
+			srv.ackErrorsSentTotal.Add(1)
+		}
+	}
+}
+
+// ack queues the reply to the message reqID, errMsg is empty for an accepted message.
+func (srv *grpcSrv) ack(acks chan<- *mdtdialout.MdtDialoutArgs, reqID int64, errMsg string) {
+	if srv.acks == AckNone || (srv.acks == AckErrors && errMsg == "") {
+		return
+	}
+	select {
+	case acks <- &mdtdialout.MdtDialoutArgs{ReqId: reqID, Errors: errMsg}:
+	default:
+		srv.acksFailedTotal.Add(1)
+	}
+}
@@ -0,0 +1,141 @@
+package grpc_feeder
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+	"github.com/sbezverk/tools/telemetry_feeder/proto/mdtdialout"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestAcks(t *testing.T) {
+	fdr, err := NewWithOptions("127.0.0.1:0",
+		WithAcks(AckAll),
+		WithQueueCapacity(1),
+		WithOverflow(feeder.OverflowConfig{Policy: feeder.OverflowDropNewest}),
+		WithLogger(feeder.NopLogger()))
+	if err != nil {
+		t.Fatalf("failed to create feeder: %v", err)
+	}
+	defer fdr.Stop()
+	addr := fdr.(*grpcSrv).conn.Addr().String()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	cc, err := grpc.DialContext(ctx, addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer cc.Close()
+	stream, err := mdtdialout.NewGRPCMdtDialoutClient(cc).MdtDialout(ctx)
+	if err != nil {
+		t.Fatalf("failed to open stream: %v", err)
+	}
+	// The first message fills the queue, the second is dropped and the third is malformed.
+	for i, data := range [][]byte{{0x08, 0x01}, {0x08, 0x02}, {0x0a, 0x05}} {
+		if err := stream.Send(&mdtdialout.MdtDialoutArgs{ReqId: int64(i + 1), Data: data}); err != nil {
+			t.Fatalf("failed to send: %v", err)
+		}
+	}
+	for i, want := range []string{"", "queue is full", "failed to decode"} {
+		reply, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("failed to receive reply %d: %v", i+1, err)
+		}
+		if reply.GetReqId() != int64(i+1) || (want == "") != (reply.GetErrors() == "") || !strings.Contains(reply.GetErrors(), want) {
+			t.Fatalf("unexpected reply %d: %v", i+1, reply)
+		}
+	}
+	if item := <-fdr.GetFeed(); item.Err != nil || item.TelemetryMsg[1] != 0x01 {
+		t.Fatalf("unexpected item %+v", item)
+	}
+
+	// The sender counts a reply once Send returns, which may be after the client received it.
+	var stats Stats
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		b, err := fdr.GetStatsJson()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := json.Unmarshal(b, &stats); err != nil {
+			t.Fatalf("failed to decode stats: %v", err)
+		}
+		if stats.AcksSentTotal == 3 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if stats.AcksSentTotal != 3 || stats.AckErrorsSentTotal != 2 || stats.AcksFailedTotal != 0 || stats.MessagesReceivedTotal != 3 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestAcksFlushedOnSessionEnd(t *testing.T) {
+	const n = 500
+	fdr, err := NewWithOptions("127.0.0.1:0", WithAcks(AckAll), WithQueueCapacity(2*n), WithLogger(feeder.NopLogger()))
+	if err != nil {
+		t.Fatalf("failed to create feeder: %v", err)
+	}
+	defer fdr.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	cc, err := grpc.DialContext(ctx, fdr.(*grpcSrv).conn.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer cc.Close()
+	stream, err := mdtdialout.NewGRPCMdtDialoutClient(cc).MdtDialout(ctx)
+	if err != nil {
+		t.Fatalf("failed to open stream: %v", err)
+	}
+	for i := 0; i < n; i++ {
+		if err := stream.Send(&mdtdialout.MdtDialoutArgs{ReqId: int64(i + 1), Data: []byte{0x08, 0x01}}); err != nil {
+			t.Fatalf("failed to send: %v", err)
+		}
+	}
+	// Ending the session right away leaves most replies queued when the handler returns.
+	if err := stream.CloseSend(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+	replies := 0
+	for {
+		reply, err := stream.Recv()
+		if err != nil {
+			break
+		}
+		replies++
+		if reply.GetReqId() != int64(replies) {
+			t.Fatalf("unexpected reply %d: %v", replies, reply)
+		}
+	}
+	if replies != n {
+		t.Fatalf("expected %d replies before the end of the session, got %d", n, replies)
+	}
+}
+
+func TestValidatePayload(t *testing.T) {
+	for _, tt := range []struct {
+		data  []byte
+		valid bool
+	}{
+		{data: []byte{0x08, 0x96, 0x01, 0x12, 0x01, 'a'}, valid: true},
+		{data: nil},
+		{data: []byte{0x08}},
+		{data: []byte{0x12, 0x05, 'a'}},
+		{data: []byte("data")},
+	} {
+		if err := validatePayload(tt.data); (err == nil) != tt.valid {
+			t.Fatalf("payload %x: expected valid %t, got %v", tt.data, tt.valid, err)
+		}
+	}
+	if f, err := NewWithOptions("127.0.0.1:0", WithAcks("sometimes")); err == nil {
+		f.Stop()
+		t.Fatal("expected unknown ack mode to be rejected")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -14,7 +15,6 @@ import (
 	"github.com/sbezverk/tools/telemetry_feeder/proto/mdtdialout"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
@@ -29,58 +29,134 @@ type grpcSrv struct {
 	conn                        net.Listener
 	gSrv                        *grpc.Server
 	stopCh                      chan struct{}
-	feed                        chan *feeder.Feed
+	stopOnce                    sync.Once
+	drainCh                     chan struct{}
+	drainOnce                   sync.Once
+	mu                          sync.Mutex
+	draining                    bool
+	sessions                    sync.WaitGroup
+	queue                       *feeder.FeedQueue
+	events                      *feeder.EventQueue
+	producers                   *feeder.ProducerTracker
+	pool                        *feeder.BufferPool
+	acks                        AckMode
+	logger                      feeder.Logger
 	startTime                   time.Time
 	messagesReceivedTotal       atomic.Int64
 	payloadBytesReceivedTotal   atomic.Int64
 	transportBytesReceivedTotal atomic.Int64
-	feedItemsEnqueuedTotal      atomic.Int64
-	feedErrorItemsEnqueuedTotal atomic.Int64
-	feedQueueDepthMax           atomic.Int64
-	feedPublishBlockNanosTotal  atomic.Int64
-	feedPublishBlockNanosMax    atomic.Int64
 	receiveErrorsTotal          atomic.Int64
 	receiveTimeoutErrorsTotal   atomic.Int64
 	receiveClosedTotal          atomic.Int64
 	receiveOtherErrorsTotal     atomic.Int64
+	acksSentTotal               atomic.Int64
+	ackErrorsSentTotal          atomic.Int64
+	acksFailedTotal             atomic.Int64
 	mdtdialout.UnimplementedGRPCMdtDialoutServer
 }
 
+// Stats are the gRPC feeder counters returned by GetStatsJson. AcksFailedTotal counts the
+// replies which were dropped or failed to send.
+type Stats struct {
+	feeder.StatsSnapshot
+	AcksSentTotal      int64 `json:"acks_sent_total"`
+	AckErrorsSentTotal int64 `json:"ack_errors_sent_total"`
+	AcksFailedTotal    int64 `json:"acks_failed_total"`
+}
+
 func (srv *grpcSrv) GetFeed() chan *feeder.Feed {
-	return srv.feed
+	return srv.queue.Feed()
+}
+
+func (srv *grpcSrv) Events() <-chan *feeder.Event {
+	return srv.events.Events()
+}
+
+// Stopped is closed once the server is stopped, by Stop or when Serve fails.
+func (srv *grpcSrv) Stopped() <-chan struct{} {
+	return srv.stopCh
 }
 
 func (srv *grpcSrv) Stop() {
-	srv.gSrv.Stop()
-	close(srv.stopCh)
-	srv.conn.Close()
+	srv.stopOnce.Do(func() {
+		// Sessions blocked on a full queue return once stopCh is closed.
+		close(srv.stopCh)
+		srv.gSrv.Stop()
+		srv.conn.Close()
+	})
+}
+
+// StopGracefully refuses new sessions and ends the current ones after the message being
+// processed, then waits for the consumer to take the queued items and closes the feed channel.
+func (srv *grpcSrv) StopGracefully(ctx context.Context) error {
+	srv.mu.Lock()
+	srv.draining = true
+	srv.mu.Unlock()
+	srv.drainOnce.Do(func() {
+		close(srv.drainCh)
+	})
+	ended := make(chan struct{})
+	go func() {
+		// Closes the listener, sends GOAWAY to producers and waits for the sessions to end.
+		srv.gSrv.GracefulStop()
+		close(ended)
+	}()
+	var err error
+	select {
+	case <-ended:
+		err = srv.queue.Drain(ctx)
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	srv.Stop()
+	<-ended
+	srv.sessions.Wait()
+	srv.queue.Close()
+	srv.events.Close()
+
+	return err
+}
+
+// startSession registers a new session unless the feeder is being stopped gracefully, the feed
+// channel is closed only once every registered session ended.
+func (srv *grpcSrv) startSession() bool {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.draining {
+		return false
+	}
+	srv.sessions.Add(1)
+
+	return true
 }
 
 func (srv *grpcSrv) statsSnapshot() feeder.StatsSnapshot {
-	return feeder.StatsSnapshot{
+	snapshot := feeder.StatsSnapshot{
 		Transport:                   "grpc",
 		StartTime:                   srv.startTime.UTC(),
 		UptimeSeconds:               int64(time.Since(srv.startTime).Seconds()),
 		MessagesReceivedTotal:       srv.messagesReceivedTotal.Load(),
 		PayloadBytesReceivedTotal:   srv.payloadBytesReceivedTotal.Load(),
 		TransportBytesReceivedTotal: srv.transportBytesReceivedTotal.Load(),
-		FeedItemsEnqueuedTotal:      srv.feedItemsEnqueuedTotal.Load(),
-		FeedErrorItemsEnqueuedTotal: srv.feedErrorItemsEnqueuedTotal.Load(),
-		FeedQueueDepth:              int64(len(srv.feed)),
-		FeedQueueDepthMax:           srv.feedQueueDepthMax.Load(),
-		FeedQueueCapacity:           int64(cap(srv.feed)),
-		FeedPublishBlockNanosTotal:  srv.feedPublishBlockNanosTotal.Load(),
-		FeedPublishBlockNanosMax:    srv.feedPublishBlockNanosMax.Load(),
 		ReceiveErrorsTotal:          srv.receiveErrorsTotal.Load(),
 		ReceiveTimeoutErrorsTotal:   srv.receiveTimeoutErrorsTotal.Load(),
 		ReceiveClosedTotal:          srv.receiveClosedTotal.Load(),
 		ReceiveOtherErrorsTotal:     srv.receiveOtherErrorsTotal.Load(),
 	}
+	srv.queue.FillStats(&snapshot)
+	srv.producers.FillStats(&snapshot)
+	srv.events.FillStats(&snapshot)
+
+	return snapshot
 }
 
 func (srv *grpcSrv) GetStatsJson() ([]byte, error) {
-	snapshot := srv.statsSnapshot()
-	return json.Marshal(snapshot)
+	return json.Marshal(Stats{
+		StatsSnapshot:      srv.statsSnapshot(),
+		AcksSentTotal:      srv.acksSentTotal.Load(),
+		AckErrorsSentTotal: srv.ackErrorsSentTotal.Load(),
+		AcksFailedTotal:    srv.acksFailedTotal.Load(),
+	})
 }
 
 func classifyReceiveError(err error) string {
@@ -99,67 +175,93 @@ func classifyReceiveError(err error) string {
 	return "other"
 }
 
-func updateMax(max *atomic.Int64, value int64) {
-	for {
-		current := max.Load()
-		if value <= current || max.CompareAndSwap(current, value) {
-			return
-		}
-	}
+// failed checks if the session ended with an error of the producer, rather than being closed by it
+// or canceled.
+func failed(err error) bool {
+	return err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, context.Canceled) && status.Code(err) != codes.Canceled
 }
 
 func (srv *grpcSrv) publishFeed(item *feeder.Feed) bool {
-	// If stopCh is already closed, prevent publishing (even if the send would not block).
-	select {
-	case <-srv.stopCh:
-		return false
-	default:
+	return srv.queue.Publish(item)
+}
+
+func New(addr string) (feeder.Feeder, error) {
+	return NewWithOptions(addr)
+}
+
+// NewWithOverflow creates gRPC dial-out feeder with the feed queue overflow policy defined by cfg.
+func NewWithOverflow(addr string, cfg feeder.OverflowConfig) (feeder.Feeder, error) {
+	return NewWithOptions(addr, WithOverflow(cfg))
+}
+
+// NewWithOptions creates gRPC dial-out feeder, settings not customized by opts keep the
+// defaults used by New.
+func NewWithOptions(addr string, opts ...Option) (feeder.Feeder, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
 	}
-	queueDepthAfterSend := int64(len(srv.feed) + 1)
-	if queueDepthAfterSend > int64(cap(srv.feed)) {
-		queueDepthAfterSend = int64(cap(srv.feed))
+	if err := o.validate(); err != nil {
+		return nil, err
 	}
-	started := time.Now()
-	select {
-	case <-srv.stopCh:
-		return false
-	case srv.feed <- item:
-		blocked := time.Since(started).Nanoseconds()
-		srv.feedItemsEnqueuedTotal.Add(1)
-		if item.Err != nil {
-			srv.feedErrorItemsEnqueuedTotal.Add(1)
+	serverOpts := []grpc.ServerOption{
+		grpc.MaxRecvMsgSize(o.maxMsgSize),
+		grpc.KeepaliveParams(o.keepalive),
+	}
+	if o.enforcement != nil {
+		serverOpts = append(serverOpts, grpc.KeepaliveEnforcementPolicy(*o.enforcement))
+	}
+	if o.tls != nil {
+		reloader, err := newCertReloader(*o.tls, o.logger)
+		if err != nil {
+			return nil, err
 		}
-		srv.feedPublishBlockNanosTotal.Add(blocked)
-		updateMax(&srv.feedPublishBlockNanosMax, blocked)
-		updateMax(&srv.feedQueueDepthMax, queueDepthAfterSend)
-		return true
+		serverOpts = append(serverOpts, grpc.Creds(reloader.credentials()))
 	}
-}
-
-func New(addr string) (feeder.Feeder, error) {
 	conn, err := net.Listen("tcp", addr)
 	if err != nil {
 		return nil, err
 	}
+	stopCh := make(chan struct{})
+	queue, err := feeder.NewFeedQueue(o.queueCapacity, stopCh, o.overflow)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	events := feeder.NewEventQueue(feeder.TransportGRPC)
+	queue.SetEvents(events)
 
 	srv := &grpcSrv{
 		conn:      conn,
-		stopCh:    make(chan struct{}),
-		feed:      make(chan *feeder.Feed, feedQueueCapacity),
+		stopCh:    stopCh,
+		drainCh:   make(chan struct{}),
+		queue:     queue,
+		events:    events,
+		producers: feeder.NewProducerTracker(o.producers),
+		pool:      o.pool,
+		acks:      o.acks,
+		logger:    o.logger,
 		startTime: time.Now(),
-		gSrv: grpc.NewServer(
-			grpc.MaxRecvMsgSize(MaxRcvMsgSize),
-			grpc.KeepaliveParams(keepalive.ServerParameters{Time: time.Second * 30, Timeout: time.Second * 10}),
-		),
+		gSrv:      grpc.NewServer(serverOpts...),
 	}
 	mdtdialout.RegisterGRPCMdtDialoutServer(srv.gSrv, srv)
 
-	go srv.gSrv.Serve(conn)
+	go func() {
+		if err := srv.gSrv.Serve(conn); err != nil {
+			srv.logger.Errorf("gRPC dial-out server on %s failed with error: %+v", conn.Addr(), err)
+			// No session can be accepted anymore.
+			srv.Stop()
+		}
+	}()
 
 	return srv, nil
 }
 
 func (srv *grpcSrv) MdtDialout(session mdtdialout.GRPCMdtDialout_MdtDialoutServer) error {
+	if !srv.startSession() {
+		return status.Error(codes.Unavailable, "collector is shutting down")
+	}
+	defer srv.sessions.Done()
 	infoCh := make(chan *mdtdialout.MdtDialoutArgs)
 	errCh := make(chan error)
 
@@ -170,11 +272,39 @@ func (srv *grpcSrv) worker(session mdtdialout.GRPCMdtDialout_MdtDialoutServer,
 	infoCh chan *mdtdialout.MdtDialoutArgs,
 	errCh chan error) error {
 	var producer net.Addr
+	var subject string
 	if p, ok := peer.FromContext(session.Context()); ok {
 		producer = p.Addr
+		subject = peerSubject(p)
 	} else {
 		producer = &net.IPAddr{IP: net.ParseIP("0.0.0.0")}
 	}
+	srv.producers.SessionStarted(producer)
+	defer srv.producers.SessionEnded(producer)
+	srv.events.ProducerConnected(producer)
+	reason := feeder.ErrFeederStopped
+	defer func() {
+		srv.events.ProducerDisconnected(producer, reason)
+	}()
+	var acks chan *mdtdialout.MdtDialoutArgs
+	if srv.acks != AckNone {
+		acks = make(chan *mdtdialout.MdtDialoutArgs, ackQueueCapacity)
+		sent := make(chan struct{})
+		go func() {
+			defer close(sent)
+			srv.sendAcks(session, acks)
+		}()
+		// Replies cannot be sent once the handler returned, the queued ones are flushed first.
+		defer func() {
+			close(acks)
+			<-sent
+		}()
+	}
+	if subject != "" {
+		srv.logger.Infof("gRPC dial-out session from %s authenticated as %q started", producer, subject)
+	} else {
+		srv.logger.Infof("gRPC dial-out session from %s started", producer)
+	}
 	go func(iCh chan *mdtdialout.MdtDialoutArgs, eCh chan error) {
 		for {
 			info, err := session.Recv()
@@ -227,24 +357,56 @@ func (srv *grpcSrv) worker(session mdtdialout.GRPCMdtDialout_MdtDialoutServer,
 			if msg == nil {
 				continue
 			}
+			data := msg.GetData()
+			srv.messagesReceivedTotal.Add(1)
+			srv.payloadBytesReceivedTotal.Add(int64(len(data)))
+			srv.transportBytesReceivedTotal.Add(int64(proto.Size(msg)))
+			srv.producers.Received(producer, len(data), proto.Size(msg))
+			if srv.acks != AckNone {
+				// Payloads are checked only when the producer can be told about the failure.
+				if err := validatePayload(data); err != nil {
+					srv.ack(acks, msg.GetReqId(), fmt.Sprintf("collector failed to decode message: %v", err))
+					srv.producers.Error(producer)
+					err = fmt.Errorf("%w from peer %s: %v", feeder.ErrUnmarshalTelemetryMsg, producer.String(), err)
+					srv.events.FramingError(producer, err)
+					if !srv.publishFeed(&feeder.Feed{
+						ProducerAddr: producer,
+						Err:          err,
+						Transport:    feeder.TransportGRPC,
+						Encoding:     feeder.EncodingGPB,
+						Framing:      feeder.FramingNone,
+						PeerSubject:  subject,
+						ReceivedAt:   time.Now(),
+					}) {
+						return nil
+					}
+					continue
+				}
+			}
 			f := &feeder.Feed{
 				ProducerAddr: producer,
 				Transport:    feeder.TransportGRPC,
 				Encoding:     feeder.EncodingGPB,
 				Framing:      feeder.FramingNone,
+				PeerSubject:  subject,
+				ReceivedAt:   time.Now(),
 			}
-			data := msg.GetData()
-			f.TelemetryMsg = make([]byte, len(data))
-			copy(f.TelemetryMsg, data)
-			f.Err = nil
-			srv.messagesReceivedTotal.Add(1)
-			srv.payloadBytesReceivedTotal.Add(int64(len(data)))
-			srv.transportBytesReceivedTotal.Add(int64(proto.Size(msg)))
+			f.CopyTelemetryMsg(data, srv.pool)
 			// Sending recieved Telemetry message for processing
-			if !srv.publishFeed(f) {
+			switch srv.queue.PublishWithOutcome(f) {
+			case feeder.PublishStopped:
 				return nil
+			case feeder.PublishDropped:
+				srv.ack(acks, msg.GetReqId(), "collector feed queue is full, message dropped")
+			default:
+				srv.ack(acks, msg.GetReqId(), "")
 			}
 		case err := <-errCh:
+			srv.logger.Infof("gRPC dial-out session from %s ended: %v", producer, err)
+			if failed(err) {
+				srv.producers.Error(producer)
+			}
+			reason = err
 			if !srv.publishFeed(&feeder.Feed{
 				ProducerAddr: producer,
 				TelemetryMsg: nil,
@@ -252,10 +414,14 @@ func (srv *grpcSrv) worker(session mdtdialout.GRPCMdtDialout_MdtDialoutServer,
 				Transport:    feeder.TransportGRPC,
 				Encoding:     feeder.EncodingGPB,
 				Framing:      feeder.FramingNone,
+				PeerSubject:  subject,
 			}) {
 				return nil
 			}
 			return err
+		case <-srv.drainCh:
+			srv.logger.Infof("gRPC dial-out session from %s ended, collector is shutting down", producer)
+			return nil
 		case <-srv.stopCh:
 			return nil
 		}
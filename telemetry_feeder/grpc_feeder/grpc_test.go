@@ -0,0 +1,143 @@
+package grpc_feeder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+	"github.com/sbezverk/tools/telemetry_feeder/proto/mdtdialout"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// sendMessages opens a dial-out session and sends n messages, it returns once they are all queued.
+func sendMessages(t *testing.T, fdr feeder.Feeder, n int) mdtdialout.GRPCMdtDialout_MdtDialoutClient {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	cc, err := grpc.DialContext(ctx, fdr.(*grpcSrv).conn.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { cc.Close() })
+	stream, err := mdtdialout.NewGRPCMdtDialoutClient(cc).MdtDialout(ctx)
+	if err != nil {
+		t.Fatalf("failed to open stream: %v", err)
+	}
+	for i := 0; i < n; i++ {
+		if err := stream.Send(&mdtdialout.MdtDialoutArgs{ReqId: int64(i + 1), Data: []byte{0x08, byte(i)}}); err != nil {
+			t.Fatalf("failed to send: %v", err)
+		}
+	}
+	for deadline := time.Now().Add(5 * time.Second); len(fdr.GetFeed()) < n; {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d queued messages", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return stream
+}
+
+func TestStopGracefully(t *testing.T) {
+	fdr, err := NewWithOptions("127.0.0.1:0", WithLogger(feeder.NopLogger()))
+	if err != nil {
+		t.Fatalf("failed to create feeder: %v", err)
+	}
+	stream := sendMessages(t, fdr, 3)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- fdr.StopGracefully(ctx)
+	}()
+	var items []*feeder.Feed
+	for item := range fdr.GetFeed() {
+		items = append(items, item)
+	}
+	if err := <-stopped; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("expected the 3 queued messages, got %d items", len(items))
+	}
+	for i, item := range items {
+		if item.Err != nil || item.TelemetryMsg[1] != byte(i) {
+			t.Fatalf("unexpected item %d: %+v", i, item)
+		}
+	}
+	// The session is ended by the collector without an error.
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Fatalf("expected the session to end cleanly, got %v", err)
+	}
+	var events []*feeder.Event
+	for e := range fdr.(feeder.EventSource).Events() {
+		events = append(events, e)
+	}
+	if len(events) != 2 || events[0].Type != feeder.EventProducerConnected ||
+		events[1].Type != feeder.EventProducerDisconnected || !errors.Is(events[1].Err, feeder.ErrFeederStopped) {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	fdr.Stop()
+}
+
+func TestStopGracefullyDeadline(t *testing.T) {
+	fdr, err := NewWithOptions("127.0.0.1:0", WithLogger(feeder.NopLogger()))
+	if err != nil {
+		t.Fatalf("failed to create feeder: %v", err)
+	}
+	sendMessages(t, fdr, 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := fdr.StopGracefully(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline error without a consumer, got %v", err)
+	}
+	n := 0
+	for range fdr.GetFeed() {
+		n++
+	}
+	if n != 2 {
+		t.Fatalf("expected the 2 queued items before the end of the feed, got %d", n)
+	}
+}
+
+func TestSessionClosedByProducer(t *testing.T) {
+	fdr, err := NewWithOptions("127.0.0.1:0", WithLogger(feeder.NopLogger()))
+	if err != nil {
+		t.Fatalf("failed to create feeder: %v", err)
+	}
+	defer fdr.Stop()
+	stream := sendMessages(t, fdr, 1)
+	if err := stream.CloseSend(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+	if item := <-fdr.GetFeed(); item.Err != nil {
+		t.Fatalf("unexpected item %+v", item)
+	}
+	if item := <-fdr.GetFeed(); !errors.Is(item.Err, io.EOF) {
+		t.Fatalf("expected the session to end cleanly, got %+v", item)
+	}
+	b, err := fdr.GetStatsJson()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var stats Stats
+	if err := json.Unmarshal(b, &stats); err != nil {
+		t.Fatalf("failed to decode stats: %v", err)
+	}
+	// A session closed by the producer is not an error of the producer.
+	if len(stats.Producers) != 1 {
+		t.Fatalf("unexpected producers %+v", stats.Producers)
+	}
+	for addr, p := range stats.Producers {
+		if p.MessagesReceivedTotal != 1 || p.ReceiveErrorsTotal != 0 {
+			t.Fatalf("unexpected stats of producer %s: %+v", addr, p)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package grpc_feeder
+
+import (
+	"fmt"
+	"time"
+
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+	"google.golang.org/grpc/keepalive"
+)
+
+type options struct {
+	queueCapacity int
+	maxMsgSize    int
+	keepalive     keepalive.ServerParameters
+	enforcement   *keepalive.EnforcementPolicy
+	tls           *TLSConfig
+	overflow      feeder.OverflowConfig
+	producers     feeder.ProducerStatsConfig
+	pool          *feeder.BufferPool
+	acks          AckMode
+	logger        feeder.Logger
+}
+
+// Option customizes the gRPC feeder created by NewWithOptions.
+type Option func(*options)
+
+func defaultOptions() options {
+	return options{
+		queueCapacity: feedQueueCapacity,
+		maxMsgSize:    MaxRcvMsgSize,
+		keepalive:     keepalive.ServerParameters{Time: time.Second * 30, Timeout: time.Second * 10},
+		overflow:      feeder.OverflowConfig{Policy: feeder.OverflowBlock},
+		acks:          AckNone,
+		logger:        feeder.GlogLogger(),
+	}
+}
+
+// WithQueueCapacity sets the number of items the feed channel buffers.
+func WithQueueCapacity(n int) Option {
+	return func(o *options) {
+		o.queueCapacity = n
+	}
+}
+
+// WithMaxMsgSize sets the maximum size of a MdtDialoutArgs message the server accepts.
+func WithMaxMsgSize(n int) Option {
+	return func(o *options) {
+		o.maxMsgSize = n
+	}
+}
+
+// WithKeepalive sets the server side keepalive parameters.
+func WithKeepalive(params keepalive.ServerParameters) Option {
+	return func(o *options) {
+		o.keepalive = params
+	}
+}
+
+// WithKeepaliveEnforcement sets the policy for client keepalive pings, without it gRPC
+// defaults apply and clients pinging more often than every 5 minutes are disconnected.
+func WithKeepaliveEnforcement(policy keepalive.EnforcementPolicy) Option {
+	return func(o *options) {
+		o.enforcement = &policy
+	}
+}
+
+// WithTLS enables TLS, or mutual TLS when the client CA file is set, the server is plaintext
+// without it.
+func WithTLS(cfg TLSConfig) Option {
+	return func(o *options) {
+		o.tls = &cfg
+	}
+}
+
+// WithOverflow sets the feed queue overflow policy.
+func WithOverflow(cfg feeder.OverflowConfig) Option {
+	return func(o *options) {
+		o.overflow = cfg
+	}
+}
+
+// WithProducerStats sets the eviction policy of the per-producer stats.
+func WithProducerStats(cfg feeder.ProducerStatsConfig) Option {
+	return func(o *options) {
+		o.producers = cfg
+	}
+}
+
+// WithBufferPool backs the payload of every feed with a buffer of pool, consumers return it
+// with Feed.Release once done with the payload.
+func WithBufferPool(pool *feeder.BufferPool) Option {
+	return func(o *options) {
+		o.pool = pool
+	}
+}
+
+// WithAcks makes the feeder reply to the messages of producers on the MdtDialout stream, so
+// that routers log messages the collector dropped or failed to decode. With acks enabled the
+// payload is checked to be a well formed protobuf message and a malformed one is published as
+// an error item wrapping feeder.ErrUnmarshalTelemetryMsg.
+func WithAcks(mode AckMode) Option {
+	return func(o *options) {
+		o.acks = mode
+	}
+}
+
+// WithLogger sets the logger, glog is used by default.
+func WithLogger(l feeder.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+func (o *options) validate() error {
+	if o.queueCapacity < 0 {
+		return fmt.Errorf("invalid feed queue capacity %d", o.queueCapacity)
+	}
+	if o.maxMsgSize <= 0 {
+		return fmt.Errorf("invalid maximum message size %d", o.maxMsgSize)
+	}
+	if err := o.acks.validate(); err != nil {
+		return err
+	}
+	if o.logger == nil {
+		o.logger = feeder.NopLogger()
+	}
+
+	return nil
+}
@@ -0,0 +1,181 @@
+package grpc_feeder
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+const defaultTLSReloadInterval = time.Second * 30
+
+// TLSConfig defines TLS server credentials of the gRPC dial-out feeder. When ClientCAFile is
+// set, client certificates are verified against it and the connection is rejected without
+// one, unless ClientCertOptional is set. The files are checked for changes at most once per
+// ReloadInterval on new connections, updated certificates apply without restarting the
+// listener while established sessions keep their credentials.
+type TLSConfig struct {
+	CertFile           string
+	KeyFile            string
+	ClientCAFile       string
+	ClientCertOptional bool
+	// ReloadInterval defaults to 30 seconds, a negative value disables reloading.
+	ReloadInterval time.Duration
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// certReloader keeps the TLS configuration built from the files in TLSConfig and rebuilds it
+// when any of the files changes. A failed reload keeps the previous configuration.
+type certReloader struct {
+	cfg       TLSConfig
+	logger    feeder.Logger
+	mu        sync.Mutex
+	tlsConfig *tls.Config
+	stamps    map[string]fileStamp
+	checked   time.Time
+}
+
+func newCertReloader(cfg TLSConfig, logger feeder.Logger) (*certReloader, error) {
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, fmt.Errorf("TLS requires both certificate and key files")
+	}
+	if cfg.ReloadInterval == 0 {
+		cfg.ReloadInterval = defaultTLSReloadInterval
+	}
+	r := &certReloader{
+		cfg:    cfg,
+		logger: logger,
+	}
+	stamps, err := r.stat()
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+	r.tlsConfig = tlsConfig
+	r.stamps = stamps
+	r.checked = time.Now()
+
+	return r, nil
+}
+
+func (r *certReloader) files() []string {
+	files := []string{r.cfg.CertFile, r.cfg.KeyFile}
+	if r.cfg.ClientCAFile != "" {
+		files = append(files, r.cfg.ClientCAFile)
+	}
+	return files
+}
+
+func (r *certReloader) stat() (map[string]fileStamp, error) {
+	stamps := make(map[string]fileStamp)
+	for _, name := range r.files() {
+		fi, err := os.Stat(name)
+		if err != nil {
+			return nil, err
+		}
+		stamps[name] = fileStamp{modTime: fi.ModTime(), size: fi.Size()}
+	}
+	return stamps, nil
+}
+
+func (r *certReloader) load() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate %s with key %s: %w", r.cfg.CertFile, r.cfg.KeyFile, err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"h2"},
+	}
+	if r.cfg.ClientCAFile != "" {
+		b, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file %s: %w", r.cfg.ClientCAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no certificates found in client CA file %s", r.cfg.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		if r.cfg.ClientCertOptional {
+			tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// reloadIfChanged rebuilds the TLS configuration when any of the files has changed since
+// the last load.
+func (r *certReloader) reloadIfChanged() {
+	stamps, err := r.stat()
+	if err != nil {
+		r.logger.Errorf("failed to check TLS files for changes, keeping current certificates: %+v", err)
+		return
+	}
+	changed := false
+	for name, stamp := range stamps {
+		if prev, ok := r.stamps[name]; !ok || !prev.modTime.Equal(stamp.modTime) || prev.size != stamp.size {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return
+	}
+	tlsConfig, err := r.load()
+	if err != nil {
+		// Files may be in the middle of being replaced, retry on the next check.
+		r.logger.Errorf("failed to reload TLS certificates, keeping current ones: %+v", err)
+		return
+	}
+	r.tlsConfig = tlsConfig
+	r.stamps = stamps
+	r.logger.Infof("TLS certificates reloaded from %s", r.cfg.CertFile)
+}
+
+// getConfigForClient is tls.Config.GetConfigForClient hook returning the current configuration.
+func (r *certReloader) getConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cfg.ReloadInterval > 0 && time.Since(r.checked) >= r.cfg.ReloadInterval {
+		r.checked = time.Now()
+		r.reloadIfChanged()
+	}
+	return r.tlsConfig, nil
+}
+
+func (r *certReloader) credentials() credentials.TransportCredentials {
+	return credentials.NewTLS(&tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		GetConfigForClient: r.getConfigForClient,
+	})
+}
+
+// peerSubject returns the subject of the certificate presented by the peer, empty when the
+// connection is not TLS or the peer has not presented a certificate.
+func peerSubject(p *peer.Peer) string {
+	if p == nil {
+		return ""
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.PeerCertificates) == 0 {
+		return ""
+	}
+	return info.State.PeerCertificates[0].Subject.String()
+}
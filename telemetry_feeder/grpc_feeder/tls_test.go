@@ -0,0 +1,241 @@
+package grpc_feeder
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+	"github.com/sbezverk/tools/telemetry_feeder/proto/mdtdialout"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate CA key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create CA certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// issue returns PEM encoded certificate and key signed by the CA.
+func (ca *testCA) issue(t *testing.T, cn string, serial int64, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn, Organization: []string{"test"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, name string, b []byte, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(name, b, 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	if err := os.Chtimes(name, modTime, modTime); err != nil {
+		t.Fatalf("failed to set modification time of %s: %v", name, err)
+	}
+}
+
+func newTLSTestFeeder(t *testing.T, cfg TLSConfig) (*grpcSrv, string) {
+	t.Helper()
+	fdr, err := NewWithOptions("127.0.0.1:0", WithTLS(cfg), WithLogger(feeder.NopLogger()))
+	if err != nil {
+		t.Fatalf("failed to create feeder: %v", err)
+	}
+	srv := fdr.(*grpcSrv)
+	return srv, srv.conn.Addr().String()
+}
+
+func dialAndSend(t *testing.T, addr string, tlsConfig *tls.Config, data []byte) error {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	cc, err := grpc.DialContext(ctx, addr, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	if err != nil {
+		return err
+	}
+	defer cc.Close()
+	stream, err := mdtdialout.NewGRPCMdtDialoutClient(cc).MdtDialout(ctx)
+	if err != nil {
+		return err
+	}
+	if err := stream.Send(&mdtdialout.MdtDialoutArgs{ReqId: 1, Data: data}); err != nil {
+		return err
+	}
+	_, err = stream.Recv()
+	if ctx.Err() != nil {
+		// Server never closes the stream, running into the deadline means data was accepted.
+		return nil
+	}
+	return err
+}
+
+func TestMutualTLSPeerSubject(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.issue(t, "collector", 2, x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(t, "router1", 3, x509.ExtKeyUsageClientAuth)
+	cfg := TLSConfig{
+		CertFile:     filepath.Join(dir, "server.crt"),
+		KeyFile:      filepath.Join(dir, "server.key"),
+		ClientCAFile: filepath.Join(dir, "ca.crt"),
+	}
+	now := time.Now()
+	writeFile(t, cfg.CertFile, serverCert, now)
+	writeFile(t, cfg.KeyFile, serverKey, now)
+	writeFile(t, cfg.ClientCAFile, ca.pem, now)
+
+	srv, addr := newTLSTestFeeder(t, cfg)
+	defer srv.Stop()
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(ca.pem)
+	pair, err := tls.X509KeyPair(clientCert, clientKey)
+	if err != nil {
+		t.Fatalf("failed to load client certificate: %v", err)
+	}
+	go dialAndSend(t, addr, &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{pair}, ServerName: "localhost"}, []byte("data"))
+
+	select {
+	case f := <-srv.GetFeed():
+		if f.Err != nil {
+			t.Fatalf("unexpected error feed: %v", f.Err)
+		}
+		if f.PeerSubject != "CN=router1,O=test" {
+			t.Fatalf("peer subject mismatch, got %q", f.PeerSubject)
+		}
+		if string(f.TelemetryMsg) != "data" {
+			t.Fatalf("payload mismatch, got %q", f.TelemetryMsg)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for feed")
+	}
+
+	// Without a client certificate the handshake must fail.
+	if err := dialAndSend(t, addr, &tls.Config{RootCAs: roots, ServerName: "localhost"}, []byte("data")); err == nil {
+		t.Fatal("expected connection without client certificate to be rejected")
+	}
+	select {
+	case f := <-srv.GetFeed():
+		t.Fatalf("expected no feed from unauthenticated client, got %+v", f)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestCertificateReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	cfg := TLSConfig{
+		CertFile:       filepath.Join(dir, "server.crt"),
+		KeyFile:        filepath.Join(dir, "server.key"),
+		ReloadInterval: time.Millisecond,
+	}
+	cert, key := ca.issue(t, "collector-1", 2, x509.ExtKeyUsageServerAuth)
+	now := time.Now()
+	writeFile(t, cfg.CertFile, cert, now)
+	writeFile(t, cfg.KeyFile, key, now)
+
+	r, err := newCertReloader(cfg, feeder.NopLogger())
+	if err != nil {
+		t.Fatalf("failed to create reloader: %v", err)
+	}
+	serverCN := func() string {
+		time.Sleep(2 * time.Millisecond)
+		c, err := r.getConfigForClient(nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		leaf, err := x509.ParseCertificate(c.Certificates[0].Certificate[0])
+		if err != nil {
+			t.Fatalf("failed to parse certificate: %v", err)
+		}
+		return leaf.Subject.CommonName
+	}
+	if cn := serverCN(); cn != "collector-1" {
+		t.Fatalf("expected collector-1, got %q", cn)
+	}
+
+	// A broken certificate keeps the previous one.
+	writeFile(t, cfg.CertFile, []byte("garbage"), now.Add(time.Second))
+	if cn := serverCN(); cn != "collector-1" {
+		t.Fatalf("expected collector-1 to be kept, got %q", cn)
+	}
+
+	cert, key = ca.issue(t, "collector-2", 3, x509.ExtKeyUsageServerAuth)
+	writeFile(t, cfg.CertFile, cert, now.Add(2*time.Second))
+	writeFile(t, cfg.KeyFile, key, now.Add(2*time.Second))
+	if cn := serverCN(); cn != "collector-2" {
+		t.Fatalf("expected collector-2 after reload, got %q", cn)
+	}
+}
+
+func TestTLSConfigValidation(t *testing.T) {
+	if _, err := NewWithOptions("127.0.0.1:0", WithTLS(TLSConfig{CertFile: "server.crt"})); err == nil {
+		t.Fatal("expected error for missing key file")
+	}
+	dir := t.TempDir()
+	if _, err := NewWithOptions("127.0.0.1:0", WithTLS(TLSConfig{
+		CertFile: filepath.Join(dir, "server.crt"),
+		KeyFile:  filepath.Join(dir, "server.key"),
+	})); err == nil {
+		t.Fatal("expected error for missing certificate files")
+	}
+}
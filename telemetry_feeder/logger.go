@@ -0,0 +1,45 @@
+package telemetry_feeder
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+)
+
+// Logger reports feeder events which are not delivered through the feed, such as accepted
+// and closed connections. Feeders default to GlogLogger.
+type Logger interface {
+	Infof(format string, args ...any)
+	Warningf(format string, args ...any)
+	Errorf(format string, args ...any)
+}
+
+type glogLogger struct{}
+
+func (glogLogger) Infof(format string, args ...any) {
+	glog.InfoDepth(1, fmt.Sprintf(format, args...))
+}
+
+func (glogLogger) Warningf(format string, args ...any) {
+	glog.WarningDepth(1, fmt.Sprintf(format, args...))
+}
+
+func (glogLogger) Errorf(format string, args ...any) {
+	glog.ErrorDepth(1, fmt.Sprintf(format, args...))
+}
+
+// GlogLogger returns Logger writing through glog.
+func GlogLogger() Logger {
+	return glogLogger{}
+}
+
+// NopLogger returns Logger discarding everything.
+func NopLogger() Logger {
+	return nopLogger{}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Infof(string, ...any)    {}
+func (nopLogger) Warningf(string, ...any) {}
+func (nopLogger) Errorf(string, ...any)   {}
@@ -0,0 +1,137 @@
+package mux_feeder
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+)
+
+// Subscription is a fan-out subscriber with its own buffered channel. When the channel is
+// full, items are dropped for this subscriber only and counted in Dropped, so a slow
+// subscriber never holds back the others.
+type Subscription struct {
+	Name      string
+	feed      chan *feeder.Feed
+	delivered atomic.Int64
+	dropped   atomic.Int64
+}
+
+func (s *Subscription) GetFeed() chan *feeder.Feed {
+	return s.feed
+}
+
+// Delivered returns the number of items placed into the subscriber channel.
+func (s *Subscription) Delivered() int64 {
+	return s.delivered.Load()
+}
+
+// Dropped returns the number of items dropped because the subscriber channel was full.
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Fanout distributes every item of a single feed channel to all subscribers. Items are
+// shared between subscribers and must be treated as read-only, pooled feeds must not be released.
+type Fanout struct {
+	in     chan *feeder.Feed
+	stopCh chan struct{}
+	once   sync.Once
+	mu     sync.RWMutex
+	subs   map[string]*Subscription
+	closed bool
+}
+
+// NewFanout starts distributing items read from in, subscribers channels are closed when in
+// is closed or Stop is called.
+func NewFanout(in chan *feeder.Feed) *Fanout {
+	f := &Fanout{
+		in:     in,
+		stopCh: make(chan struct{}),
+		subs:   make(map[string]*Subscription),
+	}
+	go f.worker()
+
+	return f
+}
+
+// Subscribe adds a subscriber with a channel buffered to capacity items.
+func (f *Fanout) Subscribe(name string, capacity int) (*Subscription, error) {
+	if name == "" {
+		return nil, fmt.Errorf("subscriber name cannot be empty")
+	}
+	if capacity < 0 {
+		return nil, fmt.Errorf("invalid subscriber %q capacity %d", name, capacity)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return nil, fmt.Errorf("fanout is stopped")
+	}
+	if _, ok := f.subs[name]; ok {
+		return nil, fmt.Errorf("subscriber with name %q already exists", name)
+	}
+	s := &Subscription{
+		Name: name,
+		feed: make(chan *feeder.Feed, capacity),
+	}
+	f.subs[name] = s
+
+	return s, nil
+}
+
+// Unsubscribe removes the subscriber and closes its channel.
+func (f *Fanout) Unsubscribe(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.subs[name]
+	if !ok {
+		return
+	}
+	delete(f.subs, name)
+	close(s.feed)
+}
+
+func (f *Fanout) Stop() {
+	f.once.Do(func() {
+		close(f.stopCh)
+	})
+}
+
+func (f *Fanout) worker() {
+	defer f.closeAll()
+	for {
+		select {
+		case <-f.stopCh:
+			return
+		case item, ok := <-f.in:
+			if !ok {
+				return
+			}
+			if item == nil {
+				continue
+			}
+			f.mu.RLock()
+			for _, s := range f.subs {
+				select {
+				case s.feed <- item:
+					s.delivered.Add(1)
+				default:
+					s.dropped.Add(1)
+				}
+			}
+			f.mu.RUnlock()
+		}
+	}
+}
+
+func (f *Fanout) closeAll() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	for name, s := range f.subs {
+		delete(f.subs, name)
+		close(s.feed)
+	}
+}
@@ -0,0 +1,283 @@
+package mux_feeder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+)
+
+const (
+	feedQueueCapacity = 1024 * 25
+)
+
+// Source is a named feeder merged by the mux, the name is used to tag every feed item
+// coming from the feeder and to key its stats.
+type Source struct {
+	Name   string
+	Feeder feeder.Feeder
+}
+
+// StatsSnapshot aggregates the receive counters of all sources, the queue counters describe
+// the merged feed channel. Sources holds the stats document of every source as is.
+type StatsSnapshot struct {
+	feeder.StatsSnapshot
+	Sources map[string]json.RawMessage `json:"sources"`
+}
+
+type muxFeeder struct {
+	sources                     []Source
+	stopCh                      chan struct{}
+	stopOnce                    sync.Once
+	wg                          sync.WaitGroup
+	feed                        chan *feeder.Feed
+	closed                      chan struct{}
+	stopped                     chan struct{}
+	events                      *feeder.EventQueue
+	eventsWg                    sync.WaitGroup
+	startTime                   time.Time
+	feedItemsEnqueuedTotal      atomic.Int64
+	feedErrorItemsEnqueuedTotal atomic.Int64
+	feedQueueDepthMax           atomic.Int64
+	feedPublishBlockNanosTotal  atomic.Int64
+	feedPublishBlockNanosMax    atomic.Int64
+}
+
+// New merges the feeds of all sources into a single channel. The channel is closed once
+// every source channel is closed or the mux is stopped, stopping the mux stops all sources.
+func New(sources ...Source) (feeder.Feeder, error) {
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("at least one source feeder must be provided")
+	}
+	names := make(map[string]struct{}, len(sources))
+	for _, s := range sources {
+		if s.Name == "" {
+			return nil, fmt.Errorf("source name cannot be empty")
+		}
+		if s.Feeder == nil {
+			return nil, fmt.Errorf("source %q feeder cannot be nil", s.Name)
+		}
+		if _, ok := names[s.Name]; ok {
+			return nil, fmt.Errorf("source with name %q already exists", s.Name)
+		}
+		names[s.Name] = struct{}{}
+	}
+	m := &muxFeeder{
+		sources:   sources,
+		stopCh:    make(chan struct{}),
+		feed:      make(chan *feeder.Feed, feedQueueCapacity),
+		closed:    make(chan struct{}),
+		stopped:   make(chan struct{}),
+		events:    feeder.NewEventQueue(""),
+		startTime: time.Now(),
+	}
+	for _, s := range m.sources {
+		m.wg.Add(1)
+		go m.forward(s)
+		m.eventsWg.Add(1)
+		go m.forwardEvents(s)
+	}
+	go func() {
+		m.wg.Wait()
+		close(m.feed)
+		close(m.closed)
+	}()
+	go func() {
+		select {
+		case <-m.stopCh:
+		case <-m.closed:
+		}
+		close(m.stopped)
+	}()
+
+	return m, nil
+}
+
+func (m *muxFeeder) GetFeed() chan *feeder.Feed {
+	return m.feed
+}
+
+// Events merges the events of the sources implementing feeder.EventSource, every event is
+// tagged with the source name.
+func (m *muxFeeder) Events() <-chan *feeder.Event {
+	return m.events.Events()
+}
+
+// Stopped is closed once the mux is stopped or every source channel is closed.
+func (m *muxFeeder) Stopped() <-chan struct{} {
+	return m.stopped
+}
+
+func (m *muxFeeder) Stop() {
+	m.stopOnce.Do(func() {
+		close(m.stopCh)
+		for _, s := range m.sources {
+			s.Feeder.Stop()
+		}
+	})
+}
+
+// StopGracefully stops all sources gracefully at the same time, their remaining items are
+// forwarded until the source channels are closed. The merged feed channel is closed on return.
+func (m *muxFeeder) StopGracefully(ctx context.Context) error {
+	errs := make([]error, len(m.sources))
+	var wg sync.WaitGroup
+	for i, s := range m.sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.Feeder.StopGracefully(ctx); err != nil {
+				errs[i] = fmt.Errorf("source %q: %w", s.Name, err)
+			}
+		}()
+	}
+	wg.Wait()
+	err := errors.Join(errs...)
+	if err == nil {
+		if err = feeder.WaitContext(ctx, &m.wg); err == nil {
+			err = feeder.WaitDrained(ctx, m.feed)
+		}
+	}
+	// Sources close their events once stopped, the merged events follow.
+	feeder.WaitContext(ctx, &m.eventsWg)
+	m.Stop()
+	<-m.closed
+	m.eventsWg.Wait()
+	m.events.Close()
+
+	return err
+}
+
+func (m *muxFeeder) forward(s Source) {
+	defer m.wg.Done()
+	in := s.Feeder.GetFeed()
+	for {
+		select {
+		case <-m.stopCh:
+			return
+		case f, ok := <-in:
+			if !ok {
+				return
+			}
+			if f == nil {
+				continue
+			}
+			if f.Source == "" {
+				f.Source = s.Name
+			}
+			if !m.publishFeed(f) {
+				return
+			}
+		}
+	}
+}
+
+func (m *muxFeeder) forwardEvents(s Source) {
+	defer m.eventsWg.Done()
+	in := feeder.EventsOf(s.Feeder)
+	for {
+		select {
+		case <-m.stopCh:
+			return
+		case e, ok := <-in:
+			if !ok {
+				return
+			}
+			if e.Source == "" {
+				e.Source = s.Name
+			}
+			m.events.Emit(e)
+		}
+	}
+}
+
+func updateMax(max *atomic.Int64, value int64) {
+	for {
+		current := max.Load()
+		if value <= current || max.CompareAndSwap(current, value) {
+			return
+		}
+	}
+}
+
+func (m *muxFeeder) publishFeed(item *feeder.Feed) bool {
+	// If stopCh is already closed, prevent publishing (even if the send would not block).
+	select {
+	case <-m.stopCh:
+		return false
+	default:
+	}
+	queueDepthAfterSend := int64(len(m.feed) + 1)
+	if queueDepthAfterSend > int64(cap(m.feed)) {
+		queueDepthAfterSend = int64(cap(m.feed))
+	}
+	started := time.Now()
+	select {
+	case <-m.stopCh:
+		return false
+	case m.feed <- item:
+		blocked := time.Since(started).Nanoseconds()
+		m.feedItemsEnqueuedTotal.Add(1)
+		if item.Err != nil {
+			m.feedErrorItemsEnqueuedTotal.Add(1)
+		}
+		m.feedPublishBlockNanosTotal.Add(blocked)
+		updateMax(&m.feedPublishBlockNanosMax, blocked)
+		updateMax(&m.feedQueueDepthMax, queueDepthAfterSend)
+		return true
+	}
+}
+
+func (m *muxFeeder) statsSnapshot() StatsSnapshot {
+	snapshot := StatsSnapshot{
+		StatsSnapshot: feeder.StatsSnapshot{
+			Transport:                   "mux",
+			StartTime:                   m.startTime.UTC(),
+			UptimeSeconds:               int64(time.Since(m.startTime).Seconds()),
+			FeedItemsEnqueuedTotal:      m.feedItemsEnqueuedTotal.Load(),
+			FeedErrorItemsEnqueuedTotal: m.feedErrorItemsEnqueuedTotal.Load(),
+			FeedQueueDepth:              int64(len(m.feed)),
+			FeedQueueDepthMax:           m.feedQueueDepthMax.Load(),
+			FeedQueueCapacity:           int64(cap(m.feed)),
+			FeedPublishBlockNanosTotal:  m.feedPublishBlockNanosTotal.Load(),
+			FeedPublishBlockNanosMax:    m.feedPublishBlockNanosMax.Load(),
+		},
+		Sources: make(map[string]json.RawMessage, len(m.sources)),
+	}
+	m.events.FillStats(&snapshot.StatsSnapshot)
+	for _, s := range m.sources {
+		b, err := s.Feeder.GetStatsJson()
+		if err != nil || !json.Valid(b) {
+			if err == nil {
+				err = fmt.Errorf("source returned invalid JSON")
+			}
+			b, _ = json.Marshal(map[string]string{"error": err.Error()})
+			snapshot.Sources[s.Name] = b
+			continue
+		}
+		snapshot.Sources[s.Name] = b
+		var src feeder.StatsSnapshot
+		if err := json.Unmarshal(b, &src); err != nil {
+			continue
+		}
+		snapshot.MessagesReceivedTotal += src.MessagesReceivedTotal
+		snapshot.PayloadBytesReceivedTotal += src.PayloadBytesReceivedTotal
+		snapshot.TransportBytesReceivedTotal += src.TransportBytesReceivedTotal
+		snapshot.ReceiveErrorsTotal += src.ReceiveErrorsTotal
+		snapshot.ReceiveTimeoutErrorsTotal += src.ReceiveTimeoutErrorsTotal
+		snapshot.ReceiveClosedTotal += src.ReceiveClosedTotal
+		snapshot.ReceiveOtherErrorsTotal += src.ReceiveOtherErrorsTotal
+	}
+
+	return snapshot
+}
+
+func (m *muxFeeder) GetStatsJson() ([]byte, error) {
+	snapshot := m.statsSnapshot()
+	return json.Marshal(snapshot)
+}
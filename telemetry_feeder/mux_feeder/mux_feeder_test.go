@@ -0,0 +1,231 @@
+package mux_feeder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+)
+
+type fakeFeeder struct {
+	feed    chan *feeder.Feed
+	stats   feeder.StatsSnapshot
+	statErr error
+	stopped atomic.Bool
+}
+
+func newFakeFeeder(transport string, received int64) *fakeFeeder {
+	return &fakeFeeder{
+		feed:  make(chan *feeder.Feed, 10),
+		stats: feeder.StatsSnapshot{Transport: transport, MessagesReceivedTotal: received, PayloadBytesReceivedTotal: received * 10},
+	}
+}
+
+func (f *fakeFeeder) GetFeed() chan *feeder.Feed {
+	return f.feed
+}
+
+func (f *fakeFeeder) GetStatsJson() ([]byte, error) {
+	if f.statErr != nil {
+		return nil, f.statErr
+	}
+	return json.Marshal(f.stats)
+}
+
+func (f *fakeFeeder) Stop() {
+	f.stopped.Store(true)
+}
+
+func (f *fakeFeeder) StopGracefully(ctx context.Context) error {
+	f.Stop()
+	return nil
+}
+
+func TestMergeTagsSource(t *testing.T) {
+	grpc := newFakeFeeder("grpc", 0)
+	udp := newFakeFeeder("udp", 0)
+	m, err := New(Source{Name: "grpc", Feeder: grpc}, Source{Name: "udp", Feeder: udp})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer m.Stop()
+
+	grpc.feed <- &feeder.Feed{TelemetryMsg: []byte("g"), Transport: feeder.TransportGRPC}
+	udp.feed <- &feeder.Feed{TelemetryMsg: []byte("u"), Transport: feeder.TransportUDP}
+
+	got := make(map[string]string)
+	for i := 0; i < 2; i++ {
+		select {
+		case f := <-m.GetFeed():
+			got[f.Source] = string(f.TelemetryMsg)
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for merged feed")
+		}
+	}
+	if got["grpc"] != "g" || got["udp"] != "u" {
+		t.Fatalf("unexpected merged feeds %v", got)
+	}
+}
+
+func TestFeedClosedWhenSourcesClose(t *testing.T) {
+	a := newFakeFeeder("udp", 0)
+	b := newFakeFeeder("udp", 0)
+	m, err := New(Source{Name: "a", Feeder: a}, Source{Name: "b", Feeder: b})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a.feed <- &feeder.Feed{TelemetryMsg: []byte("last")}
+	close(a.feed)
+	close(b.feed)
+
+	n := 0
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case _, ok := <-m.GetFeed():
+			if !ok {
+				if n != 1 {
+					t.Fatalf("expected 1 item before close, got %d", n)
+				}
+				return
+			}
+			n++
+		case <-timeout:
+			t.Fatal("merged feed was not closed")
+		}
+	}
+}
+
+func TestStatsAggregation(t *testing.T) {
+	grpc := newFakeFeeder("grpc", 3)
+	udp := newFakeFeeder("udp", 4)
+	offline := newFakeFeeder("offline", 0)
+	offline.statErr = fmt.Errorf("stats are not supported")
+	m, err := New(Source{Name: "grpc", Feeder: grpc}, Source{Name: "udp", Feeder: udp}, Source{Name: "offline", Feeder: offline})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer m.Stop()
+
+	b, err := m.GetStatsJson()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var stats StatsSnapshot
+	if err := json.Unmarshal(b, &stats); err != nil {
+		t.Fatalf("failed to unmarshal stats: %v", err)
+	}
+	if stats.Transport != "mux" {
+		t.Fatalf("transport mismatch, want mux got %q", stats.Transport)
+	}
+	if stats.MessagesReceivedTotal != 7 || stats.PayloadBytesReceivedTotal != 70 {
+		t.Fatalf("unexpected aggregated counters %+v", stats.StatsSnapshot)
+	}
+	if len(stats.Sources) != 3 {
+		t.Fatalf("expected stats of 3 sources, got %d", len(stats.Sources))
+	}
+	var udpStats feeder.StatsSnapshot
+	if err := json.Unmarshal(stats.Sources["udp"], &udpStats); err != nil || udpStats.MessagesReceivedTotal != 4 {
+		t.Fatalf("unexpected udp source stats %s", stats.Sources["udp"])
+	}
+	var offlineStats map[string]string
+	if err := json.Unmarshal(stats.Sources["offline"], &offlineStats); err != nil || offlineStats["error"] == "" {
+		t.Fatalf("expected error document for offline source, got %s", stats.Sources["offline"])
+	}
+}
+
+func TestStopPropagates(t *testing.T) {
+	a := newFakeFeeder("udp", 0)
+	b := newFakeFeeder("grpc", 0)
+	m, err := New(Source{Name: "a", Feeder: a}, Source{Name: "b", Feeder: b})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m.Stop()
+	m.Stop()
+	if !a.stopped.Load() || !b.stopped.Load() {
+		t.Fatal("expected Stop to be propagated to all sources")
+	}
+	select {
+	case _, ok := <-m.GetFeed():
+		if ok {
+			t.Fatal("expected merged feed to be closed after stop")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("merged feed was not closed after stop")
+	}
+}
+
+func TestNewValidatesSources(t *testing.T) {
+	f := newFakeFeeder("udp", 0)
+	if _, err := New(); err == nil {
+		t.Fatal("expected error for no sources")
+	}
+	if _, err := New(Source{Feeder: f}); err == nil {
+		t.Fatal("expected error for empty source name")
+	}
+	if _, err := New(Source{Name: "a", Feeder: f}, Source{Name: "a", Feeder: f}); err == nil {
+		t.Fatal("expected error for duplicate source name")
+	}
+}
+
+func TestFanoutIndependentBuffering(t *testing.T) {
+	in := make(chan *feeder.Feed)
+	fo := NewFanout(in)
+	defer fo.Stop()
+
+	fast, err := fo.Subscribe("fast", 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	slow, err := fo.Subscribe("slow", 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := fo.Subscribe("fast", 1); err == nil {
+		t.Fatal("expected error for duplicate subscriber")
+	}
+
+	for i := 0; i < 3; i++ {
+		in <- &feeder.Feed{TelemetryMsg: []byte{byte(i)}}
+	}
+	close(in)
+
+	n := 0
+	for range fast.GetFeed() {
+		n++
+	}
+	if n != 3 || fast.Delivered() != 3 || fast.Dropped() != 0 {
+		t.Fatalf("fast subscriber: received %d delivered %d dropped %d", n, fast.Delivered(), fast.Dropped())
+	}
+	n = 0
+	for range slow.GetFeed() {
+		n++
+	}
+	if n != 1 || slow.Delivered() != 1 || slow.Dropped() != 2 {
+		t.Fatalf("slow subscriber: received %d delivered %d dropped %d", n, slow.Delivered(), slow.Dropped())
+	}
+}
+
+func TestFanoutUnsubscribe(t *testing.T) {
+	in := make(chan *feeder.Feed)
+	fo := NewFanout(in)
+	defer fo.Stop()
+
+	s, err := fo.Subscribe("s", 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fo.Unsubscribe("s")
+	if _, ok := <-s.GetFeed(); ok {
+		t.Fatal("expected subscriber channel to be closed")
+	}
+	in <- &feeder.Feed{}
+	if s.Delivered() != 0 {
+		t.Fatal("expected no delivery after unsubscribe")
+	}
+}
@@ -1,98 +1,325 @@
 package offline_feeder
 
 import (
-	"encoding/binary"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang/glog"
 	feeder "github.com/sbezverk/tools/telemetry_feeder"
+	"github.com/sbezverk/tools/telemetry_feeder/capture"
+	"github.com/sbezverk/tools/telemetry_feeder/pcap"
 )
 
+// records is a reader of the feeds stored in a file, capture.Reader or pcap.Reader.
+type records interface {
+	Next() (*feeder.Feed, error)
+}
+
 type offFeeder struct {
-	file *os.File
-	feed chan *feeder.Feed
-	stop chan struct{}
-	once sync.Once
+	fn        string
+	file      *os.File
+	fileSize  int64
+	reader    records
+	opts      options
+	feed      chan *feeder.Feed
+	events    *feeder.EventQueue
+	stop      chan struct{}
+	once      sync.Once
+	drain     chan struct{}
+	drainOnce sync.Once
+	done      chan struct{}
+	producers *feeder.ProducerTracker
+	startTime time.Time
+	// bytesRead counts the bytes of the current pass, bytesReadBefore those of the completed
+	// passes.
+	bytesRead            atomic.Int64
+	bytesReadBefore      atomic.Int64
+	recordsFiltered      atomic.Int64
+	payloadBytesReplayed atomic.Int64
+	recordsReplayed      atomic.Int64
+	recordsTotal         atomic.Int64
+	corruptSkipped       atomic.Int64
+	readErrors           atomic.Int64
+	itemsEnqueued        atomic.Int64
+	errorItemsEnqueued   atomic.Int64
+	loopsCompleted       atomic.Int64
 }
 
 func (o *offFeeder) GetFeed() chan *feeder.Feed {
 	return o.feed
 }
 
+// Events is closed with the feed, a replay has no producer sessions to report.
+func (o *offFeeder) Events() <-chan *feeder.Event {
+	return o.events.Events()
+}
+
 func (o *offFeeder) GetStatsJson() ([]byte, error) {
-	return nil, fmt.Errorf("stats are not supported for offline feeder")
+	return json.Marshal(o.stats())
 }
 
-func (o *offFeeder) retrieve() {
-	ticker := time.NewTicker(time.Second * 1)
-	for {
+// openReader returns the reader of the file format, detected by the magic number. Reads go
+// through r, f is used to detect the format only.
+func openReader(f *os.File, r io.Reader, o *options) (records, error) {
+	magic := make([]byte, 4)
+	if n, err := f.ReadAt(magic, 0); err != nil && err != io.EOF {
+		return nil, err
+	} else if pcap.IsCapture(magic[:n]) {
+		return pcap.NewReader(r, o.pcap)
+	}
+	reader, err := capture.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	if o.needsTimestamps() && reader.Format() == capture.FormatLegacy {
+		return nil, fmt.Errorf("file has no receive times, %s replay and time range require a %s capture",
+			o.mode, capture.FormatV1)
+	}
+
+	return reader, nil
+}
+
+// rewind reopens the capture from the beginning of the file for the next loop.
+func (o *offFeeder) rewind() error {
+	if _, err := o.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	o.bytesReadBefore.Add(o.bytesRead.Swap(0))
+	reader, err := openReader(o.file, o.counted(), &o.opts)
+	if err != nil {
+		return err
+	}
+	o.reader = reader
+
+	return nil
+}
+
+func (o *offFeeder) counted() io.Reader {
+	return &countingReader{r: o.file, n: &o.bytesRead}
+}
+
+// send passes the item to the consumer, false is returned when the feeder is stopped meanwhile.
+func (o *offFeeder) send(f *feeder.Feed) bool {
+	select {
+	case <-o.stop:
+		return false
+	case o.feed <- f:
+	}
+	o.itemsEnqueued.Add(1)
+	if f.Err != nil {
+		o.errorItemsEnqueued.Add(1)
+	}
+
+	return true
+}
+
+// fail ends the replay with an error item, consumers see why the feed is closed.
+func (o *offFeeder) fail(err error) {
+	o.readErrors.Add(1)
+	glog.Errorf("failed to read offline telemetry file %s with error: %+v", o.fn, err)
+	o.send(&feeder.Feed{
+		Err: fmt.Errorf("failed to read offline telemetry file %s: %w", o.fn, err),
+	})
+}
+
+// wait blocks until d elapses, false is returned when the feeder is stopped or stopping
+// gracefully meanwhile.
+func (o *offFeeder) wait(d time.Duration) bool {
+	if d <= 0 {
 		select {
 		case <-o.stop:
-			ticker.Stop()
-			return
-		case <-ticker.C:
-			lb := make([]byte, 4)
-			if _, err := o.file.Read(lb); err != nil {
-				if err == io.EOF {
-					glog.Info("processing offline telemetry file completed")
-					return
-				}
-				glog.Errorf("failed to read length of the record with error: %+v", err)
+			return false
+		case <-o.drain:
+			return false
+		default:
+			return true
+		}
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-o.stop:
+		return false
+	case <-o.drain:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func (o *offFeeder) retrieve() {
+	defer close(o.feed)
+	for pass := 0; ; pass++ {
+		if pass > 0 {
+			if err := o.rewind(); err != nil {
+				o.fail(err)
 				return
 			}
-			l := binary.BigEndian.Uint32(lb)
-			glog.Infof("Expected record length %d", l)
-			b := make([]byte, l)
-			if _, err := o.file.Read(b); err != nil {
-				if err == io.EOF {
-					glog.Info("processing offline telemetry file completed")
-					close(o.feed)
-					return
+		}
+		if !o.replay() || !o.opts.loop {
+			return
+		}
+		o.loopsCompleted.Add(1)
+	}
+}
+
+// replay replays a single pass over the file, false is returned when the feeder is stopped or
+// the file cannot be read.
+func (o *offFeeder) replay() bool {
+	var first, base time.Time
+	var wallBase time.Time
+	for i := int64(0); ; i++ {
+		f, err := o.reader.Next()
+		if err != nil {
+			if err == io.EOF {
+				glog.Info("processing offline telemetry file completed")
+				return true
+			}
+			if isCorrupt(err) {
+				if o.corruptSkipped.Add(1) == 1 {
+					glog.Warningf("skipping corrupted record %d of offline telemetry file %s: %+v", i, o.fn, err)
 				}
-				glog.Errorf("failed to read the record with error: %+v", err)
-				close(o.feed)
-				return
+				continue
 			}
-			f := &feeder.Feed{}
-			f.TelemetryMsg = make([]byte, len(b))
-			copy(f.TelemetryMsg, b)
-			f.Err = nil
-			// Sending recieved Telemetry message for processing
-			select {
-			case <-o.stop:
-				ticker.Stop()
-				close(o.feed)
-				return
-			case o.feed <- f:
+			o.fail(err)
+			return false
+		}
+		// Time offsets are relative to the first record of the file, whatever the record range.
+		if first.IsZero() {
+			first = f.ReceivedAt
+		}
+		if o.opts.stopRecord > 0 && i >= o.opts.stopRecord {
+			return true
+		}
+		if i < o.opts.startRecord {
+			o.recordsFiltered.Add(1)
+			continue
+		}
+		if o.opts.needsTimestamps() && f.ReceivedAt.IsZero() {
+			o.fail(fmt.Errorf("record %d has no receive time, it cannot be replayed with the requested timing", i))
+			return false
+		}
+		offset := f.ReceivedAt.Sub(first)
+		if offset < o.opts.startOffset {
+			o.recordsFiltered.Add(1)
+			continue
+		}
+		if o.opts.stopOffset > 0 && offset >= o.opts.stopOffset {
+			return true
+		}
+		var delay time.Duration
+		switch o.opts.mode {
+		case ReplayFixedRate:
+			delay = o.opts.interval
+		case ReplayOriginalTiming:
+			if base.IsZero() {
+				base, wallBase = f.ReceivedAt, time.Now()
+			}
+			// Delays are computed from the start of the pass so they do not accumulate drift.
+			delay = time.Until(wallBase.Add(time.Duration(float64(f.ReceivedAt.Sub(base)) / o.opts.speed)))
+		}
+		if !o.wait(delay) {
+			return false
+		}
+		// Sending recieved Telemetry message for processing
+		if !o.send(f) {
+			return false
+		}
+		o.recordsReplayed.Add(1)
+		o.payloadBytesReplayed.Add(int64(len(f.TelemetryMsg)))
+		if f.ProducerAddr != nil {
+			o.producers.Received(f.ProducerAddr, len(f.TelemetryMsg), len(f.TelemetryMsg))
+			if f.Err != nil {
+				o.producers.Error(f.ProducerAddr)
 			}
 		}
 	}
 }
 
+// Stopped is closed once the replay ends, at the end of the file or when stopped.
+func (o *offFeeder) Stopped() <-chan struct{} {
+	return o.done
+}
+
 func (o *offFeeder) Stop() {
 	o.once.Do(func() {
 		close(o.stop)
-		o.file.Close()
 	})
 }
 
+// StopGracefully ends the replay once the record being replayed is taken by the consumer, the
+// feed channel is unbuffered so nothing else is queued. The feed channel is closed on return.
+func (o *offFeeder) StopGracefully(ctx context.Context) error {
+	o.drainOnce.Do(func() {
+		close(o.drain)
+	})
+	var err error
+	select {
+	case <-o.done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	o.Stop()
+	<-o.done
+
+	return err
+}
+
+// New replays the file at the rate of one record per second.
 func New(fn string) (feeder.Feeder, error) {
+	return NewWithOptions(fn)
+}
+
+// NewWithOptions replays the file as defined by opts, settings not customized keep the
+// defaults used by New.
+func NewWithOptions(fn string, opts ...Option) (feeder.Feeder, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
 	f, err := os.Open(fn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open offline telemetry file %s with error: %+v", fn, err)
 	}
-	o := &offFeeder{
-		feed: make(chan *feeder.Feed),
-		stop: make(chan struct{}),
-		file: f,
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to open offline telemetry file %s with error: %+v", fn, err)
+	}
+	of := &offFeeder{
+		fn:        fn,
+		feed:      make(chan *feeder.Feed),
+		events:    feeder.NewEventQueue(""),
+		stop:      make(chan struct{}),
+		drain:     make(chan struct{}),
+		done:      make(chan struct{}),
+		file:      f,
+		fileSize:  fi.Size(),
+		opts:      o,
+		producers: feeder.NewProducerTracker(feeder.ProducerStatsConfig{}),
+		startTime: time.Now(),
+	}
+	of.recordsTotal.Store(-1)
+	if of.reader, err = openReader(f, of.counted(), &o); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to open offline telemetry file %s with error: %+v", fn, err)
 	}
+	go of.countRecords()
 	go func() {
-		o.retrieve()
+		defer close(of.done)
+		defer of.events.Close()
+		defer of.file.Close()
+		of.retrieve()
 	}()
 
-	return o, nil
+	return of, nil
 }
@@ -0,0 +1,245 @@
+package offline_feeder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+	"github.com/sbezverk/tools/telemetry_feeder/capture"
+)
+
+// writeCapture writes n records named "0".."n-1", received step apart.
+func writeCapture(t *testing.T, format capture.Format, n int, step time.Duration) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "capture.bin")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create capture: %v", err)
+	}
+	defer f.Close()
+	w, err := capture.NewWriter(f, format, capture.Header{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		if _, err := w.Write(&feeder.Feed{
+			TelemetryMsg: []byte(fmt.Sprint(i)),
+			ReceivedAt:   start.Add(time.Duration(i) * step),
+		}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	return path
+}
+
+// collect reads up to max records, it stops early when the feed is closed.
+func collect(t *testing.T, f feeder.Feeder, max int) []string {
+	t.Helper()
+	var got []string
+	for len(got) < max {
+		select {
+		case item, ok := <-f.GetFeed():
+			if !ok {
+				return got
+			}
+			got = append(got, string(item.TelemetryMsg))
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out after %d records", len(got))
+		}
+	}
+
+	return got
+}
+
+func TestReplayRanges(t *testing.T) {
+	path := writeCapture(t, capture.FormatV1, 10, time.Second)
+	tests := []struct {
+		name     string
+		opts     []Option
+		want     string
+		filtered int64
+	}{
+		{name: "all", want: "[0 1 2 3 4 5 6 7 8 9]"},
+		{name: "record range", opts: []Option{WithRecordRange(2, 5)}, want: "[2 3 4]", filtered: 2},
+		{name: "open record range", opts: []Option{WithRecordRange(7, 0)}, want: "[7 8 9]", filtered: 7},
+		{name: "time range", opts: []Option{WithTimeRange(3*time.Second, 6*time.Second)}, want: "[3 4 5]", filtered: 3},
+		{name: "record and time range", opts: []Option{WithRecordRange(4, 9), WithTimeRange(3*time.Second, 6*time.Second)}, want: "[4 5]", filtered: 4},
+		{name: "time range after the record range", opts: []Option{WithRecordRange(0, 3), WithTimeRange(3*time.Second, 0)}, want: "[]", filtered: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewWithOptions(path, append(tt.opts, WithReplayMode(ReplayAsFastAsPossible))...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer f.Stop()
+			got := collect(t, f, 100)
+			if fmt.Sprint(got) != tt.want {
+				t.Fatalf("expected %s, got %s", tt.want, fmt.Sprint(got))
+			}
+			// Only the replayed records count as received.
+			stats := f.(*offFeeder).stats()
+			if stats.MessagesReceivedTotal != int64(len(got)) || stats.RecordsFilteredTotal != tt.filtered {
+				t.Fatalf("unexpected stats %+v", stats)
+			}
+		})
+	}
+}
+
+func TestReplayLoop(t *testing.T) {
+	path := writeCapture(t, capture.FormatLegacy, 3, 0)
+	f, err := NewWithOptions(path, WithReplayMode(ReplayAsFastAsPossible), WithLoop())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.Stop()
+	if got := fmt.Sprint(collect(t, f, 7)); got != "[0 1 2 0 1 2 0]" {
+		t.Fatalf("unexpected records %s", got)
+	}
+}
+
+func TestStopGracefully(t *testing.T) {
+	path := writeCapture(t, capture.FormatV1, 10, time.Second)
+	f, err := NewWithOptions(path, WithReplayMode(ReplayAsFastAsPossible))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := fmt.Sprint(collect(t, f, 2)); got != "[0 1]" {
+		t.Fatalf("unexpected records %s", got)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- f.StopGracefully(ctx)
+	}()
+	// Records read before the replay noticed the stop are still delivered in order, then the
+	// feed is closed.
+	for i, got := range collect(t, f, 10) {
+		if got != fmt.Sprint(i+2) {
+			t.Fatalf("unexpected record %s after stopping", got)
+		}
+	}
+	if err := <-stopped; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A replay waiting for the time of the next record stops right away.
+	f, err = NewWithOptions(path, WithReplayMode(ReplayFixedRate), WithInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := f.StopGracefully(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := <-f.GetFeed(); ok {
+		t.Fatal("expected the feed channel to be closed")
+	}
+}
+
+func TestReplayOriginalTiming(t *testing.T) {
+	// 100ms between records at ten times the speed leaves 10ms between records.
+	path := writeCapture(t, capture.FormatV1, 5, 100*time.Millisecond)
+	f, err := NewWithOptions(path, WithReplayMode(ReplayOriginalTiming), WithSpeed(10))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.Stop()
+	start := time.Now()
+	if got := collect(t, f, 5); len(got) != 5 {
+		t.Fatalf("expected 5 records, got %v", got)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond || elapsed > time.Second {
+		t.Fatalf("expected replay to take about 40ms, took %s", elapsed)
+	}
+}
+
+func TestReplayOptionValidation(t *testing.T) {
+	legacy := writeCapture(t, capture.FormatLegacy, 1, 0)
+	for _, opts := range [][]Option{
+		{WithReplayMode("bogus")},
+		{WithInterval(0)},
+		{WithReplayMode(ReplayOriginalTiming), WithSpeed(0)},
+		{WithRecordRange(5, 2)},
+		{WithTimeRange(-time.Second, 0)},
+		// Legacy captures have no receive times.
+		{WithReplayMode(ReplayOriginalTiming)},
+		{WithTimeRange(time.Second, 0)},
+	} {
+		if f, err := NewWithOptions(legacy, opts...); err == nil {
+			f.Stop()
+			t.Fatalf("expected error for %d options", len(opts))
+		}
+	}
+}
+
+func TestReplayStats(t *testing.T) {
+	path := writeCapture(t, capture.FormatV1, 3, time.Second)
+	// A record with an intact length but undecodable content.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("failed to open capture: %v", err)
+	}
+	f.Write([]byte{0, 0, 0, 3, 'b', 'a', 'd'})
+	f.Close()
+
+	o, err := NewWithOptions(path, WithReplayMode(ReplayAsFastAsPossible))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer o.Stop()
+	if got := fmt.Sprint(collect(t, o, 100)); got != "[0 1 2]" {
+		t.Fatalf("unexpected records %s", got)
+	}
+	var stats Stats
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		b, err := o.GetStatsJson()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := json.Unmarshal(b, &stats); err != nil {
+			t.Fatalf("failed to decode stats: %v", err)
+		}
+		if stats.RecordsTotal >= 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if stats.Transport != "offline" || stats.RecordsReplayedTotal != 3 || stats.CorruptRecordsSkippedTotal != 1 ||
+		stats.RecordsTotal != 4 || stats.PercentComplete != 100 || stats.FeedItemsEnqueuedTotal != 3 ||
+		stats.BytesRead != stats.FileSizeBytes || stats.PayloadBytesReceivedTotal != 3 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestShortReadEmitsError(t *testing.T) {
+	path := writeCapture(t, capture.FormatLegacy, 2, 0)
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat capture: %v", err)
+	}
+	if err := os.Truncate(path, fi.Size()-1); err != nil {
+		t.Fatalf("failed to truncate capture: %v", err)
+	}
+	o, err := NewWithOptions(path, WithReplayMode(ReplayAsFastAsPossible))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer o.Stop()
+	var items []*feeder.Feed
+	for item := range o.GetFeed() {
+		items = append(items, item)
+	}
+	if len(items) != 2 || string(items[0].TelemetryMsg) != "0" || !errors.Is(items[1].Err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected a record and a terminal error, got %+v", items)
+	}
+}
@@ -0,0 +1,131 @@
+package offline_feeder
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sbezverk/tools/telemetry_feeder/pcap"
+)
+
+// ReplayMode defines how fast records are replayed.
+type ReplayMode string
+
+const (
+	// ReplayFixedRate emits one record per interval, one second unless set with WithInterval.
+	ReplayFixedRate ReplayMode = "fixed-rate"
+	// ReplayAsFastAsPossible emits records as fast as the consumer takes them.
+	ReplayAsFastAsPossible ReplayMode = "as-fast-as-possible"
+	// ReplayOriginalTiming reproduces the recorded inter-arrival times, divided by the speed
+	// set with WithSpeed. It requires a capture in the versioned format.
+	ReplayOriginalTiming ReplayMode = "original-timing"
+)
+
+const defaultInterval = time.Second
+
+type options struct {
+	mode        ReplayMode
+	interval    time.Duration
+	speed       float64
+	loop        bool
+	startRecord int64
+	stopRecord  int64
+	startOffset time.Duration
+	stopOffset  time.Duration
+	pcap        pcap.Config
+}
+
+// Option customizes the offline feeder created by NewWithOptions.
+type Option func(*options)
+
+func defaultOptions() options {
+	return options{
+		mode:     ReplayFixedRate,
+		interval: defaultInterval,
+		speed:    1,
+	}
+}
+
+// WithReplayMode sets how fast records are replayed, ReplayFixedRate by default.
+func WithReplayMode(mode ReplayMode) Option {
+	return func(o *options) {
+		o.mode = mode
+	}
+}
+
+// WithInterval sets the interval between records of ReplayFixedRate.
+func WithInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.interval = d
+	}
+}
+
+// WithSpeed sets the speed multiplier of ReplayOriginalTiming, 10 replays ten times faster
+// than recorded, 0.5 twice slower.
+func WithSpeed(speed float64) Option {
+	return func(o *options) {
+		o.speed = speed
+	}
+}
+
+// WithLoop restarts the replay from the beginning of the file until the feeder is stopped.
+func WithLoop() Option {
+	return func(o *options) {
+		o.loop = true
+	}
+}
+
+// WithRecordRange replays only records with index in [start, stop), counted from 0, a zero
+// stop replays up to the end of the file.
+func WithRecordRange(start, stop int64) Option {
+	return func(o *options) {
+		o.startRecord = start
+		o.stopRecord = stop
+	}
+}
+
+// WithTimeRange replays only records received within [start, stop) after the first record of
+// the file, a zero stop replays up to the end of the file. It requires a capture in the
+// versioned format.
+func WithTimeRange(start, stop time.Duration) Option {
+	return func(o *options) {
+		o.startOffset = start
+		o.stopOffset = stop
+	}
+}
+
+// WithPcap selects the telemetry extracted from pcap and pcapng captures, it is required to
+// replay them and ignored for other files.
+func WithPcap(cfg pcap.Config) Option {
+	return func(o *options) {
+		o.pcap = cfg
+	}
+}
+
+func (o *options) validate() error {
+	switch o.mode {
+	case ReplayFixedRate:
+		if o.interval <= 0 {
+			return fmt.Errorf("invalid replay interval %s", o.interval)
+		}
+	case ReplayAsFastAsPossible:
+	case ReplayOriginalTiming:
+		if o.speed <= 0 {
+			return fmt.Errorf("invalid replay speed %g", o.speed)
+		}
+	default:
+		return fmt.Errorf("unknown replay mode %q", o.mode)
+	}
+	if o.startRecord < 0 || o.stopRecord < 0 || (o.stopRecord > 0 && o.stopRecord <= o.startRecord) {
+		return fmt.Errorf("invalid record range [%d, %d)", o.startRecord, o.stopRecord)
+	}
+	if o.startOffset < 0 || o.stopOffset < 0 || (o.stopOffset > 0 && o.stopOffset <= o.startOffset) {
+		return fmt.Errorf("invalid time range [%s, %s)", o.startOffset, o.stopOffset)
+	}
+
+	return nil
+}
+
+// needsTimestamps is true when the options rely on recorded receive times.
+func (o *options) needsTimestamps() bool {
+	return o.mode == ReplayOriginalTiming || o.startOffset > 0 || o.stopOffset > 0
+}
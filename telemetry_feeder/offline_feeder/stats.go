@@ -0,0 +1,108 @@
+package offline_feeder
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+	"github.com/sbezverk/tools/telemetry_feeder/capture"
+	"github.com/sbezverk/tools/telemetry_feeder/pcap"
+)
+
+// Stats are the offline feeder counters returned by GetStatsJson. The StatsSnapshot fields are
+// filled as by the live feeders, with the file standing for the transport, the received messages
+// and payload bytes are those of the replayed records.
+type Stats struct {
+	feeder.StatsSnapshot
+	File          string `json:"file"`
+	FileSizeBytes int64  `json:"file_size_bytes"`
+	// BytesRead and PercentComplete refer to the current pass over the file, the compressed
+	// bytes for a gzip compressed capture.
+	BytesRead            int64   `json:"bytes_read"`
+	PercentComplete      float64 `json:"percent_complete"`
+	RecordsReplayedTotal int64   `json:"records_replayed_total"`
+	// RecordsFilteredTotal counts the records read but left out by the record or time range.
+	RecordsFilteredTotal int64 `json:"records_filtered_total"`
+	// RecordsTotal is the number of records in the file, -1 until they are counted.
+	RecordsTotal               int64 `json:"records_total"`
+	CorruptRecordsSkippedTotal int64 `json:"corrupt_records_skipped_total"`
+	LoopsCompletedTotal        int64 `json:"loops_completed_total"`
+}
+
+// countingReader counts the bytes read from the file.
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// isCorrupt checks if err is returned for a single record which can be skipped.
+func isCorrupt(err error) bool {
+	return errors.Is(err, capture.ErrCorruptRecord) || errors.Is(err, pcap.ErrCorruptRecord)
+}
+
+// countRecords counts the records of the file in the background so that the progress of the
+// replay can be reported against the total.
+func (o *offFeeder) countRecords() {
+	f, err := os.Open(o.fn)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	reader, err := openReader(f, f, &o.opts)
+	if err != nil {
+		return
+	}
+	var n int64
+	for {
+		select {
+		case <-o.stop:
+			return
+		default:
+		}
+		if _, err := reader.Next(); err != nil && !isCorrupt(err) {
+			break
+		}
+		n++
+	}
+	o.recordsTotal.Store(n)
+}
+
+func (o *offFeeder) stats() Stats {
+	s := Stats{
+		StatsSnapshot: feeder.StatsSnapshot{
+			Transport:                   "offline",
+			StartTime:                   o.startTime.UTC(),
+			UptimeSeconds:               int64(time.Since(o.startTime).Seconds()),
+			MessagesReceivedTotal:       o.recordsReplayed.Load(),
+			PayloadBytesReceivedTotal:   o.payloadBytesReplayed.Load(),
+			TransportBytesReceivedTotal: o.bytesReadBefore.Load() + o.bytesRead.Load(),
+			FeedItemsEnqueuedTotal:      o.itemsEnqueued.Load(),
+			FeedErrorItemsEnqueuedTotal: o.errorItemsEnqueued.Load(),
+			ReceiveErrorsTotal:          o.readErrors.Load(),
+			ReceiveOtherErrorsTotal:     o.readErrors.Load(),
+		},
+		File:                       o.fn,
+		FileSizeBytes:              o.fileSize,
+		BytesRead:                  o.bytesRead.Load(),
+		RecordsReplayedTotal:       o.recordsReplayed.Load(),
+		RecordsFilteredTotal:       o.recordsFiltered.Load(),
+		RecordsTotal:               o.recordsTotal.Load(),
+		CorruptRecordsSkippedTotal: o.corruptSkipped.Load(),
+		LoopsCompletedTotal:        o.loopsCompleted.Load(),
+	}
+	if o.fileSize > 0 {
+		s.PercentComplete = float64(min(s.BytesRead, o.fileSize)) * 100 / float64(o.fileSize)
+	}
+	o.producers.FillStats(&s.StatsSnapshot)
+
+	return s
+}
@@ -0,0 +1,480 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+)
+
+// Link layer types, see https://www.tcpdump.org/linktypes.html
+const (
+	linkTypeNull     = 0
+	linkTypeEthernet = 1
+	linkTypeRaw      = 101
+	linkTypeLoop     = 108
+	linkTypeLinuxSLL = 113
+	linkTypeIPv4     = 228
+	linkTypeIPv6     = 229
+	linkTypeSLL2     = 276
+)
+
+const (
+	etherTypeIPv4 = 0x0800
+	etherTypeIPv6 = 0x86dd
+	etherTypeVLAN = 0x8100
+	etherTypeQinQ = 0x88a8
+
+	protoTCP      = 6
+	protoUDP      = 17
+	ipv6HopByHop  = 0
+	ipv6Routing   = 43
+	ipv6Fragment  = 44
+	ipv6DestOpts  = 60
+	tcpFlagFIN    = 0x01
+	tcpFlagSYN    = 0x02
+	tcpFlagRST    = 0x04
+	maxFragmented = 1024
+)
+
+// network returns the IP packet carried by a link layer frame.
+func network(linkType uint32, b []byte) []byte {
+	var etherType uint16
+	switch linkType {
+	case linkTypeEthernet:
+		if len(b) < 14 {
+			return nil
+		}
+		etherType, b = binary.BigEndian.Uint16(b[12:14]), b[14:]
+		for (etherType == etherTypeVLAN || etherType == etherTypeQinQ) && len(b) >= 4 {
+			etherType, b = binary.BigEndian.Uint16(b[2:4]), b[4:]
+		}
+	case linkTypeNull, linkTypeLoop:
+		// The address family is in the byte order of the capturing host, IPv4 is 2 everywhere.
+		if len(b) < 4 {
+			return nil
+		}
+		return b[4:]
+	case linkTypeRaw, linkTypeIPv4, linkTypeIPv6:
+		return b
+	case linkTypeLinuxSLL:
+		if len(b) < 16 {
+			return nil
+		}
+		etherType, b = binary.BigEndian.Uint16(b[14:16]), b[16:]
+	case linkTypeSLL2:
+		if len(b) < 20 {
+			return nil
+		}
+		etherType, b = binary.BigEndian.Uint16(b[0:2]), b[20:]
+	default:
+		return nil
+	}
+	if etherType != etherTypeIPv4 && etherType != etherTypeIPv6 {
+		return nil
+	}
+	return b
+}
+
+// datagram is an IP packet after fragment reassembly.
+type datagram struct {
+	src, dst net.IP
+	proto    byte
+	payload  []byte
+}
+
+// decode extracts the telemetry carried by a captured packet.
+func (r *Reader) decode(p *packet) {
+	b := network(p.linkType, p.data)
+	if len(b) == 0 {
+		return
+	}
+	var d *datagram
+	switch b[0] >> 4 {
+	case 4:
+		d = r.ipv4(b)
+	case 6:
+		d = r.ipv6(b)
+	}
+	if d == nil {
+		return
+	}
+	switch d.proto {
+	case protoUDP:
+		r.udp(p.ts, d)
+	case protoTCP:
+		r.tcp(p.ts, d)
+	}
+}
+
+func (r *Reader) ipv4(b []byte) *datagram {
+	if len(b) < 20 {
+		return nil
+	}
+	hl := int(b[0]&0x0f) * 4
+	total := int(binary.BigEndian.Uint16(b[2:4]))
+	if hl < 20 || total < hl || hl > len(b) {
+		return nil
+	}
+	// Frames may be padded beyond the end of the IP packet, a packet truncated by the capture
+	// snap length is left as is for the transport to report.
+	if total < len(b) {
+		b = b[:total]
+	}
+	d := &datagram{
+		src:     net.IP(append([]byte{}, b[12:16]...)),
+		dst:     net.IP(append([]byte{}, b[16:20]...)),
+		proto:   b[9],
+		payload: b[hl:],
+	}
+	ff := binary.BigEndian.Uint16(b[6:8])
+	more, offset := ff&0x2000 != 0, int(ff&0x1fff)*8
+	if !more && offset == 0 {
+		return d
+	}
+	return r.frags.add(d, uint32(binary.BigEndian.Uint16(b[4:6])), offset, more)
+}
+
+func (r *Reader) ipv6(b []byte) *datagram {
+	if len(b) < 40 {
+		return nil
+	}
+	// A zero payload length is used by jumbograms, the captured length is taken as is then.
+	if l := int(binary.BigEndian.Uint16(b[4:6])); l > 0 && 40+l < len(b) {
+		b = b[:40+l]
+	}
+	d := &datagram{
+		src:     net.IP(append([]byte{}, b[8:24]...)),
+		dst:     net.IP(append([]byte{}, b[24:40]...)),
+		proto:   b[6],
+		payload: b[40:],
+	}
+	for {
+		switch d.proto {
+		case ipv6HopByHop, ipv6Routing, ipv6DestOpts:
+			if len(d.payload) < 8 {
+				return nil
+			}
+			l := (int(d.payload[1]) + 1) * 8
+			if l > len(d.payload) {
+				return nil
+			}
+			d.proto, d.payload = d.payload[0], d.payload[l:]
+		case ipv6Fragment:
+			if len(d.payload) < 8 {
+				return nil
+			}
+			fo := binary.BigEndian.Uint16(d.payload[2:4])
+			id := binary.BigEndian.Uint32(d.payload[4:8])
+			d.proto, d.payload = d.payload[0], d.payload[8:]
+			return r.frags.add(d, id, int(fo&0xfff8), fo&1 != 0)
+		default:
+			return d
+		}
+	}
+}
+
+type fragKey struct {
+	src, dst string
+	id       uint32
+	proto    byte
+}
+
+type fragment struct {
+	offset int
+	data   []byte
+}
+
+type fragmented struct {
+	seq       int64
+	fragments []fragment
+	// total is the length of the reassembled payload, known once the last fragment is seen.
+	total int
+}
+
+// defragmenter reassembles fragmented IP datagrams, when too many datagrams are incomplete the
+// oldest is dropped.
+type defragmenter struct {
+	seq     int64
+	pending map[fragKey]*fragmented
+}
+
+func newDefragmenter() *defragmenter {
+	return &defragmenter{
+		pending: make(map[fragKey]*fragmented),
+	}
+}
+
+// add stores a fragment of d, the reassembled datagram is returned with the last missing
+// fragment, nil until then.
+func (f *defragmenter) add(d *datagram, id uint32, offset int, more bool) *datagram {
+	key := fragKey{src: string(d.src), dst: string(d.dst), id: id, proto: d.proto}
+	e, ok := f.pending[key]
+	if !ok {
+		if len(f.pending) >= maxFragmented {
+			f.evictOldest()
+		}
+		f.seq++
+		e = &fragmented{seq: f.seq, total: -1}
+		f.pending[key] = e
+	}
+	e.fragments = append(e.fragments, fragment{offset: offset, data: append([]byte{}, d.payload...)})
+	if !more {
+		e.total = offset + len(d.payload)
+	}
+	if e.total < 0 {
+		return nil
+	}
+	payload := make([]byte, e.total)
+	covered := make([]bool, e.total)
+	for _, fr := range e.fragments {
+		if fr.offset+len(fr.data) > e.total {
+			// Inconsistent fragments, the datagram cannot be reassembled.
+			delete(f.pending, key)
+			return nil
+		}
+		copy(payload[fr.offset:], fr.data)
+		for i := fr.offset; i < fr.offset+len(fr.data); i++ {
+			covered[i] = true
+		}
+	}
+	for _, c := range covered {
+		if !c {
+			return nil
+		}
+	}
+	delete(f.pending, key)
+	d.payload = payload
+
+	return d
+}
+
+func (f *defragmenter) evictOldest() {
+	var oldest fragKey
+	var seq int64 = -1
+	for k, e := range f.pending {
+		if seq < 0 || e.seq < seq {
+			oldest, seq = k, e.seq
+		}
+	}
+	delete(f.pending, oldest)
+}
+
+func (r *Reader) udp(ts time.Time, d *datagram) {
+	b := d.payload
+	if len(b) < 8 || !r.udpPorts[binary.BigEndian.Uint16(b[2:4])] {
+		return
+	}
+	addr := &net.UDPAddr{IP: d.src, Port: int(binary.BigEndian.Uint16(b[0:2]))}
+	l := int(binary.BigEndian.Uint16(b[4:6]))
+	if l < 8 || l > len(b) {
+		r.emit(&feeder.Feed{
+			ProducerAddr: addr,
+			Err:          fmt.Errorf("UDP datagram of %d bytes truncated to %d bytes in the capture", l, len(b)),
+			Transport:    feeder.TransportUDP,
+			Encoding:     feeder.EncodingJSON,
+			Framing:      feeder.FramingNone,
+			ReceivedAt:   ts,
+		})
+		return
+	}
+	payload := b[8:l]
+	m, err := feeder.MakeFeederMsgFromJson(payload, len(payload), feeder.TransportUDP)
+	if err != nil {
+		r.emit(&feeder.Feed{
+			ProducerAddr: addr,
+			Err:          err,
+			Transport:    feeder.TransportUDP,
+			Encoding:     feeder.EncodingJSON,
+			Framing:      feeder.FramingNone,
+			ReceivedAt:   ts,
+		})
+		return
+	}
+	m.ProducerAddr = addr
+	m.ReceivedAt = ts
+	r.emit(&m)
+}
+
+type streamKey struct {
+	src, dst     string
+	sport, dport uint16
+}
+
+// stream is one direction of a TCP connection.
+type stream struct {
+	addr *net.TCPAddr
+	// next is the sequence number of the next in order byte.
+	next uint32
+	buf  []byte
+	// outOfOrder holds segments received ahead of next.
+	outOfOrder      map[uint32][]byte
+	outOfOrderBytes int
+	// lost is set once the frame boundary is lost, the rest of the connection is ignored.
+	lost bool
+}
+
+func (r *Reader) tcp(ts time.Time, d *datagram) {
+	b := d.payload
+	if len(b) < 20 {
+		return
+	}
+	dport := binary.BigEndian.Uint16(b[2:4])
+	if !r.tcpPorts[dport] {
+		return
+	}
+	sport := binary.BigEndian.Uint16(b[0:2])
+	seq := binary.BigEndian.Uint32(b[4:8])
+	off := int(b[12]>>4) * 4
+	flags := b[13]
+	if off < 20 || off > len(b) {
+		return
+	}
+	payload := b[off:]
+	key := streamKey{src: string(d.src), dst: string(d.dst), sport: sport, dport: dport}
+	s := r.streams[key]
+	if flags&tcpFlagSYN != 0 {
+		s = &stream{
+			addr:       &net.TCPAddr{IP: d.src, Port: int(sport)},
+			next:       seq + 1,
+			outOfOrder: make(map[uint32][]byte),
+		}
+		r.streams[key] = s
+		return
+	}
+	if s == nil {
+		if len(payload) == 0 {
+			return
+		}
+		// The capture started after the connection was established, the first segment is
+		// assumed to start a frame.
+		s = &stream{
+			addr:       &net.TCPAddr{IP: d.src, Port: int(sport)},
+			next:       seq,
+			outOfOrder: make(map[uint32][]byte),
+		}
+		r.streams[key] = s
+	}
+	if !s.lost && len(payload) > 0 {
+		r.segment(ts, s, seq, payload)
+	}
+	if flags&(tcpFlagFIN|tcpFlagRST) == 0 {
+		return
+	}
+	delete(r.streams, key)
+	var err error
+	if flags&tcpFlagRST != 0 {
+		err = fmt.Errorf("connection with peer %s has been terminated with the error: %w", s.addr.String(), syscall.ECONNRESET)
+	} else {
+		err = fmt.Errorf("connection with peer %s has been closed cleanly: %w", s.addr.String(), io.EOF)
+	}
+	r.emit(&feeder.Feed{
+		ProducerAddr: s.addr,
+		Err:          err,
+		Transport:    feeder.TransportTCP,
+		Framing:      feeder.FramingCiscoXRST,
+		ReceivedAt:   ts,
+	})
+}
+
+// segment adds a TCP segment to the stream and emits the frames it completes.
+func (r *Reader) segment(ts time.Time, s *stream, seq uint32, payload []byte) {
+	if d := int32(seq - s.next); d > 0 {
+		if s.outOfOrderBytes+len(payload) > r.cfg.MaxMsgSize {
+			r.lose(ts, s, fmt.Errorf("TCP stream from %s has a gap of more than %d bytes in the capture", s.addr, r.cfg.MaxMsgSize))
+			return
+		}
+		if _, ok := s.outOfOrder[seq]; !ok {
+			s.outOfOrder[seq] = append([]byte{}, payload...)
+			s.outOfOrderBytes += len(payload)
+		}
+		return
+	}
+	s.append(seq, payload)
+	// Segments received ahead of time may now be in order.
+	for progress := true; progress && len(s.outOfOrder) > 0; {
+		progress = false
+		for seq, p := range s.outOfOrder {
+			if int32(seq-s.next) > 0 {
+				continue
+			}
+			delete(s.outOfOrder, seq)
+			s.outOfOrderBytes -= len(p)
+			s.append(seq, p)
+			progress = true
+		}
+	}
+	r.frames(ts, s)
+}
+
+// append adds the part of a segment starting at or before next which was not seen yet.
+func (s *stream) append(seq uint32, p []byte) {
+	if d := int(s.next - seq); d > 0 {
+		if d >= len(p) {
+			return
+		}
+		p = p[d:]
+	}
+	s.buf = append(s.buf, p...)
+	s.next += uint32(len(p))
+}
+
+// lose reports the loss of the frame boundary, the rest of the connection is ignored.
+func (r *Reader) lose(ts time.Time, s *stream, err error) {
+	s.lost = true
+	s.buf = nil
+	s.outOfOrder = nil
+	s.outOfOrderBytes = 0
+	r.emit(&feeder.Feed{
+		ProducerAddr: s.addr,
+		Err:          err,
+		Transport:    feeder.TransportTCP,
+		Framing:      feeder.FramingCiscoXRST,
+		ReceivedAt:   ts,
+	})
+}
+
+// frames emits the complete XR ST frames of the stream the way tcp_feeder does.
+func (r *Reader) frames(ts time.Time, s *stream) {
+	consumed := 0
+	for len(s.buf)-consumed >= feeder.XRSTHeaderLength {
+		h, _ := feeder.ParseXRSTHeader(s.buf[consumed:])
+		if uint64(h.Length) > uint64(r.cfg.MaxMsgSize) {
+			r.lose(ts, s, fmt.Errorf("Cisco XR ST framed message length %d exceeds maximum %d", h.Length, r.cfg.MaxMsgSize))
+			return
+		}
+		end := consumed + feeder.XRSTHeaderLength + int(h.Length)
+		if end > len(s.buf) {
+			break
+		}
+		payload := append([]byte{}, s.buf[consumed+feeder.XRSTHeaderLength:end]...)
+		consumed = end
+		if h.MsgType == feeder.XRSTMsgTypeHeartbeat {
+			continue
+		}
+		encoding, err := h.PayloadEncoding(payload)
+		if err != nil {
+			r.emit(&feeder.Feed{
+				ProducerAddr: s.addr,
+				Err:          err,
+				Transport:    feeder.TransportTCP,
+				Framing:      feeder.FramingCiscoXRST,
+				ReceivedAt:   ts,
+			})
+			continue
+		}
+		r.emit(&feeder.Feed{
+			ProducerAddr: s.addr,
+			TelemetryMsg: payload,
+			Transport:    feeder.TransportTCP,
+			Encoding:     encoding,
+			Framing:      feeder.FramingCiscoXRST,
+			ReceivedAt:   ts,
+		})
+	}
+	s.buf = append(s.buf[:0], s.buf[consumed:]...)
+}
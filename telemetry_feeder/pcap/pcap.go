@@ -0,0 +1,403 @@
+// Package pcap extracts telemetry messages from pcap and pcapng packet captures.
+//
+// UDP datagrams sent to the configured ports are decoded exactly as udp_feeder decodes them,
+// TCP streams to the configured ports are reassembled and split into Cisco XR ST frames as
+// tcp_feeder does. Ethernet (with VLAN tags), raw IP, BSD loopback and Linux cooked captures
+// of IPv4 and IPv6 traffic are supported, fragmented IP datagrams are reassembled.
+package pcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"time"
+
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+)
+
+const (
+	// DefaultMaxMsgSize is the default limit of a message reassembled from a TCP stream.
+	DefaultMaxMsgSize = 1024 * 1024 * 4
+
+	readBufSize = 1024 * 64
+	// maxBlockLength limits the size of a packet record or a pcapng block.
+	maxBlockLength = 1024 * 1024 * 64
+
+	magicMicros   = 0xa1b2c3d4
+	magicNanos    = 0xa1b23c4d
+	blockSHB      = 0x0a0d0d0a
+	blockIDB      = 1
+	blockOPB      = 2
+	blockSPB      = 3
+	blockEPB      = 6
+	byteOrderBOM  = 0x1a2b3c4d
+	optEnd        = 0
+	optIfTsresol  = 9
+	defaultTsUnit = 1000 * 1000
+)
+
+var (
+	ErrNotCapture = errors.New("not a pcap or pcapng capture")
+	// ErrCorruptRecord is returned for a pcapng packet block which cannot be decoded although
+	// its length is intact, reading may continue with the next block.
+	ErrCorruptRecord = errors.New("corrupted pcapng packet block")
+)
+
+// Config selects the traffic extracted from the capture.
+type Config struct {
+	// UDPPorts and TCPPorts are the destination ports telemetry is sent to.
+	UDPPorts []int
+	TCPPorts []int
+	// MaxMsgSize limits the size of a message reassembled from a TCP stream,
+	// DefaultMaxMsgSize when 0.
+	MaxMsgSize int
+}
+
+func (c *Config) validate() error {
+	if len(c.UDPPorts) == 0 && len(c.TCPPorts) == 0 {
+		return fmt.Errorf("no UDP or TCP telemetry ports configured")
+	}
+	for _, p := range append(append([]int{}, c.UDPPorts...), c.TCPPorts...) {
+		if p <= 0 || p > 0xffff {
+			return fmt.Errorf("invalid port %d", p)
+		}
+	}
+	if c.MaxMsgSize < 0 {
+		return fmt.Errorf("invalid maximum message size %d", c.MaxMsgSize)
+	}
+	if c.MaxMsgSize == 0 {
+		c.MaxMsgSize = DefaultMaxMsgSize
+	}
+
+	return nil
+}
+
+// IsCapture checks if b, the first 4 bytes of a file, opens a pcap or pcapng capture.
+func IsCapture(b []byte) bool {
+	if len(b) < 4 {
+		return false
+	}
+	switch binary.BigEndian.Uint32(b) {
+	case magicMicros, magicNanos, blockSHB:
+		return true
+	}
+	switch binary.LittleEndian.Uint32(b) {
+	case magicMicros, magicNanos:
+		return true
+	}
+	return false
+}
+
+// iface is a pcapng interface, timestamps are in units of 1/unitsPerSecond seconds.
+type iface struct {
+	linkType       uint32
+	snapLen        uint32
+	unitsPerSecond uint64
+}
+
+type packet struct {
+	ts       time.Time
+	linkType uint32
+	data     []byte
+}
+
+// Reader returns the telemetry messages found in a capture.
+type Reader struct {
+	r        *bufio.Reader
+	cfg      Config
+	udpPorts map[uint16]bool
+	tcpPorts map[uint16]bool
+	ng       bool
+	order    binary.ByteOrder
+	// linkType and unitsPerSecond describe the packets of a pcap capture.
+	linkType       uint32
+	unitsPerSecond uint64
+	// ifaces are the interfaces of the current pcapng section.
+	ifaces  []iface
+	frags   *defragmenter
+	streams map[streamKey]*stream
+	pending []*feeder.Feed
+}
+
+// NewReader reads the file header of the capture, ErrNotCapture is returned when r is neither
+// a pcap nor a pcapng capture.
+func NewReader(r io.Reader, cfg Config) (*Reader, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	pr := &Reader{
+		r:        bufio.NewReaderSize(r, readBufSize),
+		cfg:      cfg,
+		udpPorts: make(map[uint16]bool),
+		tcpPorts: make(map[uint16]bool),
+		frags:    newDefragmenter(),
+		streams:  make(map[streamKey]*stream),
+	}
+	for _, p := range cfg.UDPPorts {
+		pr.udpPorts[uint16(p)] = true
+	}
+	for _, p := range cfg.TCPPorts {
+		pr.tcpPorts[uint16(p)] = true
+	}
+	magic, err := pr.r.Peek(4)
+	if err != nil || !IsCapture(magic) {
+		return nil, ErrNotCapture
+	}
+	if binary.BigEndian.Uint32(magic) == blockSHB {
+		pr.ng = true
+		// The section header block sets the byte order, it is read with the first packet.
+		return pr, nil
+	}
+	if err := pr.readFileHeader(); err != nil {
+		return nil, err
+	}
+
+	return pr, nil
+}
+
+func (r *Reader) readFileHeader() error {
+	b := make([]byte, 24)
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		return fmt.Errorf("failed to read pcap file header: %w", noEOF(err))
+	}
+	r.order = binary.ByteOrder(binary.BigEndian)
+	magic := binary.BigEndian.Uint32(b)
+	if magic != magicMicros && magic != magicNanos {
+		r.order = binary.LittleEndian
+		magic = binary.LittleEndian.Uint32(b)
+	}
+	r.unitsPerSecond = defaultTsUnit
+	if magic == magicNanos {
+		r.unitsPerSecond = uint64(time.Second)
+	}
+	r.linkType = r.order.Uint32(b[20:24])
+
+	return nil
+}
+
+// noEOF turns io.EOF in the middle of a record into io.ErrUnexpectedEOF.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// timestamp converts a count of 1/unitsPerSecond seconds into time.
+func timestamp(units, unitsPerSecond uint64) time.Time {
+	sec, rem := units/unitsPerSecond, units%unitsPerSecond
+	hi, lo := bits.Mul64(rem, uint64(time.Second))
+	nsec, _ := bits.Div64(hi, lo, unitsPerSecond)
+
+	return time.Unix(int64(sec), int64(nsec))
+}
+
+// Next returns the next telemetry message, io.EOF is returned at the end of the capture and
+// io.ErrUnexpectedEOF when the capture ends in the middle of a record, ErrCorruptRecord for a
+// packet block which cannot be decoded. Messages which cannot be decoded are returned as feeds
+// with Err set, the way the live feeders report them.
+func (r *Reader) Next() (*feeder.Feed, error) {
+	for len(r.pending) == 0 {
+		p, err := r.nextPacket()
+		if err != nil {
+			return nil, err
+		}
+		r.decode(p)
+	}
+	f := r.pending[0]
+	r.pending[0] = nil
+	r.pending = r.pending[1:]
+
+	return f, nil
+}
+
+func (r *Reader) emit(f *feeder.Feed) {
+	r.pending = append(r.pending, f)
+}
+
+func (r *Reader) nextPacket() (*packet, error) {
+	if r.ng {
+		return r.nextBlock()
+	}
+	hdr := make([]byte, 16)
+	if _, err := io.ReadFull(r.r, hdr); err != nil {
+		return nil, err
+	}
+	inclLen := r.order.Uint32(hdr[8:12])
+	if inclLen > maxBlockLength {
+		return nil, fmt.Errorf("pcap packet record of %d bytes exceeds maximum %d", inclLen, maxBlockLength)
+	}
+	data := make([]byte, inclLen)
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return nil, noEOF(err)
+	}
+	units := uint64(r.order.Uint32(hdr[0:4]))*r.unitsPerSecond + uint64(r.order.Uint32(hdr[4:8]))
+
+	return &packet{
+		ts:       timestamp(units, r.unitsPerSecond),
+		linkType: r.linkType,
+		data:     data,
+	}, nil
+}
+
+// nextBlock reads pcapng blocks until a packet block is found, blocks of other types are
+// skipped.
+func (r *Reader) nextBlock() (*packet, error) {
+	for {
+		hdr := make([]byte, 8)
+		if _, err := io.ReadFull(r.r, hdr); err != nil {
+			return nil, err
+		}
+		if binary.BigEndian.Uint32(hdr) == blockSHB {
+			if err := r.readSectionHeader(hdr); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if r.order == nil {
+			return nil, fmt.Errorf("pcapng capture does not start with a section header block")
+		}
+		blockType, length := r.order.Uint32(hdr[0:4]), r.order.Uint32(hdr[4:8])
+		if length < 12 || length%4 != 0 || length > maxBlockLength {
+			return nil, fmt.Errorf("invalid pcapng block length %d", length)
+		}
+		b := make([]byte, length-8)
+		if _, err := io.ReadFull(r.r, b); err != nil {
+			return nil, noEOF(err)
+		}
+		// The block ends with a copy of its length.
+		body := b[:len(b)-4]
+		switch blockType {
+		case blockIDB:
+			if err := r.readInterface(body); err != nil {
+				return nil, err
+			}
+		case blockEPB, blockOPB:
+			return r.readPacketBlock(blockType, body)
+		case blockSPB:
+			return r.readSimplePacket(body)
+		}
+	}
+}
+
+func (r *Reader) readSectionHeader(hdr []byte) error {
+	bom := make([]byte, 4)
+	if _, err := io.ReadFull(r.r, bom); err != nil {
+		return fmt.Errorf("failed to read pcapng section header: %w", noEOF(err))
+	}
+	switch {
+	case binary.BigEndian.Uint32(bom) == byteOrderBOM:
+		r.order = binary.BigEndian
+	case binary.LittleEndian.Uint32(bom) == byteOrderBOM:
+		r.order = binary.LittleEndian
+	default:
+		return fmt.Errorf("invalid pcapng byte order magic %x", bom)
+	}
+	length := r.order.Uint32(hdr[4:8])
+	if length < 28 || length%4 != 0 || length > maxBlockLength {
+		return fmt.Errorf("invalid pcapng section header length %d", length)
+	}
+	if _, err := io.CopyN(io.Discard, r.r, int64(length-12)); err != nil {
+		return fmt.Errorf("failed to read pcapng section header: %w", noEOF(err))
+	}
+	// Interface ids are scoped to the section.
+	r.ifaces = r.ifaces[:0]
+
+	return nil
+}
+
+func (r *Reader) readInterface(body []byte) error {
+	if len(body) < 8 {
+		return fmt.Errorf("pcapng interface description block too short: %d bytes", len(body))
+	}
+	ifc := iface{
+		linkType:       uint32(r.order.Uint16(body[0:2])),
+		snapLen:        r.order.Uint32(body[4:8]),
+		unitsPerSecond: defaultTsUnit,
+	}
+	for opts := body[8:]; len(opts) >= 4; {
+		code, l := r.order.Uint16(opts[0:2]), int(r.order.Uint16(opts[2:4]))
+		if code == optEnd || 4+l > len(opts) {
+			break
+		}
+		if code == optIfTsresol && l >= 1 {
+			v := opts[4]
+			if v&0x80 != 0 {
+				if v&0x7f > 63 {
+					return fmt.Errorf("unsupported pcapng timestamp resolution 2^-%d", v&0x7f)
+				}
+				ifc.unitsPerSecond = 1 << (v & 0x7f)
+			} else {
+				if v > 19 {
+					return fmt.Errorf("unsupported pcapng timestamp resolution 10^-%d", v)
+				}
+				ifc.unitsPerSecond = 1
+				for i := byte(0); i < v; i++ {
+					ifc.unitsPerSecond *= 10
+				}
+			}
+		}
+		// Option values are padded to 32 bits.
+		opts = opts[min(len(opts), 4+(l+3)&^3):]
+	}
+	r.ifaces = append(r.ifaces, ifc)
+
+	return nil
+}
+
+func (r *Reader) iface(id uint32) (iface, error) {
+	if int(id) >= len(r.ifaces) {
+		return iface{}, fmt.Errorf("%w: unknown interface %d", ErrCorruptRecord, id)
+	}
+	return r.ifaces[id], nil
+}
+
+func (r *Reader) readPacketBlock(blockType uint32, body []byte) (*packet, error) {
+	if len(body) < 20 {
+		return nil, fmt.Errorf("%w: packet block too short, %d bytes", ErrCorruptRecord, len(body))
+	}
+	var id uint32
+	if blockType == blockOPB {
+		id = uint32(r.order.Uint16(body[0:2]))
+	} else {
+		id = r.order.Uint32(body[0:4])
+	}
+	ifc, err := r.iface(id)
+	if err != nil {
+		return nil, err
+	}
+	units := uint64(r.order.Uint32(body[4:8]))<<32 | uint64(r.order.Uint32(body[8:12]))
+	capLen := r.order.Uint32(body[12:16])
+	if uint64(capLen) > uint64(len(body)-20) {
+		return nil, fmt.Errorf("%w: packet of %d bytes exceeds its block", ErrCorruptRecord, capLen)
+	}
+
+	return &packet{
+		ts:       timestamp(units, ifc.unitsPerSecond),
+		linkType: ifc.linkType,
+		data:     body[20 : 20+capLen],
+	}, nil
+}
+
+// readSimplePacket reads a simple packet block, it carries no timestamp.
+func (r *Reader) readSimplePacket(body []byte) (*packet, error) {
+	if len(body) < 4 {
+		return nil, fmt.Errorf("%w: simple packet block too short, %d bytes", ErrCorruptRecord, len(body))
+	}
+	ifc, err := r.iface(0)
+	if err != nil {
+		return nil, err
+	}
+	capLen := min(uint64(r.order.Uint32(body[0:4])), uint64(len(body)-4))
+	if ifc.snapLen > 0 {
+		capLen = min(capLen, uint64(ifc.snapLen))
+	}
+
+	return &packet{
+		linkType: ifc.linkType,
+		data:     body[4 : 4+capLen],
+	}, nil
+}
@@ -0,0 +1,245 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+)
+
+var (
+	producerIP  = net.IPv4(10, 0, 0, 1).To4()
+	collectorIP = net.IPv4(10, 0, 0, 2).To4()
+	captureTime = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+)
+
+// ethernet wraps an IP packet into an Ethernet frame with a VLAN tag.
+func ethernet(ip []byte) []byte {
+	b := make([]byte, 12, 18+len(ip))
+	b = binary.BigEndian.AppendUint16(b, etherTypeVLAN)
+	b = binary.BigEndian.AppendUint16(b, 100)
+	b = binary.BigEndian.AppendUint16(b, etherTypeIPv4)
+	return append(b, ip...)
+}
+
+func ipv4Packet(proto byte, id uint16, fragOffset int, more bool, payload []byte) []byte {
+	b := make([]byte, 20, 20+len(payload))
+	b[0] = 0x45
+	binary.BigEndian.PutUint16(b[2:4], uint16(20+len(payload)))
+	binary.BigEndian.PutUint16(b[4:6], id)
+	ff := uint16(fragOffset / 8)
+	if more {
+		ff |= 0x2000
+	}
+	binary.BigEndian.PutUint16(b[6:8], ff)
+	b[8] = 64
+	b[9] = proto
+	copy(b[12:16], producerIP)
+	copy(b[16:20], collectorIP)
+	return append(b, payload...)
+}
+
+func udpDatagram(sport, dport uint16, payload []byte) []byte {
+	b := binary.BigEndian.AppendUint16(nil, sport)
+	b = binary.BigEndian.AppendUint16(b, dport)
+	b = binary.BigEndian.AppendUint16(b, uint16(8+len(payload)))
+	b = binary.BigEndian.AppendUint16(b, 0)
+	return append(b, payload...)
+}
+
+func tcpSegment(sport, dport uint16, seq uint32, flags byte, payload []byte) []byte {
+	b := make([]byte, 20, 20+len(payload))
+	binary.BigEndian.PutUint16(b[0:2], sport)
+	binary.BigEndian.PutUint16(b[2:4], dport)
+	binary.BigEndian.PutUint32(b[4:8], seq)
+	b[12] = 5 << 4
+	b[13] = flags
+	return append(b, payload...)
+}
+
+func xrstFrame(msgType feeder.XRSTMsgType, encoding feeder.XRSTEncoding, payload []byte) []byte {
+	b := binary.BigEndian.AppendUint16(nil, uint16(msgType))
+	b = binary.BigEndian.AppendUint16(b, uint16(encoding))
+	b = binary.BigEndian.AppendUint16(b, 1)
+	b = binary.BigEndian.AppendUint16(b, 0)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(payload)))
+	return append(b, payload...)
+}
+
+// pcapFile writes Ethernet frames into a little-endian microsecond pcap, a millisecond apart.
+func pcapFile(frames ...[]byte) []byte {
+	b := binary.LittleEndian.AppendUint32(nil, magicMicros)
+	b = binary.LittleEndian.AppendUint16(b, 2)
+	b = binary.LittleEndian.AppendUint16(b, 4)
+	b = append(b, make([]byte, 8)...)
+	b = binary.LittleEndian.AppendUint32(b, 65535)
+	b = binary.LittleEndian.AppendUint32(b, linkTypeEthernet)
+	for i, f := range frames {
+		ts := captureTime.Add(time.Duration(i) * time.Millisecond)
+		b = binary.LittleEndian.AppendUint32(b, uint32(ts.Unix()))
+		b = binary.LittleEndian.AppendUint32(b, uint32(ts.Nanosecond()/1000))
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(f)))
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(f)))
+		b = append(b, f...)
+	}
+	return b
+}
+
+func pcapngBlock(blockType uint32, body []byte) []byte {
+	for len(body)%4 != 0 {
+		body = append(body, 0)
+	}
+	b := binary.BigEndian.AppendUint32(nil, blockType)
+	b = binary.BigEndian.AppendUint32(b, uint32(12+len(body)))
+	b = append(b, body...)
+	return binary.BigEndian.AppendUint32(b, uint32(12+len(body)))
+}
+
+// pcapngFile writes raw IP packets into a big-endian pcapng with nanosecond timestamps, a
+// millisecond apart.
+func pcapngFile(packets ...[]byte) []byte {
+	shb := binary.BigEndian.AppendUint32(nil, byteOrderBOM)
+	shb = binary.BigEndian.AppendUint16(shb, 1)
+	shb = binary.BigEndian.AppendUint16(shb, 0)
+	shb = binary.BigEndian.AppendUint64(shb, ^uint64(0))
+	b := pcapngBlock(blockSHB, shb)
+	idb := binary.BigEndian.AppendUint16(nil, linkTypeRaw)
+	idb = binary.BigEndian.AppendUint16(idb, 0)
+	idb = binary.BigEndian.AppendUint32(idb, 0)
+	idb = binary.BigEndian.AppendUint16(idb, optIfTsresol)
+	idb = binary.BigEndian.AppendUint16(idb, 1)
+	idb = append(idb, 9, 0, 0, 0)
+	idb = binary.BigEndian.AppendUint32(idb, optEnd)
+	b = append(b, pcapngBlock(blockIDB, idb)...)
+	for i, p := range packets {
+		ts := uint64(captureTime.Add(time.Duration(i) * time.Millisecond).UnixNano())
+		epb := binary.BigEndian.AppendUint32(nil, 0)
+		epb = binary.BigEndian.AppendUint32(epb, uint32(ts>>32))
+		epb = binary.BigEndian.AppendUint32(epb, uint32(ts))
+		epb = binary.BigEndian.AppendUint32(epb, uint32(len(p)))
+		epb = binary.BigEndian.AppendUint32(epb, uint32(len(p)))
+		b = append(b, pcapngBlock(blockEPB, append(epb, p...))...)
+	}
+	return b
+}
+
+func readAll(t *testing.T, file []byte, cfg Config) []*feeder.Feed {
+	t.Helper()
+	r, err := NewReader(bytes.NewReader(file), cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var feeds []*feeder.Feed
+	for {
+		f, err := r.Next()
+		if err == io.EOF {
+			return feeds
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		feeds = append(feeds, f)
+	}
+}
+
+func TestPcapUDP(t *testing.T) {
+	json := []byte(`{"node_id_str":"r1"}`)
+	nxos := append([]byte{0x01, 0x02, 0, byte(len(json)), 0, 0}, json...)
+	file := pcapFile(
+		ethernet(ipv4Packet(protoUDP, 1, 0, false, udpDatagram(40000, 57500, json))),
+		// Not a telemetry port.
+		ethernet(ipv4Packet(protoUDP, 2, 0, false, udpDatagram(40000, 53, json))),
+		ethernet(ipv4Packet(protoUDP, 3, 0, false, udpDatagram(40000, 57500, nxos))),
+		ethernet(ipv4Packet(protoUDP, 4, 0, false, udpDatagram(40000, 57500, []byte("garbage")))),
+	)
+	feeds := readAll(t, file, Config{UDPPorts: []int{57500}})
+	if len(feeds) != 3 {
+		t.Fatalf("expected 3 feeds, got %d", len(feeds))
+	}
+	if string(feeds[0].TelemetryMsg) != string(json) || feeds[0].Framing != feeder.FramingNone ||
+		feeds[0].ProducerAddr.String() != "10.0.0.1:40000" || !feeds[0].ReceivedAt.Equal(captureTime) {
+		t.Fatalf("unexpected feed %+v", feeds[0])
+	}
+	if string(feeds[1].TelemetryMsg) != string(json) || feeds[1].Framing != feeder.FramingCiscoNXOSUDP ||
+		!feeds[1].ReceivedAt.Equal(captureTime.Add(2*time.Millisecond)) {
+		t.Fatalf("unexpected feed %+v", feeds[1])
+	}
+	if feeds[2].Err == nil || feeds[2].TelemetryMsg != nil {
+		t.Fatalf("expected error feed, got %+v", feeds[2])
+	}
+}
+
+func TestPcapngFragmentedUDP(t *testing.T) {
+	json := []byte(`{"encoding_path":"Cisco-IOS-XR-infra-statsd-oper:infra-statistics","data_json":[]}`)
+	datagram := udpDatagram(40000, 57500, json)
+	// Fragments arrive out of order.
+	file := pcapngFile(
+		ipv4Packet(protoUDP, 7, 48, false, datagram[48:]),
+		ipv4Packet(protoUDP, 7, 0, true, datagram[:24]),
+		ipv4Packet(protoUDP, 7, 24, true, datagram[24:48]),
+	)
+	feeds := readAll(t, file, Config{UDPPorts: []int{57500}})
+	if len(feeds) != 1 || string(feeds[0].TelemetryMsg) != string(json) {
+		t.Fatalf("unexpected feeds %+v", feeds)
+	}
+	if want := captureTime.Add(2 * time.Millisecond); !feeds[0].ReceivedAt.Equal(want) {
+		t.Fatalf("expected receive time %s, got %s", want, feeds[0].ReceivedAt)
+	}
+}
+
+func TestPcapTCPReassembly(t *testing.T) {
+	gpb := []byte{0x0a, 0x02, 'r', '1'}
+	json := []byte(`{"node_id_str":"r1"}`)
+	stream := append(xrstFrame(feeder.XRSTMsgTypeTelemetryData, feeder.XRSTEncodingGPB, gpb),
+		xrstFrame(feeder.XRSTMsgTypeHeartbeat, feeder.XRSTEncodingUnset, nil)...)
+	stream = append(stream, xrstFrame(feeder.XRSTMsgTypeTelemetryData, feeder.XRSTEncodingJSON, json)...)
+	const isn = 0xfffffff0
+	seg := func(from, to int) []byte {
+		return ethernet(ipv4Packet(protoTCP, 0, 0, false, tcpSegment(40000, 57400, isn+1+uint32(from), 0, stream[from:to])))
+	}
+	file := pcapFile(
+		ethernet(ipv4Packet(protoTCP, 0, 0, false, tcpSegment(40000, 57400, isn, tcpFlagSYN, nil))),
+		seg(0, 10),
+		// Out of order, then a retransmission overlapping data already seen.
+		seg(20, len(stream)),
+		seg(5, 20),
+		ethernet(ipv4Packet(protoTCP, 0, 0, false, tcpSegment(40000, 57400, isn+1+uint32(len(stream)), tcpFlagFIN, nil))),
+	)
+	feeds := readAll(t, file, Config{TCPPorts: []int{57400}})
+	if len(feeds) != 3 {
+		t.Fatalf("expected 3 feeds, got %+v", feeds)
+	}
+	if string(feeds[0].TelemetryMsg) != string(gpb) || feeds[0].Encoding != feeder.EncodingGPB ||
+		feeds[0].Transport != feeder.TransportTCP || feeds[0].ProducerAddr.String() != "10.0.0.1:40000" {
+		t.Fatalf("unexpected feed %+v", feeds[0])
+	}
+	if string(feeds[1].TelemetryMsg) != string(json) || feeds[1].Encoding != feeder.EncodingJSON {
+		t.Fatalf("unexpected feed %+v", feeds[1])
+	}
+	if !errors.Is(feeds[2].Err, io.EOF) {
+		t.Fatalf("expected connection closed feed, got %+v", feeds[2])
+	}
+}
+
+func TestNewReaderErrors(t *testing.T) {
+	file := pcapFile()
+	if _, err := NewReader(bytes.NewReader(file), Config{}); err == nil {
+		t.Fatal("expected error without ports")
+	}
+	if _, err := NewReader(bytes.NewReader([]byte("MDTCAP")), Config{UDPPorts: []int{57500}}); !errors.Is(err, ErrNotCapture) {
+		t.Fatalf("expected ErrNotCapture, got %v", err)
+	}
+	truncated := pcapFile(ethernet(ipv4Packet(protoUDP, 1, 0, false, udpDatagram(40000, 57500, []byte("{}")))))
+	r, err := NewReader(bytes.NewReader(truncated[:len(truncated)-3]), Config{UDPPorts: []int{57500}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := r.Next(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
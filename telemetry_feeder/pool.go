@@ -0,0 +1,124 @@
+package telemetry_feeder
+
+import (
+	"math/bits"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// Buffers are pooled in power of two size classes from 512 bytes to 64 MB, larger payloads
+	// are allocated as usual.
+	minPoolClassShift = 9
+	maxPoolClassShift = 26
+	poolClasses       = maxPoolClassShift - minPoolClassShift + 1
+)
+
+// BufferPool recycles the TelemetryMsg buffers of feeds. Feeders using a pool back the payload
+// of every feed with a pooled buffer, the consumer hands it back with Feed.Release once done
+// with the payload. Feeds which are never released are collected as usual.
+type BufferPool struct {
+	classes [poolClasses]sync.Pool
+	gets    atomic.Int64
+	misses  atomic.Int64
+	puts    atomic.Int64
+}
+
+// BufferPoolStats are the pool counters, misses counts buffers which had to be allocated.
+type BufferPoolStats struct {
+	Gets   int64 `json:"gets"`
+	Misses int64 `json:"misses"`
+	Puts   int64 `json:"puts"`
+}
+
+func NewBufferPool() *BufferPool {
+	return &BufferPool{}
+}
+
+// poolClass returns the size class of a buffer of n bytes, -1 when n is too large to pool.
+func poolClass(n int) int {
+	if n <= 1<<minPoolClassShift {
+		return 0
+	}
+	shift := bits.Len(uint(n - 1))
+	if shift > maxPoolClassShift {
+		return -1
+	}
+	return shift - minPoolClassShift
+}
+
+// get returns a buffer of length n, nil is returned when n is too large to pool.
+func (p *BufferPool) get(n int) *[]byte {
+	class := poolClass(n)
+	if class < 0 {
+		return nil
+	}
+	p.gets.Add(1)
+	if b, ok := p.classes[class].Get().(*[]byte); ok {
+		*b = (*b)[:n]
+		return b
+	}
+	p.misses.Add(1)
+	b := make([]byte, n, 1<<(class+minPoolClassShift))
+
+	return &b
+}
+
+func (p *BufferPool) put(b *[]byte) {
+	class := poolClass(cap(*b))
+	if class < 0 || cap(*b) != 1<<(class+minPoolClassShift) {
+		return
+	}
+	p.puts.Add(1)
+	p.classes[class].Put(b)
+}
+
+func (p *BufferPool) Stats() BufferPoolStats {
+	return BufferPoolStats{
+		Gets:   p.gets.Load(),
+		Misses: p.misses.Load(),
+		Puts:   p.puts.Load(),
+	}
+}
+
+// CopyTelemetryMsg sets TelemetryMsg to a copy of b, backed by a buffer of pool when pool is
+// not nil.
+func (f *Feed) CopyTelemetryMsg(b []byte, pool *BufferPool) {
+	f.TelemetryMsg = f.allocTelemetryMsg(len(b), pool)
+	copy(f.TelemetryMsg, b)
+}
+
+// allocTelemetryMsg returns a buffer of n bytes for TelemetryMsg, taken from pool when pool is
+// not nil.
+func (f *Feed) allocTelemetryMsg(n int, pool *BufferPool) []byte {
+	if pool != nil {
+		if b := pool.get(n); b != nil {
+			f.pool, f.pooled = pool, b
+			return *b
+		}
+	}
+	return make([]byte, n)
+}
+
+// AllocTelemetryMsg sets TelemetryMsg to a buffer of n bytes, taken from pool when pool is not
+// nil, for the transport to read the payload into.
+func (f *Feed) AllocTelemetryMsg(n int, pool *BufferPool) {
+	f.TelemetryMsg = f.allocTelemetryMsg(n, pool)
+}
+
+// Release returns the payload buffer of a feed built with a BufferPool to the pool, neither
+// TelemetryMsg nor slices of it may be used afterwards. It is a no-op for feeds not backed by
+// a pool, for copies whose TelemetryMsg was replaced and when called again. Feeds shared by
+// several consumers, as by mux_feeder.Fanout, must not be released.
+func (f *Feed) Release() {
+	if f == nil || f.pool == nil {
+		return
+	}
+	pool, b := f.pool, f.pooled
+	f.pool, f.pooled = nil, nil
+	if cap(f.TelemetryMsg) == 0 || &f.TelemetryMsg[:1][0] != &(*b)[:1][0] {
+		return
+	}
+	f.TelemetryMsg = nil
+	pool.put(b)
+}
@@ -0,0 +1,97 @@
+package telemetry_feeder
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPoolClass(t *testing.T) {
+	tests := []struct {
+		n    int
+		want int
+	}{
+		{n: 0, want: 0},
+		{n: 512, want: 0},
+		{n: 513, want: 1},
+		{n: 1024, want: 1},
+		{n: 1 << maxPoolClassShift, want: poolClasses - 1},
+		{n: 1<<maxPoolClassShift + 1, want: -1},
+	}
+	for _, tt := range tests {
+		if got := poolClass(tt.n); got != tt.want {
+			t.Fatalf("poolClass(%d): want %d got %d", tt.n, tt.want, got)
+		}
+	}
+}
+
+func TestMakeFeederMsgWithPoolRelease(t *testing.T) {
+	pool := NewBufferPool()
+	payload := []byte(`{"encoding_path":"rib"}`)
+	f, err := MakeFeederMsgWithPool(payload, len(payload), TransportUDP, pool)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(f.TelemetryMsg, payload) || cap(f.TelemetryMsg) != 512 {
+		t.Fatalf("unexpected payload %q of capacity %d", f.TelemetryMsg, cap(f.TelemetryMsg))
+	}
+	// A copy carrying another payload, as made by gpbkv.ConvertFeed, does not own the buffer.
+	converted := f
+	converted.TelemetryMsg = []byte(`{}`)
+	converted.Release()
+	if got := pool.Stats(); got.Gets != 1 || got.Misses != 1 || got.Puts != 0 {
+		t.Fatalf("unexpected pool stats %+v", got)
+	}
+	f.Release()
+	f.Release()
+	if f.TelemetryMsg != nil {
+		t.Fatalf("expected payload to be cleared, got %q", f.TelemetryMsg)
+	}
+	if got := pool.Stats(); got.Puts != 1 {
+		t.Fatalf("expected a single buffer returned, got %+v", got)
+	}
+	// Feeds without a pool are not affected.
+	plain, _ := MakeFeederMsgFromJson(payload, len(payload), TransportUDP)
+	plain.Release()
+	if !bytes.Equal(plain.TelemetryMsg, payload) {
+		t.Fatalf("unexpected payload %q", plain.TelemetryMsg)
+	}
+	var nilFeed *Feed
+	nilFeed.Release()
+}
+
+// benchmarkPayload is an XR ST framed JSON message of a typical size.
+func benchmarkPayload() []byte {
+	payload := append([]byte(`{"encoding_path":"rib","data":"`), bytes.Repeat([]byte("x"), 4000)...)
+	payload = append(payload, `"}`...)
+	msg := make([]byte, XRSTHeaderLength, XRSTHeaderLength+len(payload))
+	msg[3] = byte(XRSTEncodingJSON)
+	msg[8], msg[9], msg[10], msg[11] = byte(len(payload)>>24), byte(len(payload)>>16), byte(len(payload)>>8), byte(len(payload))
+	return append(msg, payload...)
+}
+
+func BenchmarkMakeFeederMsgCopy(b *testing.B) {
+	msg := benchmarkPayload()
+	b.ReportAllocs()
+	b.SetBytes(int64(len(msg)))
+	for i := 0; i < b.N; i++ {
+		f, err := MakeFeederMsgFromJson(msg, len(msg), TransportUDP)
+		if err != nil {
+			b.Fatal(err)
+		}
+		f.Release()
+	}
+}
+
+func BenchmarkMakeFeederMsgPooled(b *testing.B) {
+	msg := benchmarkPayload()
+	pool := NewBufferPool()
+	b.ReportAllocs()
+	b.SetBytes(int64(len(msg)))
+	for i := 0; i < b.N; i++ {
+		f, err := MakeFeederMsgWithPool(msg, len(msg), TransportUDP, pool)
+		if err != nil {
+			b.Fatal(err)
+		}
+		f.Release()
+	}
+}
@@ -0,0 +1,232 @@
+package telemetry_feeder
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultProducerIdleTimeout = time.Minute * 15
+	DefaultMaxProducers        = 10000
+)
+
+// ProducerStats are the counters of a single producer reported in StatsSnapshot.Producers.
+type ProducerStats struct {
+	MessagesReceivedTotal       int64     `json:"messages_received_total"`
+	PayloadBytesReceivedTotal   int64     `json:"payload_bytes_received_total"`
+	TransportBytesReceivedTotal int64     `json:"transport_bytes_received_total"`
+	ReceiveErrorsTotal          int64     `json:"receive_errors_total"`
+	ActiveSessions              int64     `json:"active_sessions"`
+	FirstSeen                   time.Time `json:"first_seen"`
+	LastSeen                    time.Time `json:"last_seen"`
+}
+
+// ProducerStatsConfig controls the eviction of producers from the per-producer stats.
+// A producer without active sessions is evicted once it has not been seen for IdleTimeout,
+// when MaxProducers is reached the least recently seen producer without active sessions is
+// evicted to make room for a new one. Zero values select the defaults, a negative
+// IdleTimeout disables idle eviction and a negative MaxProducers disables the limit.
+// KeyByHost accounts all sessions of a host to a single producer, whatever their source port.
+type ProducerStatsConfig struct {
+	IdleTimeout  time.Duration
+	MaxProducers int
+	KeyByHost    bool
+}
+
+type producerEntry struct {
+	messages       atomic.Int64
+	payloadBytes   atomic.Int64
+	transportBytes atomic.Int64
+	errors         atomic.Int64
+	sessions       atomic.Int64
+	firstSeen      time.Time
+	lastSeen       atomic.Int64
+}
+
+func (e *producerEntry) touch(now time.Time) {
+	e.lastSeen.Store(now.UnixNano())
+}
+
+func (e *producerEntry) idle(now time.Time, timeout time.Duration) bool {
+	return e.sessions.Load() <= 0 && now.Sub(time.Unix(0, e.lastSeen.Load())) >= timeout
+}
+
+// ProducerTracker keeps per-producer counters. Producers are keyed by Feed.ProducerAddr, or by
+// its host part with ProducerStatsConfig.KeyByHost.
+type ProducerTracker struct {
+	mu           sync.RWMutex
+	producers    map[string]*producerEntry
+	idleTimeout  time.Duration
+	maxProducers int
+	keyByHost    bool
+	evictedTotal atomic.Int64
+	lastSweep    time.Time
+	now          func() time.Time
+}
+
+func NewProducerTracker(cfg ProducerStatsConfig) *ProducerTracker {
+	if cfg.IdleTimeout == 0 {
+		cfg.IdleTimeout = DefaultProducerIdleTimeout
+	}
+	if cfg.MaxProducers == 0 {
+		cfg.MaxProducers = DefaultMaxProducers
+	}
+	return &ProducerTracker{
+		producers:    make(map[string]*producerEntry),
+		idleTimeout:  cfg.IdleTimeout,
+		maxProducers: cfg.MaxProducers,
+		keyByHost:    cfg.KeyByHost,
+		now:          time.Now,
+	}
+}
+
+// ProducerKey returns the key the producer is tracked under, the host part of the address
+// when byHost is set.
+func ProducerKey(addr net.Addr, byHost bool) string {
+	if addr == nil {
+		return ""
+	}
+	s := addr.String()
+	if !byHost {
+		return s
+	}
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		return host
+	}
+	return s
+}
+
+// update applies fn to the entry of the producer, creating it when needed. The lookup and fn
+// run under the same lock, so the entry cannot be evicted in between and a session started on
+// an entry is seen by the next sweep.
+func (t *ProducerTracker) update(addr net.Addr, fn func(e *producerEntry)) {
+	key := ProducerKey(addr, t.keyByHost)
+	if key == "" {
+		return
+	}
+	t.mu.RLock()
+	e, ok := t.producers[key]
+	if ok {
+		// Counters are atomic, evictions need the write lock.
+		fn(e)
+	}
+	t.mu.RUnlock()
+	if ok {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if e, ok := t.producers[key]; ok {
+		fn(e)
+		return
+	}
+	now := t.now()
+	t.sweep(now)
+	if t.maxProducers > 0 && len(t.producers) >= t.maxProducers {
+		t.evictLeastRecentlySeen()
+	}
+	e = &producerEntry{firstSeen: now}
+	e.touch(now)
+	t.producers[key] = e
+	fn(e)
+}
+
+// sweep evicts idle producers, at most once per second since it walks the whole map.
+// Must be called with the lock held.
+func (t *ProducerTracker) sweep(now time.Time) {
+	if t.idleTimeout < 0 || now.Sub(t.lastSweep) < time.Second {
+		return
+	}
+	t.lastSweep = now
+	for key, e := range t.producers {
+		if e.idle(now, t.idleTimeout) {
+			delete(t.producers, key)
+			t.evictedTotal.Add(1)
+		}
+	}
+}
+
+// evictLeastRecentlySeen must be called with the lock held.
+func (t *ProducerTracker) evictLeastRecentlySeen() {
+	var oldestKey string
+	var oldest int64
+	for key, e := range t.producers {
+		if e.sessions.Load() > 0 {
+			continue
+		}
+		if seen := e.lastSeen.Load(); oldestKey == "" || seen < oldest {
+			oldestKey, oldest = key, seen
+		}
+	}
+	if oldestKey != "" {
+		delete(t.producers, oldestKey)
+		t.evictedTotal.Add(1)
+	}
+}
+
+// Received accounts a message received from the producer.
+func (t *ProducerTracker) Received(addr net.Addr, payloadBytes, transportBytes int) {
+	t.update(addr, func(e *producerEntry) {
+		e.messages.Add(1)
+		e.payloadBytes.Add(int64(payloadBytes))
+		e.transportBytes.Add(int64(transportBytes))
+		e.touch(t.now())
+	})
+}
+
+// Error accounts a receive or decode error of the producer.
+func (t *ProducerTracker) Error(addr net.Addr) {
+	t.update(addr, func(e *producerEntry) {
+		e.errors.Add(1)
+		e.touch(t.now())
+	})
+}
+
+// SessionStarted accounts a new connection or gRPC session of the producer, a producer with
+// active sessions is never evicted.
+func (t *ProducerTracker) SessionStarted(addr net.Addr) {
+	t.update(addr, func(e *producerEntry) {
+		e.sessions.Add(1)
+		e.touch(t.now())
+	})
+}
+
+func (t *ProducerTracker) SessionEnded(addr net.Addr) {
+	t.update(addr, func(e *producerEntry) {
+		if e.sessions.Add(-1) < 0 {
+			// SessionEnded without a matching SessionStarted.
+			e.sessions.Store(0)
+		}
+		e.touch(t.now())
+	})
+}
+
+// Snapshot returns the counters of all tracked producers after evicting idle ones.
+func (t *ProducerTracker) Snapshot() map[string]ProducerStats {
+	t.mu.Lock()
+	t.sweep(t.now())
+	t.mu.Unlock()
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	producers := make(map[string]ProducerStats, len(t.producers))
+	for key, e := range t.producers {
+		producers[key] = ProducerStats{
+			MessagesReceivedTotal:       e.messages.Load(),
+			PayloadBytesReceivedTotal:   e.payloadBytes.Load(),
+			TransportBytesReceivedTotal: e.transportBytes.Load(),
+			ReceiveErrorsTotal:          e.errors.Load(),
+			ActiveSessions:              e.sessions.Load(),
+			FirstSeen:                   e.firstSeen.UTC(),
+			LastSeen:                    time.Unix(0, e.lastSeen.Load()).UTC(),
+		}
+	}
+	return producers
+}
+
+// FillStats sets the per-producer fields of the snapshot.
+func (t *ProducerTracker) FillStats(s *StatsSnapshot) {
+	s.Producers = t.Snapshot()
+	s.ProducersEvictedTotal = t.evictedTotal.Load()
+}
@@ -0,0 +1,140 @@
+package telemetry_feeder
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestTracker(cfg ProducerStatsConfig) (*ProducerTracker, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	t := NewProducerTracker(cfg)
+	t.now = clock.Now
+	return t, clock
+}
+
+func TestProducerTrackerCounters(t *testing.T) {
+	tracker, _ := newTestTracker(ProducerStatsConfig{KeyByHost: true})
+	r1a := &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1000}
+	r1b := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 2000}
+	r2 := &net.UDPAddr{IP: net.ParseIP("2001:db8::2"), Port: 1000}
+
+	tracker.Received(r1a, 10, 22)
+	tracker.SessionStarted(r1b)
+	tracker.Received(r1b, 5, 17)
+	tracker.Error(r2)
+	tracker.Received(nil, 1, 1)
+
+	var s StatsSnapshot
+	tracker.FillStats(&s)
+	if len(s.Producers) != 2 {
+		t.Fatalf("expected 2 producers, got %v", s.Producers)
+	}
+	p1 := s.Producers["192.0.2.1"]
+	if p1.MessagesReceivedTotal != 2 || p1.PayloadBytesReceivedTotal != 15 || p1.TransportBytesReceivedTotal != 39 || p1.ActiveSessions != 1 {
+		t.Fatalf("unexpected counters of 192.0.2.1: %+v", p1)
+	}
+	if p2 := s.Producers["2001:db8::2"]; p2.ReceiveErrorsTotal != 1 || p2.MessagesReceivedTotal != 0 {
+		t.Fatalf("unexpected counters of 2001:db8::2: %+v", p2)
+	}
+
+	// By default every source port is a producer of its own.
+	tracker, _ = newTestTracker(ProducerStatsConfig{})
+	tracker.Received(r1a, 10, 22)
+	tracker.Received(r1b, 5, 17)
+	tracker.Error(r2)
+	tracker.FillStats(&s)
+	if len(s.Producers) != 3 || s.Producers["192.0.2.1:1000"].MessagesReceivedTotal != 1 ||
+		s.Producers["192.0.2.1:2000"].PayloadBytesReceivedTotal != 5 || s.Producers["[2001:db8::2]:1000"].ReceiveErrorsTotal != 1 {
+		t.Fatalf("unexpected producers %+v", s.Producers)
+	}
+}
+
+func TestProducerTrackerIdleEviction(t *testing.T) {
+	tracker, clock := newTestTracker(ProducerStatsConfig{IdleTimeout: time.Minute})
+	idle := &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1000}
+	active := &net.TCPAddr{IP: net.ParseIP("192.0.2.2"), Port: 1000}
+	tracker.Received(idle, 1, 1)
+	tracker.SessionStarted(active)
+
+	clock.now = clock.now.Add(2 * time.Minute)
+	var s StatsSnapshot
+	tracker.FillStats(&s)
+	if _, ok := s.Producers["192.0.2.1:1000"]; ok || s.ProducersEvictedTotal != 1 {
+		t.Fatalf("expected idle producer to be evicted, got %v evicted %d", s.Producers, s.ProducersEvictedTotal)
+	}
+	if _, ok := s.Producers["192.0.2.2:1000"]; !ok {
+		t.Fatal("producer with an active session must not be evicted")
+	}
+
+	tracker.SessionEnded(active)
+	clock.now = clock.now.Add(2 * time.Minute)
+	tracker.FillStats(&s)
+	if len(s.Producers) != 0 || s.ProducersEvictedTotal != 2 {
+		t.Fatalf("expected all producers to be evicted, got %v evicted %d", s.Producers, s.ProducersEvictedTotal)
+	}
+}
+
+func TestProducerTrackerMaxProducers(t *testing.T) {
+	tracker, clock := newTestTracker(ProducerStatsConfig{MaxProducers: 2, IdleTimeout: -1})
+	for i := 1; i <= 3; i++ {
+		tracker.Received(&net.UDPAddr{IP: net.IPv4(192, 0, 2, byte(i)), Port: 1000}, 1, 1)
+		clock.now = clock.now.Add(time.Second)
+	}
+	var s StatsSnapshot
+	tracker.FillStats(&s)
+	if len(s.Producers) != 2 || s.ProducersEvictedTotal != 1 {
+		t.Fatalf("expected 2 producers and 1 eviction, got %v evicted %d", s.Producers, s.ProducersEvictedTotal)
+	}
+	if _, ok := s.Producers["192.0.2.1:1000"]; ok {
+		t.Fatal("expected least recently seen producer to be evicted")
+	}
+}
+
+func TestProducerTrackerConcurrentEviction(t *testing.T) {
+	tracker := NewProducerTracker(ProducerStatsConfig{MaxProducers: 8, IdleTimeout: -1})
+	var wg sync.WaitGroup
+	sessions := make([]net.Addr, 4)
+	for i := range sessions {
+		sessions[i] = &net.TCPAddr{IP: net.IPv4(192, 0, 2, byte(i+1)), Port: 57400}
+	}
+	// Producers without sessions churn the limit while the sessions start.
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				tracker.Received(&net.UDPAddr{IP: net.IPv4(198, 51, 100, byte(g)), Port: i}, 1, 1)
+			}
+		}()
+	}
+	for _, addr := range sessions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tracker.SessionStarted(addr)
+			for i := 0; i < 100; i++ {
+				tracker.Received(addr, 1, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	producers := tracker.Snapshot()
+	for _, addr := range sessions {
+		if p := producers[addr.String()]; p.ActiveSessions != 1 || p.MessagesReceivedTotal != 100 {
+			t.Fatalf("unexpected counters of %s with an active session: %+v", addr, p)
+		}
+	}
+	if len(producers) > 8 {
+		t.Fatalf("expected at most 8 producers, got %d", len(producers))
+	}
+}
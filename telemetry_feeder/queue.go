@@ -0,0 +1,294 @@
+package telemetry_feeder
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OverflowPolicy defines what a feeder does with a new item when its feed queue is full.
+type OverflowPolicy string
+
+const (
+	// OverflowBlock waits for the consumer to make room, nothing is lost but the receiver stalls.
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDropNewest discards the item which does not fit.
+	OverflowDropNewest OverflowPolicy = "drop-newest"
+	// OverflowDropOldest discards the oldest queued items to make room for the new one.
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+	// OverflowSpillToDisk appends items to a spill file, they are delivered in order once the
+	// consumer catches up.
+	OverflowSpillToDisk OverflowPolicy = "spill-to-disk"
+)
+
+func (p OverflowPolicy) Validate() error {
+	switch p {
+	case OverflowBlock, OverflowDropNewest, OverflowDropOldest, OverflowSpillToDisk:
+		return nil
+	}
+	return fmt.Errorf("unknown overflow policy %q", p)
+}
+
+// OverflowConfig selects the overflow policy of a feeder, SpillDir is only used by
+// OverflowSpillToDisk, the system temporary directory is used when it is empty.
+type OverflowConfig struct {
+	Policy   OverflowPolicy
+	SpillDir string
+}
+
+// FeedQueue is the feed channel of a feeder together with its overflow policy and counters.
+type FeedQueue struct {
+	feed                        chan *Feed
+	stopCh                      <-chan struct{}
+	policy                      OverflowPolicy
+	spill                       *Spill
+	spillDone                   chan struct{}
+	closeOnce                   sync.Once
+	events                      *EventQueue
+	overflowing                 atomic.Bool
+	feedItemsEnqueuedTotal      atomic.Int64
+	feedErrorItemsEnqueuedTotal atomic.Int64
+	feedQueueDepthMax           atomic.Int64
+	feedPublishBlockNanosTotal  atomic.Int64
+	feedPublishBlockNanosMax    atomic.Int64
+	feedDroppedNewestTotal      atomic.Int64
+	feedDroppedOldestTotal      atomic.Int64
+	feedSpilledTotal            atomic.Int64
+	feedSpillErrorsTotal        atomic.Int64
+}
+
+// NewFeedQueue creates a queue of the given capacity, publishing stops once stopCh is closed.
+// An empty policy defaults to OverflowBlock.
+func NewFeedQueue(capacity int, stopCh <-chan struct{}, cfg OverflowConfig) (*FeedQueue, error) {
+	if cfg.Policy == "" {
+		cfg.Policy = OverflowBlock
+	}
+	if err := cfg.Policy.Validate(); err != nil {
+		return nil, err
+	}
+	q := &FeedQueue{
+		feed:   make(chan *Feed, capacity),
+		stopCh: stopCh,
+		policy: cfg.Policy,
+	}
+	if cfg.Policy == OverflowSpillToDisk {
+		spill, err := NewSpill(cfg.SpillDir)
+		if err != nil {
+			return nil, err
+		}
+		q.spill = spill
+		q.spillDone = make(chan struct{})
+		go func() {
+			defer close(q.spillDone)
+			spill.Drain(q.feed, stopCh, func(item *Feed) {
+				q.enqueued(item)
+			}, func(lost int64, _ error) {
+				q.feedSpillErrorsTotal.Add(lost)
+			})
+		}()
+	}
+
+	return q, nil
+}
+
+func (q *FeedQueue) Feed() chan *Feed {
+	return q.feed
+}
+
+func (q *FeedQueue) Policy() OverflowPolicy {
+	return q.policy
+}
+
+// SetEvents makes the queue emit EventQueueOverflow into events, it must be called before the
+// first Publish.
+func (q *FeedQueue) SetEvents(events *EventQueue) {
+	q.events = events
+}
+
+// overflowed emits EventQueueOverflow unless the queue is already overflowing.
+func (q *FeedQueue) overflowed(item *Feed) {
+	if q.events == nil || q.overflowing.Swap(true) {
+		return
+	}
+	q.events.Emit(&Event{
+		Type:         EventQueueOverflow,
+		ProducerAddr: item.ProducerAddr,
+		Err:          fmt.Errorf("%w, %s policy applied", ErrQueueOverflow, q.policy),
+	})
+}
+
+func updateMax(max *atomic.Int64, value int64) {
+	for {
+		current := max.Load()
+		if value <= current || max.CompareAndSwap(current, value) {
+			return
+		}
+	}
+}
+
+func (q *FeedQueue) enqueued(item *Feed) {
+	q.feedItemsEnqueuedTotal.Add(1)
+	if item.Err != nil {
+		q.feedErrorItemsEnqueuedTotal.Add(1)
+	}
+	updateMax(&q.feedQueueDepthMax, int64(len(q.feed)))
+}
+
+// PublishOutcome is what became of an item given to PublishWithOutcome.
+type PublishOutcome int
+
+const (
+	// PublishStopped means the queue is stopped and the item was not taken.
+	PublishStopped PublishOutcome = iota
+	PublishEnqueued
+	PublishSpilled
+	// PublishDropped means the item was discarded by OverflowDropNewest or failed to spill.
+	PublishDropped
+)
+
+// Publish places the item into the queue applying the overflow policy when the queue is full,
+// false is returned only when the queue is stopped.
+func (q *FeedQueue) Publish(item *Feed) bool {
+	return q.PublishWithOutcome(item) != PublishStopped
+}
+
+// PublishWithOutcome is Publish reporting what became of the item, for feeders letting the
+// producer know about lost items. Items dropped by OverflowDropOldest to make room are not
+// reported, the new item is enqueued.
+func (q *FeedQueue) PublishWithOutcome(item *Feed) PublishOutcome {
+	// If stopCh is already closed, prevent publishing (even if the send would not block).
+	select {
+	case <-q.stopCh:
+		return PublishStopped
+	default:
+	}
+	if q.policy == OverflowBlock {
+		if !q.publishBlocking(item) {
+			return PublishStopped
+		}
+		return PublishEnqueued
+	}
+	// Once spilling started, new items go to the spill as well to preserve the order.
+	if q.spill != nil && q.spill.Pending() > 0 {
+		return q.spillItem(item)
+	}
+	for {
+		select {
+		case q.feed <- item:
+			q.enqueued(item)
+			if q.overflowing.Load() {
+				q.overflowing.Store(false)
+			}
+			return PublishEnqueued
+		default:
+		}
+		q.overflowed(item)
+		switch q.policy {
+		case OverflowDropNewest:
+			q.feedDroppedNewestTotal.Add(1)
+			item.Release()
+			return PublishDropped
+		case OverflowSpillToDisk:
+			return q.spillItem(item)
+		}
+		// OverflowDropOldest, make room and try again, the consumer may have made room meanwhile.
+		select {
+		case dropped := <-q.feed:
+			q.feedDroppedOldestTotal.Add(1)
+			dropped.Release()
+		default:
+		}
+	}
+}
+
+func (q *FeedQueue) spillItem(item *Feed) PublishOutcome {
+	// The spill keeps its own copy of the payload.
+	defer item.Release()
+	if err := q.spill.Push(item); err != nil {
+		q.feedSpillErrorsTotal.Add(1)
+		return PublishDropped
+	}
+	q.feedSpilledTotal.Add(1)
+	return PublishSpilled
+}
+
+func (q *FeedQueue) publishBlocking(item *Feed) bool {
+	queueDepthAfterSend := int64(len(q.feed) + 1)
+	if queueDepthAfterSend > int64(cap(q.feed)) {
+		queueDepthAfterSend = int64(cap(q.feed))
+	}
+	started := time.Now()
+	select {
+	case <-q.stopCh:
+		return false
+	case q.feed <- item:
+		blocked := time.Since(started).Nanoseconds()
+		q.feedItemsEnqueuedTotal.Add(1)
+		if item.Err != nil {
+			q.feedErrorItemsEnqueuedTotal.Add(1)
+		}
+		q.feedPublishBlockNanosTotal.Add(blocked)
+		updateMax(&q.feedPublishBlockNanosMax, blocked)
+		updateMax(&q.feedQueueDepthMax, queueDepthAfterSend)
+		return true
+	}
+}
+
+// drainPollInterval is how often Drain checks whether the consumer emptied the queue.
+const drainPollInterval = 10 * time.Millisecond
+
+// Drain waits until the consumer has taken every queued item, spilled items included, or ctx
+// is done in which case its error is returned. Publishers should be stopped first, otherwise
+// the queue may never become empty.
+func (q *FeedQueue) Drain(ctx context.Context) error {
+	return waitUntil(ctx, func() bool {
+		return len(q.feed) == 0 && (q.spill == nil || q.spill.Pending() == 0)
+	})
+}
+
+func waitUntil(ctx context.Context, drained func() bool) error {
+	t := time.NewTicker(drainPollInterval)
+	defer t.Stop()
+	for !drained() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+
+	return nil
+}
+
+// Close closes the feed channel so the consumer sees the end of the stream, items still queued
+// can be read before it. stopCh must be closed and every publisher must have returned before
+// Close is called, a Publish after it panics. Close is idempotent.
+func (q *FeedQueue) Close() {
+	q.closeOnce.Do(func() {
+		if q.spillDone != nil {
+			<-q.spillDone
+		}
+		close(q.feed)
+	})
+}
+
+// FillStats sets the queue related fields of the snapshot.
+func (q *FeedQueue) FillStats(s *StatsSnapshot) {
+	s.FeedItemsEnqueuedTotal = q.feedItemsEnqueuedTotal.Load()
+	s.FeedErrorItemsEnqueuedTotal = q.feedErrorItemsEnqueuedTotal.Load()
+	s.FeedQueueDepth = int64(len(q.feed))
+	s.FeedQueueDepthMax = q.feedQueueDepthMax.Load()
+	s.FeedQueueCapacity = int64(cap(q.feed))
+	s.FeedPublishBlockNanosTotal = q.feedPublishBlockNanosTotal.Load()
+	s.FeedPublishBlockNanosMax = q.feedPublishBlockNanosMax.Load()
+	s.OverflowPolicy = string(q.policy)
+	s.FeedDroppedNewestTotal = q.feedDroppedNewestTotal.Load()
+	s.FeedDroppedOldestTotal = q.feedDroppedOldestTotal.Load()
+	s.FeedSpilledTotal = q.feedSpilledTotal.Load()
+	s.FeedSpillErrorsTotal = q.feedSpillErrorsTotal.Load()
+	if q.spill != nil {
+		s.FeedSpillDepth = q.spill.Pending()
+	}
+}
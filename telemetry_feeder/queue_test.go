@@ -0,0 +1,234 @@
+package telemetry_feeder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestFeedQueueDropNewest(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	q, err := NewFeedQueue(2, stopCh, OverflowConfig{Policy: OverflowDropNewest})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		if !q.Publish(&Feed{TelemetryMsg: []byte{byte(i)}}) {
+			t.Fatalf("publish %d failed", i)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if got := (<-q.Feed()).TelemetryMsg[0]; got != byte(i) {
+			t.Fatalf("expected item %d, got %d", i, got)
+		}
+	}
+	var s StatsSnapshot
+	q.FillStats(&s)
+	if s.OverflowPolicy != string(OverflowDropNewest) || s.FeedDroppedNewestTotal != 2 || s.FeedItemsEnqueuedTotal != 2 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+}
+
+func TestFeedQueuePublishOutcome(t *testing.T) {
+	stopCh := make(chan struct{})
+	q, err := NewFeedQueue(1, stopCh, OverflowConfig{Policy: OverflowDropNewest})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := q.PublishWithOutcome(&Feed{}); got != PublishEnqueued {
+		t.Fatalf("expected the item to be enqueued, got %d", got)
+	}
+	if got := q.PublishWithOutcome(&Feed{}); got != PublishDropped {
+		t.Fatalf("expected the item to be dropped, got %d", got)
+	}
+	close(stopCh)
+	if got := q.PublishWithOutcome(&Feed{}); got != PublishStopped {
+		t.Fatalf("expected the queue to be stopped, got %d", got)
+	}
+}
+
+func TestFeedQueueOverflowEvent(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	q, err := NewFeedQueue(1, stopCh, OverflowConfig{Policy: OverflowDropNewest})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	events := NewEventQueue(TransportUDP)
+	q.SetEvents(events)
+	// Overflows are reported once per episode, the episode ends when an item fits again.
+	for i := 0; i < 3; i++ {
+		q.Publish(&Feed{})
+	}
+	<-q.Feed()
+	for i := 0; i < 3; i++ {
+		q.Publish(&Feed{})
+	}
+	events.Close()
+	n := 0
+	for e := range events.Events() {
+		if e.Type != EventQueueOverflow || !errors.Is(e.Err, ErrQueueOverflow) {
+			t.Fatalf("unexpected event %+v", e)
+		}
+		n++
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 overflow events, got %d", n)
+	}
+}
+
+func TestFeedQueueDropOldest(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	q, err := NewFeedQueue(2, stopCh, OverflowConfig{Policy: OverflowDropOldest})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		q.Publish(&Feed{TelemetryMsg: []byte{byte(i)}})
+	}
+	for i := 2; i < 4; i++ {
+		if got := (<-q.Feed()).TelemetryMsg[0]; got != byte(i) {
+			t.Fatalf("expected item %d, got %d", i, got)
+		}
+	}
+	var s StatsSnapshot
+	q.FillStats(&s)
+	if s.FeedDroppedOldestTotal != 2 || s.FeedItemsEnqueuedTotal != 4 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+}
+
+func TestFeedQueueSpillToDiskPreservesOrder(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	q, err := NewFeedQueue(2, stopCh, OverflowConfig{Policy: OverflowSpillToDisk, SpillDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	const n = 50
+	for i := 0; i < n; i++ {
+		q.Publish(&Feed{TelemetryMsg: []byte{byte(i)}, Transport: TransportUDP})
+	}
+	var s StatsSnapshot
+	q.FillStats(&s)
+	if s.FeedSpilledTotal == 0 {
+		t.Fatalf("expected items to be spilled, stats %+v", s)
+	}
+	for i := 0; i < n; i++ {
+		select {
+		case f := <-q.Feed():
+			if f.TelemetryMsg[0] != byte(i) || f.Transport != TransportUDP {
+				t.Fatalf("item %d out of order or corrupted: %+v", i, f)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for item %d", i)
+		}
+	}
+	// The drainer acknowledges the last item right after handing it over, give it a moment.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		q.FillStats(&s)
+		if s.FeedItemsEnqueuedTotal == n && s.FeedSpillDepth == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected stats after drain %+v", s)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestFeedQueueStopped(t *testing.T) {
+	stopCh := make(chan struct{})
+	q, err := NewFeedQueue(1, stopCh, OverflowConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q.Policy() != OverflowBlock {
+		t.Fatalf("expected default policy %q, got %q", OverflowBlock, q.Policy())
+	}
+	close(stopCh)
+	if q.Publish(&Feed{}) {
+		t.Fatal("expected publish to fail on stopped queue")
+	}
+	if _, err := NewFeedQueue(1, stopCh, OverflowConfig{Policy: "drop-all"}); err == nil {
+		t.Fatal("expected error for unknown policy")
+	}
+}
+
+func TestFeedQueueDrainAndClose(t *testing.T) {
+	stopCh := make(chan struct{})
+	q, err := NewFeedQueue(1, stopCh, OverflowConfig{Policy: OverflowSpillToDisk, SpillDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		q.Publish(&Feed{TelemetryMsg: []byte{byte(i)}})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := q.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the drain to time out without a consumer, got %v", err)
+	}
+	got := make(chan []byte, 3)
+	go func() {
+		for i := 0; i < 3; i++ {
+			got <- (<-q.Feed()).TelemetryMsg
+		}
+	}()
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := q.Drain(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if b := <-got; b[0] != byte(i) {
+			t.Fatalf("expected item %d, got %d", i, b[0])
+		}
+	}
+	close(stopCh)
+	q.Close()
+	q.Close()
+	if _, ok := <-q.Feed(); ok {
+		t.Fatal("expected the feed channel to be closed")
+	}
+}
+
+func TestSpillRecordRoundTrip(t *testing.T) {
+	addr := &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 5432}
+	in := &Feed{
+		ProducerAddr: addr,
+		TelemetryMsg: []byte(`{"encoding_path":"rib"}`),
+		Err:          fmt.Errorf("boom"),
+		Transport:    TransportUDP,
+		Encoding:     EncodingJSON,
+		Framing:      FramingCiscoXRST,
+		Source:       "udp",
+		PeerSubject:  "CN=router1",
+		ReceivedAt:   time.Unix(1700000000, 123),
+	}
+	b := encodeSpillRecord(in)
+	out, err := decodeSpillRecord(b[4:])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.ProducerAddr.Network() != "udp" || out.ProducerAddr.String() != addr.String() {
+		t.Fatalf("producer address mismatch: %v", out.ProducerAddr)
+	}
+	if out.Err == nil || out.Err.Error() != "boom" {
+		t.Fatalf("error mismatch: %v", out.Err)
+	}
+	if string(out.TelemetryMsg) != string(in.TelemetryMsg) || out.Transport != in.Transport ||
+		out.Encoding != in.Encoding || out.Framing != in.Framing || out.Source != in.Source || out.PeerSubject != in.PeerSubject ||
+		!out.ReceivedAt.Equal(in.ReceivedAt) {
+		t.Fatalf("feed mismatch: %+v", out)
+	}
+	if _, err := decodeSpillRecord(b[4:10]); err == nil {
+		t.Fatal("expected error for truncated record")
+	}
+}
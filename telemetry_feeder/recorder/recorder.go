@@ -0,0 +1,195 @@
+package recorder
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/glog"
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+	"github.com/sbezverk/tools/telemetry_feeder/capture"
+)
+
+const (
+	feedQueueCapacity = 1024
+	flushInterval     = time.Second
+)
+
+// Stats are the recorder counters.
+type Stats struct {
+	RecordsWrittenTotal int64    `json:"records_written_total"`
+	PayloadBytesTotal   int64    `json:"payload_bytes_total"`
+	ItemsSkippedTotal   int64    `json:"items_skipped_total"`
+	WriteErrorsTotal    int64    `json:"write_errors_total"`
+	Files               []string `json:"files"`
+}
+
+// Recorder is a feeder.Feeder passing through every item of the source feeder while writing
+// them into capture files replayable by offline_feeder. Items without payload are passed
+// through but not recorded, except error items when recording in capture.FormatV1. Write
+// failures are counted and logged, they never hold back the live feed. Items are written
+// before they are passed on, so their consumer owns them and releases pooled feeds as usual.
+type Recorder struct {
+	src            feeder.Feeder
+	w              *Writer
+	format         capture.Format
+	feed           chan *feeder.Feed
+	stopCh         chan struct{}
+	once           sync.Once
+	done           chan struct{}
+	stopped        chan struct{}
+	recordsWritten atomic.Int64
+	bytesWritten   atomic.Int64
+	itemsSkipped   atomic.Int64
+	writeErrors    atomic.Int64
+}
+
+var _ feeder.Feeder = &Recorder{}
+
+// New starts recording the feed of src as defined by cfg.
+func New(src feeder.Feeder, cfg Config) (*Recorder, error) {
+	w, err := NewWriter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	r := &Recorder{
+		src:     src,
+		w:       w,
+		format:  w.cfg.Format,
+		feed:    make(chan *feeder.Feed, feedQueueCapacity),
+		stopCh:  make(chan struct{}),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go r.worker()
+	go func() {
+		select {
+		case <-r.done:
+		case <-feeder.StoppedOf(src):
+		}
+		close(r.stopped)
+	}()
+
+	return r, nil
+}
+
+func (r *Recorder) GetFeed() chan *feeder.Feed {
+	return r.feed
+}
+
+// Events returns the events of the source feeder.
+func (r *Recorder) Events() <-chan *feeder.Event {
+	return feeder.EventsOf(r.src)
+}
+
+// GetStatsJson returns the stats of the source feeder.
+func (r *Recorder) GetStatsJson() ([]byte, error) {
+	return r.src.GetStatsJson()
+}
+
+func (r *Recorder) Stats() Stats {
+	return Stats{
+		RecordsWrittenTotal: r.recordsWritten.Load(),
+		PayloadBytesTotal:   r.bytesWritten.Load(),
+		ItemsSkippedTotal:   r.itemsSkipped.Load(),
+		WriteErrorsTotal:    r.writeErrors.Load(),
+		Files:               r.w.Files(),
+	}
+}
+
+// GetRecorderStatsJson returns the recorder counters.
+func (r *Recorder) GetRecorderStatsJson() ([]byte, error) {
+	return json.Marshal(r.Stats())
+}
+
+// Stopped is closed once the recording ended or the source feeder stopped on its own.
+func (r *Recorder) Stopped() <-chan struct{} {
+	return r.stopped
+}
+
+// Stop stops the source feeder and closes the recording once the worker has exited.
+func (r *Recorder) Stop() {
+	r.once.Do(func() {
+		close(r.stopCh)
+		r.src.Stop()
+		<-r.done
+	})
+}
+
+// StopGracefully stops the source gracefully, the items it still delivers are recorded and
+// passed on. The recording is closed once the source feed ends and the feed channel is closed
+// on return.
+func (r *Recorder) StopGracefully(ctx context.Context) error {
+	err := r.src.StopGracefully(ctx)
+	if err == nil {
+		select {
+		case <-r.done:
+			err = feeder.WaitDrained(ctx, r.feed)
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+	r.Stop()
+
+	return err
+}
+
+func (r *Recorder) record(item *feeder.Feed) {
+	// Error items carry nothing to replay in the legacy format.
+	if len(item.TelemetryMsg) == 0 && (item.Err == nil || r.format == capture.FormatLegacy) {
+		r.itemsSkipped.Add(1)
+		return
+	}
+	if item.ReceivedAt.IsZero() {
+		stamped := *item
+		stamped.ReceivedAt = time.Now()
+		item = &stamped
+	}
+	if err := r.w.Write(item); err != nil {
+		if r.writeErrors.Add(1) == 1 {
+			glog.Errorf("failed to record telemetry message with error: %+v", err)
+		}
+		return
+	}
+	r.recordsWritten.Add(1)
+	r.bytesWritten.Add(int64(len(item.TelemetryMsg)))
+}
+
+func (r *Recorder) worker() {
+	defer func() {
+		if err := r.w.Close(); err != nil {
+			glog.Errorf("failed to close recording with error: %+v", err)
+		}
+		close(r.feed)
+		close(r.done)
+	}()
+	// Periodic flush keeps the recording readable while it is in progress.
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	in := r.src.GetFeed()
+	for {
+		select {
+		case <-r.stopCh:
+			return
+		case <-ticker.C:
+			if err := r.w.Flush(); err != nil {
+				r.writeErrors.Add(1)
+			}
+		case item, ok := <-in:
+			if !ok {
+				return
+			}
+			if item == nil {
+				continue
+			}
+			r.record(item)
+			select {
+			case r.feed <- item:
+			case <-r.stopCh:
+				return
+			}
+		}
+	}
+}
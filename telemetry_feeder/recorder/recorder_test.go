@@ -0,0 +1,212 @@
+package recorder
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+	"github.com/sbezverk/tools/telemetry_feeder/capture"
+	"github.com/sbezverk/tools/telemetry_feeder/offline_feeder"
+)
+
+type fakeFeeder struct {
+	feed chan *feeder.Feed
+}
+
+func (f *fakeFeeder) GetFeed() chan *feeder.Feed {
+	return f.feed
+}
+
+func (f *fakeFeeder) GetStatsJson() ([]byte, error) {
+	return []byte(`{"transport":"fake"}`), nil
+}
+
+func (f *fakeFeeder) Stop() {}
+
+func (f *fakeFeeder) StopGracefully(ctx context.Context) error {
+	return nil
+}
+
+// readRecords reads all records of an uncompressed or gzip compressed recording.
+func readRecords(t *testing.T, name string) [][]byte {
+	t.Helper()
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", name, err)
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if filepath.Ext(name) == ".gz" {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("failed to open gzip reader: %v", err)
+		}
+		r = gz
+	}
+	var records [][]byte
+	for {
+		lb := make([]byte, 4)
+		if _, err := io.ReadFull(r, lb); err != nil {
+			if err == io.EOF {
+				return records
+			}
+			t.Fatalf("failed to read record length: %v", err)
+		}
+		b := make([]byte, binary.BigEndian.Uint32(lb))
+		if _, err := io.ReadFull(r, b); err != nil {
+			t.Fatalf("failed to read record: %v", err)
+		}
+		records = append(records, b)
+	}
+}
+
+func TestRecorderPassesThroughAndRecords(t *testing.T) {
+	src := &fakeFeeder{feed: make(chan *feeder.Feed, 10)}
+	path := filepath.Join(t.TempDir(), "capture.bin")
+	r, err := New(src, Config{Path: path})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pool := feeder.NewBufferPool()
+	first := &feeder.Feed{}
+	first.CopyTelemetryMsg([]byte("first"), pool)
+	src.feed <- first
+	src.feed <- &feeder.Feed{Err: errors.New("connection reset")}
+	src.feed <- &feeder.Feed{TelemetryMsg: []byte("second")}
+	close(src.feed)
+
+	n := 0
+	for item := range r.GetFeed() {
+		// The items are recorded by the time they are passed on.
+		item.Release()
+		n++
+	}
+	if n != 3 {
+		t.Fatalf("expected 3 items passed through, got %d", n)
+	}
+	r.Stop()
+
+	records := readRecords(t, path)
+	if len(records) != 2 || string(records[0]) != "first" || string(records[1]) != "second" || pool.Stats().Puts != 1 {
+		t.Fatalf("unexpected records %q", records)
+	}
+	stats := r.Stats()
+	if stats.RecordsWrittenTotal != 2 || stats.ItemsSkippedTotal != 1 || stats.PayloadBytesTotal != 11 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if b, err := r.GetStatsJson(); err != nil || string(b) != `{"transport":"fake"}` {
+		t.Fatalf("expected source stats, got %s %v", b, err)
+	}
+}
+
+func TestWriterRotation(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	w, err := NewWriter(Config{Path: filepath.Join(dir, "capture.bin"), MaxSize: 10, MaxAge: time.Minute})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w.now = func() time.Time { return now }
+	// Each record is 4+6 bytes, the size limit starts a new file before every other record.
+	for _, p := range []string{"record", "record"} {
+		if err := w.Write(&feeder.Feed{TelemetryMsg: []byte(p)}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// The age limit starts a new file even though the current one is small.
+	now = now.Add(2 * time.Minute)
+	w.Write(&feeder.Feed{TelemetryMsg: []byte("a")})
+	now = now.Add(30 * time.Second)
+	w.Write(&feeder.Feed{TelemetryMsg: []byte("b")})
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	files := w.Files()
+	if len(files) != 3 {
+		t.Fatalf("expected 3 files, got %v", files)
+	}
+	if filepath.Base(files[2]) != "capture-20240101T120200.000.bin" {
+		t.Fatalf("unexpected file name %s", files[2])
+	}
+	if got := readRecords(t, files[2]); len(got) != 2 {
+		t.Fatalf("expected 2 records in the last file, got %q", got)
+	}
+	for _, name := range files[:2] {
+		if got := readRecords(t, name); len(got) != 1 {
+			t.Fatalf("expected 1 record in %s, got %q", name, got)
+		}
+	}
+}
+
+func TestCompressedRecordingReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.bin")
+	w, err := NewWriter(Config{Path: path, Compress: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w.Write(&feeder.Feed{TelemetryMsg: []byte("one")})
+	w.Write(&feeder.Feed{TelemetryMsg: []byte("two")})
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	f, err := offline_feeder.New(path + ".gz")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.Stop()
+	for _, want := range []string{"one", "two"} {
+		select {
+		case got := <-f.GetFeed():
+			if string(got.TelemetryMsg) != want {
+				t.Fatalf("expected %q, got %q", want, got.TelemetryMsg)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for replayed record")
+		}
+	}
+}
+
+func TestRecorderVersionedFormat(t *testing.T) {
+	src := &fakeFeeder{feed: make(chan *feeder.Feed, 10)}
+	path := filepath.Join(t.TempDir(), "capture.bin")
+	r, err := New(src, Config{Path: path, Format: capture.FormatV1, Metadata: map[string]string{"collector": "lab"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	src.feed <- &feeder.Feed{TelemetryMsg: []byte("first"), Transport: feeder.TransportUDP, Encoding: feeder.EncodingJSON}
+	src.feed <- &feeder.Feed{Err: errors.New("connection reset"), Transport: feeder.TransportUDP}
+	close(src.feed)
+	for range r.GetFeed() {
+	}
+	r.Stop()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open recording: %v", err)
+	}
+	defer f.Close()
+	cr, err := capture.NewReader(f)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cr.Format() != capture.FormatV1 || cr.Header().Metadata["collector"] != "lab" {
+		t.Fatalf("unexpected format %s header %+v", cr.Format(), cr.Header())
+	}
+	got, err := cr.Next()
+	if err != nil || string(got.TelemetryMsg) != "first" || got.Encoding != feeder.EncodingJSON || got.ReceivedAt.IsZero() {
+		t.Fatalf("unexpected first record %+v %v", got, err)
+	}
+	got, err = cr.Next()
+	if err != nil || got.Err == nil || got.Err.Error() != "connection reset" {
+		t.Fatalf("unexpected error record %+v %v", got, err)
+	}
+}
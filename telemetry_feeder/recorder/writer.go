@@ -0,0 +1,230 @@
+package recorder
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+	"github.com/sbezverk/tools/telemetry_feeder/capture"
+)
+
+const (
+	writeBufSize = 1024 * 64
+	// timestampLayout is used in the names of rotated files, it sorts in creation order.
+	timestampLayout = "20060102T150405.000"
+)
+
+// Config defines where and how records are written. When neither MaxSize nor MaxAge is set,
+// all records go to Path. Otherwise every file is named after Path with the time it was
+// opened inserted before the extension, e.g. capture-20240101T120000.000.bin, and a new
+// file is started once the current one reaches MaxSize bytes or MaxAge. The size of a
+// compressed file is approximate since the compressor buffers data. With Compress the
+// files are gzip compressed and get the .gz suffix, offline_feeder detects them on open.
+//
+// Format selects the file format, capture.FormatLegacy when empty. Files in
+// capture.FormatV1 keep the metadata of every feed and start with a header carrying Metadata.
+type Config struct {
+	Path     string
+	MaxSize  int64
+	MaxAge   time.Duration
+	Compress bool
+	Format   capture.Format
+	Metadata map[string]string
+}
+
+func (c Config) rotates() bool {
+	return c.MaxSize > 0 || c.MaxAge > 0
+}
+
+// countingWriter counts bytes reaching the file, after compression.
+type countingWriter struct {
+	f *os.File
+	n int64
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	n, err := w.f.Write(b)
+	w.n += int64(n)
+	return n, err
+}
+
+// Writer writes feeds into capture files read by offline_feeder.
+type Writer struct {
+	cfg       Config
+	mu        sync.Mutex
+	file      *os.File
+	counter   *countingWriter
+	gz        *gzip.Writer
+	buf       *bufio.Writer
+	enc       *capture.Writer
+	opened    time.Time
+	lastStamp string
+	seq       int
+	written   bool
+	files     []string
+	now       func() time.Time
+}
+
+func NewWriter(cfg Config) (*Writer, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("recording file path cannot be empty")
+	}
+	if cfg.MaxSize < 0 || cfg.MaxAge < 0 {
+		return nil, fmt.Errorf("invalid rotation settings, max size %d max age %s", cfg.MaxSize, cfg.MaxAge)
+	}
+	if cfg.Format == "" {
+		cfg.Format = capture.FormatLegacy
+	}
+	if err := cfg.Format.Validate(); err != nil {
+		return nil, err
+	}
+	w := &Writer{
+		cfg: cfg,
+		now: time.Now,
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (w *Writer) fileName(opened time.Time) string {
+	name := w.cfg.Path
+	if w.cfg.rotates() {
+		ext := filepath.Ext(name)
+		stamp := opened.UTC().Format(timestampLayout)
+		// Several files opened within the same millisecond get a sequence number.
+		if stamp == w.lastStamp {
+			w.seq++
+			stamp = fmt.Sprintf("%s-%d", stamp, w.seq)
+		} else {
+			w.lastStamp, w.seq = stamp, 0
+		}
+		name = strings.TrimSuffix(name, ext) + "-" + stamp + ext
+	}
+	if w.cfg.Compress {
+		name += ".gz"
+	}
+	return name
+}
+
+func (w *Writer) open() error {
+	w.opened = w.now()
+	name := w.fileName(w.opened)
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create recording file %s with error: %w", name, err)
+	}
+	w.file = f
+	w.counter = &countingWriter{f: f}
+	if w.cfg.Compress {
+		w.gz = gzip.NewWriter(w.counter)
+		w.buf = bufio.NewWriterSize(w.gz, writeBufSize)
+	} else {
+		w.gz = nil
+		w.buf = bufio.NewWriterSize(w.counter, writeBufSize)
+	}
+	w.files = append(w.files, name)
+	w.written = false
+	// Every file starts with its own header, so each one can be replayed on its own.
+	w.enc, err = capture.NewWriter(w.buf, w.cfg.Format, capture.Header{Created: w.opened.UTC(), Metadata: w.cfg.Metadata})
+	if err != nil {
+		w.closeFile()
+		return err
+	}
+
+	return nil
+}
+
+func (w *Writer) closeFile() error {
+	if w.file == nil {
+		return nil
+	}
+	err := w.buf.Flush()
+	if w.gz != nil {
+		if gzErr := w.gz.Close(); err == nil {
+			err = gzErr
+		}
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	w.file = nil
+	if err != nil {
+		return fmt.Errorf("failed to close recording file with error: %w", err)
+	}
+	return nil
+}
+
+func (w *Writer) size() int64 {
+	return w.counter.n + int64(w.buf.Buffered())
+}
+
+// Write appends a record, rotating the file beforehand when it has reached its size or age.
+// The legacy format keeps only the payload of the feed.
+func (w *Writer) Write(f *feeder.Feed) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return os.ErrClosed
+	}
+	if w.cfg.rotates() && w.written {
+		if (w.cfg.MaxSize > 0 && w.size() >= w.cfg.MaxSize) || (w.cfg.MaxAge > 0 && w.now().Sub(w.opened) >= w.cfg.MaxAge) {
+			if err := w.rotate(); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := w.enc.Write(f); err != nil {
+		return err
+	}
+	w.written = true
+
+	return nil
+}
+
+func (w *Writer) rotate() error {
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+	return w.open()
+}
+
+// Flush writes buffered records to the file, compressed files are flushed up to a complete
+// gzip block so the records can be read back while recording goes on.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return os.ErrClosed
+	}
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	if w.gz != nil {
+		return w.gz.Flush()
+	}
+	return nil
+}
+
+// Files returns the names of all files written so far, the last one is the current file.
+func (w *Writer) Files() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	files := make([]string, len(w.files))
+	copy(files, w.files)
+	return files
+}
+
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.closeFile()
+}
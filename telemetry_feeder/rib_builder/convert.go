@@ -0,0 +1,152 @@
+package rib_builder
+
+import (
+	"net/netip"
+	"strings"
+
+	ipv6_route "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/ipv6_routes/route"
+	route "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/routes/route"
+	"github.com/sbezverk/tools/telemetry_feeder/schema_registry"
+)
+
+// routeRow is a route row of either ios-xr-rib route schema, route is nil for delete rows.
+type routeRow struct {
+	vrf     string
+	afi     AFI
+	network string
+	route   *Route
+}
+
+// isRouteSchema tells whether the rows of the schema are converted by convertRow.
+func isRouteSchema(s *schema_registry.Schema) bool {
+	switch s.Keys.Descriptor().FullName() {
+	case (&route.RibEdmRoute_KEYS{}).ProtoReflect().Descriptor().FullName(),
+		(&ipv6_route.RibEdmRoute_KEYS{}).ProtoReflect().Descriptor().FullName():
+		return true
+	}
+	return false
+}
+
+// convertRow returns false for rows of schemas other than routes/route and ipv6_routes/route.
+func convertRow(row *schema_registry.Row) (*routeRow, bool) {
+	var rr *routeRow
+	switch keys := row.Keys.(type) {
+	case *route.RibEdmRoute_KEYS:
+		rr = &routeRow{vrf: keys.GetVrfName(), afi: AFIIPv4, network: keys.GetNetwork()}
+		if c, ok := row.Content.(*route.RibEdmRoute); ok && !row.Delete {
+			rr.route = ipv4Route(c)
+			rr.route.Prefix = prefixOf(ipv4Addr(c.GetPrefix().GetIpAddress()), c.GetPrefix().GetPrefixLength(), rr.network)
+		}
+	case *ipv6_route.RibEdmRoute_KEYS:
+		rr = &routeRow{vrf: keys.GetVrfName(), afi: AFIIPv6, network: keys.GetNetwork()}
+		if c, ok := row.Content.(*ipv6_route.RibEdmRoute); ok && !row.Delete {
+			rr.route = ipv6Route(c)
+			rr.route.Prefix = prefixOf(ipv6Addr(c.GetPrefix().GetIpAddress()), c.GetPrefix().GetPrefixLength(), rr.network)
+		}
+	default:
+		return nil, false
+	}
+	if rr.vrf == "" {
+		rr.vrf = DefaultVRF
+	}
+	if rr.network == "" && rr.route != nil {
+		rr.network = rr.route.Prefix.String()
+	}
+
+	return rr, true
+}
+
+// prefixOf returns the prefix of the route content, falling back to the network key when the
+// content carries no prefix address. The key is either a prefix or the bare network address.
+func prefixOf(addr string, length uint32, network string) netip.Prefix {
+	if a, err := netip.ParseAddr(addr); err == nil {
+		if p, err := a.Prefix(int(length)); err == nil {
+			return p
+		}
+	}
+	if strings.Contains(network, "/") {
+		if p, err := netip.ParsePrefix(network); err == nil {
+			return p.Masked()
+		}
+		return netip.Prefix{}
+	}
+	if a, err := netip.ParseAddr(network); err == nil {
+		if p, err := a.Prefix(int(length)); err == nil {
+			return p
+		}
+	}
+	return netip.Prefix{}
+}
+
+func ipv4Route(c *route.RibEdmRoute) *Route {
+	r := &Route{
+		Protocol: c.GetProtocolName(),
+		Instance: c.GetInstance(),
+		Distance: c.GetDistance(),
+		Metric:   c.GetMetric(),
+		Version:  c.GetRouteVersion(),
+		Paths:    make([]Path, 0, len(c.GetRoutePath())),
+	}
+	for _, p := range c.GetRoutePath() {
+		r.Paths = append(r.Paths, Path{
+			NextHop:      parseAddr(ipv4Addr(p.GetAddress())),
+			Interface:    p.GetInterfaceName(),
+			NextHopVRF:   p.GetNextHopVrfName(),
+			Metric:       p.GetMetric(),
+			LoadMetric:   p.GetLoadMetric(),
+			PathID:       p.GetPathId(),
+			BackupPathID: p.GetBackupPathId(),
+			Labels:       p.GetLabelStack(),
+		})
+	}
+
+	return r
+}
+
+func ipv6Route(c *ipv6_route.RibEdmRoute) *Route {
+	r := &Route{
+		Protocol: c.GetProtocolName(),
+		Instance: c.GetInstance(),
+		Distance: c.GetDistance(),
+		Metric:   c.GetMetric(),
+		Version:  c.GetRouteVersion(),
+		Paths:    make([]Path, 0, len(c.GetRoutePath())),
+	}
+	for _, p := range c.GetRoutePath() {
+		r.Paths = append(r.Paths, Path{
+			NextHop:      parseAddr(ipv6Addr(p.GetAddress())),
+			Interface:    p.GetInterfaceName(),
+			NextHopVRF:   p.GetNextHopVrfName(),
+			Metric:       p.GetMetric(),
+			LoadMetric:   p.GetLoadMetric(),
+			PathID:       p.GetPathId(),
+			BackupPathID: p.GetBackupPathId(),
+			Labels:       p.GetLabelStack(),
+		})
+	}
+
+	return r
+}
+
+// The two schemas differ in the encoding of IPv6 addresses only.
+func ipv4Addr(a *route.RibEdmIpAddrT) string {
+	if a.GetIpv4() != "" {
+		return a.GetIpv4()
+	}
+	return a.GetIpv6().GetValue()
+}
+
+func ipv6Addr(a *ipv6_route.RibEdmIpAddrT) string {
+	if a.GetIpv6() != "" {
+		return a.GetIpv6()
+	}
+	return a.GetIpv4()
+}
+
+func parseAddr(s string) netip.Addr {
+	a, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return a
+}
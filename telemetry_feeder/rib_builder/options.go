@@ -0,0 +1,57 @@
+package rib_builder
+
+import (
+	"fmt"
+
+	"github.com/sbezverk/tools/telemetry_feeder/schema_registry"
+)
+
+type options struct {
+	registry       *schema_registry.Registry
+	fullSync       bool
+	losslessEvents bool
+}
+
+// Option customizes the builder created by New.
+type Option func(*options)
+
+func defaultOptions() options {
+	return options{
+		registry: schema_registry.NewIOSXRRIBRegistry(),
+	}
+}
+
+// WithRegistry sets the registry decoding the compact GPB rows, by default a registry of the
+// built-in ios-xr-rib schemas is used.
+func WithRegistry(r *schema_registry.Registry) Option {
+	return func(o *options) {
+		o.registry = r
+	}
+}
+
+// WithFullSync tells every collection is a complete dump of the routes of its encoding path,
+// as sent by sample interval subscriptions. Routes of the node, subscription and encoding path
+// which a completed collection did not carry are deleted. Event driven subscriptions only send
+// the changed routes and must not use it.
+func WithFullSync(fullSync bool) Option {
+	return func(o *options) {
+		o.fullSync = fullSync
+	}
+}
+
+// WithLosslessEvents makes the stage created by NewStage hold back the records while its
+// events channel is full, so a consumer mirroring the tables sees every change. By default
+// the events which do not fit are dropped.
+func WithLosslessEvents() Option {
+	return func(o *options) {
+		o.losslessEvents = true
+	}
+}
+
+func (o *options) validate() error {
+	if o.registry == nil {
+		return fmt.Errorf("schema registry must not be nil")
+	}
+
+	return nil
+}
@@ -0,0 +1,433 @@
+package rib_builder
+
+import (
+	"encoding/json"
+	"net/netip"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sbezverk/tools/telemetry_feeder/decoder"
+)
+
+// DefaultVRF is the VRF of rows which do not name one.
+const DefaultVRF = "default"
+
+// AFI is the address family of a routing table.
+type AFI string
+
+const (
+	AFIIPv4 AFI = "IPv4"
+	AFIIPv6 AFI = "IPv6"
+)
+
+// Kind is the kind of a route change.
+type Kind string
+
+const (
+	KindAdd    Kind = "add"
+	KindUpdate Kind = "update"
+	KindDelete Kind = "delete"
+)
+
+// TableKey identifies the routing table of a VRF and address family of a node.
+type TableKey struct {
+	NodeID string `json:"node_id"`
+	VRF    string `json:"vrf"`
+	AFI    AFI    `json:"afi"`
+}
+
+// Path is a path of a route.
+type Path struct {
+	NextHop      netip.Addr `json:"next_hop"`
+	Interface    string     `json:"interface,omitempty"`
+	NextHopVRF   string     `json:"next_hop_vrf,omitempty"`
+	Metric       uint32     `json:"metric"`
+	LoadMetric   uint32     `json:"load_metric"`
+	PathID       uint32     `json:"path_id"`
+	BackupPathID uint32     `json:"backup_path_id"`
+	Labels       []uint32   `json:"labels,omitempty"`
+}
+
+func (p Path) equal(o Path) bool {
+	return p.NextHop == o.NextHop && p.Interface == o.Interface && p.NextHopVRF == o.NextHopVRF &&
+		p.Metric == o.Metric && p.LoadMetric == o.LoadMetric && p.PathID == o.PathID &&
+		p.BackupPathID == o.BackupPathID && slices.Equal(p.Labels, o.Labels)
+}
+
+// Route is the state of a prefix in a routing table. Routes are replaced rather than modified
+// on updates, the routes returned by the builder and carried by events must not be modified.
+type Route struct {
+	Prefix   netip.Prefix `json:"prefix"`
+	Protocol string       `json:"protocol"`
+	Instance string       `json:"instance,omitempty"`
+	Distance uint32       `json:"distance"`
+	Metric   uint32       `json:"metric"`
+	Version  uint32       `json:"version"`
+	Paths    []Path       `json:"paths"`
+	// Updated is the timestamp of the row which last changed the route.
+	Updated time.Time `json:"updated"`
+}
+
+// equal compares the routing state of the routes, Updated and Version are ignored.
+func (r *Route) equal(o *Route) bool {
+	return r.Prefix == o.Prefix && r.Protocol == o.Protocol && r.Instance == o.Instance &&
+		r.Distance == o.Distance && r.Metric == o.Metric && slices.EqualFunc(r.Paths, o.Paths, Path.equal)
+}
+
+// Event is a change of a routing table.
+type Event struct {
+	Kind  Kind
+	Table TableKey
+	// Route is the new state of the route, nil for KindDelete.
+	Route *Route
+	// Previous is the replaced or deleted route, nil for KindAdd.
+	Previous *Route
+	// Stale is set for routes deleted without a delete row, because a full sync collection did
+	// not carry them or the node was reset.
+	Stale bool
+	Time  time.Time
+}
+
+// Table is the exported state of a routing table, routes are sorted by prefix.
+type Table struct {
+	TableKey
+	Routes []*Route `json:"routes"`
+}
+
+// Snapshot is the exported state of all routing tables, sorted by node, VRF and AFI.
+type Snapshot struct {
+	Time   time.Time `json:"time"`
+	Tables []Table   `json:"tables"`
+}
+
+type Stats struct {
+	RecordsTotal int64 `json:"records_total"`
+	RowsTotal    int64 `json:"rows_total"`
+	// IgnoredTotal counts records with an error or of encoding paths other than the route ones.
+	IgnoredTotal      int64 `json:"ignored_total"`
+	DecodeErrorsTotal int64 `json:"decode_errors_total"`
+	AddsTotal         int64 `json:"adds_total"`
+	UpdatesTotal      int64 `json:"updates_total"`
+	DeletesTotal      int64 `json:"deletes_total"`
+	// UnknownDeletesTotal counts delete rows of routes not in the table.
+	UnknownDeletesTotal int64 `json:"unknown_deletes_total"`
+	StaleDeletesTotal   int64 `json:"stale_deletes_total"`
+	FullSyncsTotal      int64 `json:"full_syncs_total"`
+	// IncompleteSyncsTotal counts full sync collections which were not completed or had rows
+	// failing to decode, their stale routes are kept.
+	IncompleteSyncsTotal int64 `json:"incomplete_syncs_total"`
+	TablesActive         int64 `json:"tables_active"`
+	RoutesActive         int64 `json:"routes_active"`
+	EventsDroppedTotal   int64 `json:"events_dropped_total"`
+}
+
+// entry is a route of a table, keyed by the network key of its rows.
+type entry struct {
+	route *Route
+	// subscription and encodingPath are those of the rows of the route, full syncs only delete
+	// their own routes.
+	subscription string
+	encodingPath string
+}
+
+type syncKey struct {
+	NodeID       string
+	Subscription string
+	EncodingPath string
+}
+
+// collection is a full sync in progress, seen holds the routes carried so far.
+type collection struct {
+	id     uint64
+	failed bool
+	seen   map[TableKey]map[string]struct{}
+}
+
+// Builder maintains the routing tables of nodes from the decoded rows of the ios-xr-rib
+// routes/route and ipv6_routes/route encoding paths. Rows add a route or update it when its
+// state changed, delete rows remove it. Builder is safe for concurrent use.
+type Builder struct {
+	opts          options
+	mu            sync.Mutex
+	tables        map[TableKey]map[string]*entry
+	syncs         map[syncKey]*collection
+	stats         Stats
+	eventsDropped atomic.Int64
+	now           func() time.Time
+}
+
+// New creates a builder, records are passed to Apply. NewStage runs it as a pipeline stage.
+func New(opts ...Option) (*Builder, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+
+	return &Builder{
+		opts:   o,
+		tables: make(map[TableKey]map[string]*entry),
+		syncs:  make(map[syncKey]*collection),
+		now:    time.Now,
+	}, nil
+}
+
+// Apply applies the rows of the record to the routing tables of its node and returns the
+// resulting changes.
+func (b *Builder) Apply(r *decoder.Record) []*Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stats.RecordsTotal++
+	if r == nil || r.Err != nil || r.Format != decoder.FormatGPB {
+		b.stats.IgnoredTotal++
+		return nil
+	}
+	if s, ok := b.opts.registry.Lookup(r.EncodingPath); !ok || !isRouteSchema(s) {
+		b.stats.IgnoredTotal++
+		return nil
+	}
+	now := b.now()
+	var c *collection
+	if b.opts.fullSync {
+		c = b.collection(r)
+	}
+	rows, err := b.opts.registry.DecodeRecord(r)
+	if err != nil {
+		b.stats.DecodeErrorsTotal++
+		if c != nil {
+			c.failed = true
+		}
+		rows = nil
+	}
+
+	var events []*Event
+	for _, row := range rows {
+		rr, ok := convertRow(row)
+		if !ok {
+			continue
+		}
+		b.stats.RowsTotal++
+		key := TableKey{NodeID: r.NodeID, VRF: rr.vrf, AFI: rr.afi}
+		if rr.route == nil {
+			if e := b.delete(key, rr.network, false, now); e != nil {
+				events = append(events, e)
+			} else {
+				b.stats.UnknownDeletesTotal++
+			}
+			continue
+		}
+		rr.route.Updated = row.Timestamp
+		if rr.route.Updated.IsZero() {
+			rr.route.Updated = r.MsgTimestamp
+		}
+		if rr.route.Updated.IsZero() {
+			rr.route.Updated = now
+		}
+		if c != nil {
+			if c.seen[key] == nil {
+				c.seen[key] = make(map[string]struct{})
+			}
+			c.seen[key][rr.network] = struct{}{}
+		}
+		if e := b.set(key, rr.network, r.Subscription, r.EncodingPath, rr.route, now); e != nil {
+			events = append(events, e)
+		}
+	}
+	if c != nil && !r.CollectionEndTime.IsZero() {
+		events = append(events, b.completeSync(r, c, now)...)
+	}
+
+	return events
+}
+
+// collection returns the full sync the record belongs to. A record of another collection
+// abandons the sync in progress, must be called with the lock held.
+func (b *Builder) collection(r *decoder.Record) *collection {
+	key := syncKey{NodeID: r.NodeID, Subscription: r.Subscription, EncodingPath: r.EncodingPath}
+	c, ok := b.syncs[key]
+	if ok && c.id == r.CollectionID {
+		return c
+	}
+	if ok {
+		b.stats.IncompleteSyncsTotal++
+	}
+	c = &collection{id: r.CollectionID, seen: make(map[TableKey]map[string]struct{})}
+	b.syncs[key] = c
+
+	return c
+}
+
+// completeSync deletes the routes of the node, subscription and encoding path which the
+// completed full sync did not carry, must be called with the lock held.
+func (b *Builder) completeSync(r *decoder.Record, c *collection, now time.Time) []*Event {
+	delete(b.syncs, syncKey{NodeID: r.NodeID, Subscription: r.Subscription, EncodingPath: r.EncodingPath})
+	if c.failed {
+		b.stats.IncompleteSyncsTotal++
+		return nil
+	}
+	b.stats.FullSyncsTotal++
+	var events []*Event
+	for key, routes := range b.tables {
+		if key.NodeID != r.NodeID {
+			continue
+		}
+		for network, e := range routes {
+			if _, ok := c.seen[key][network]; ok || e.subscription != r.Subscription || e.encodingPath != r.EncodingPath {
+				continue
+			}
+			events = append(events, b.delete(key, network, true, now))
+		}
+	}
+
+	return events
+}
+
+// set must be called with the lock held.
+func (b *Builder) set(key TableKey, network, subscription, encodingPath string, rt *Route, now time.Time) *Event {
+	routes, ok := b.tables[key]
+	if !ok {
+		routes = make(map[string]*entry)
+		b.tables[key] = routes
+	}
+	e, ok := routes[network]
+	if !ok {
+		routes[network] = &entry{route: rt, subscription: subscription, encodingPath: encodingPath}
+		b.stats.AddsTotal++
+		return &Event{Kind: KindAdd, Table: key, Route: rt, Time: now}
+	}
+	e.subscription, e.encodingPath = subscription, encodingPath
+	if e.route.equal(rt) {
+		return nil
+	}
+	prev := e.route
+	e.route = rt
+	b.stats.UpdatesTotal++
+
+	return &Event{Kind: KindUpdate, Table: key, Route: rt, Previous: prev, Time: now}
+}
+
+// delete returns nil when the route is not in the table, must be called with the lock held.
+func (b *Builder) delete(key TableKey, network string, stale bool, now time.Time) *Event {
+	e, ok := b.tables[key][network]
+	if !ok {
+		return nil
+	}
+	delete(b.tables[key], network)
+	if len(b.tables[key]) == 0 {
+		delete(b.tables, key)
+	}
+	b.stats.DeletesTotal++
+	if stale {
+		b.stats.StaleDeletesTotal++
+	}
+
+	return &Event{Kind: KindDelete, Table: key, Previous: e.route, Stale: stale, Time: now}
+}
+
+// ResetNode deletes all routes of the node, for example when its producer disconnected or its
+// collections were reset, and abandons its full syncs in progress.
+func (b *Builder) ResetNode(nodeID string) []*Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	for key := range b.syncs {
+		if key.NodeID == nodeID {
+			delete(b.syncs, key)
+			b.stats.IncompleteSyncsTotal++
+		}
+	}
+	var events []*Event
+	for key, routes := range b.tables {
+		if key.NodeID != nodeID {
+			continue
+		}
+		for network := range routes {
+			events = append(events, b.delete(key, network, true, now))
+		}
+	}
+
+	return events
+}
+
+// Table returns the routes of the table sorted by prefix, nil when the table is empty.
+func (b *Builder) Table(key TableKey) []*Route {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return sortedRoutes(b.tables[key])
+}
+
+// Snapshot returns the state of all routing tables.
+func (b *Builder) Snapshot() *Snapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := &Snapshot{
+		Time:   b.now().UTC(),
+		Tables: make([]Table, 0, len(b.tables)),
+	}
+	for key, routes := range b.tables {
+		s.Tables = append(s.Tables, Table{TableKey: key, Routes: sortedRoutes(routes)})
+	}
+	sort.Slice(s.Tables, func(i, j int) bool {
+		a, b := s.Tables[i], s.Tables[j]
+		if a.NodeID != b.NodeID {
+			return a.NodeID < b.NodeID
+		}
+		if a.VRF != b.VRF {
+			return a.VRF < b.VRF
+		}
+		return a.AFI < b.AFI
+	})
+
+	return s
+}
+
+func (b *Builder) GetSnapshotJson() ([]byte, error) {
+	return json.Marshal(b.Snapshot())
+}
+
+func (b *Builder) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := b.stats
+	s.TablesActive = int64(len(b.tables))
+	for _, routes := range b.tables {
+		s.RoutesActive += int64(len(routes))
+	}
+	s.EventsDroppedTotal = b.eventsDropped.Load()
+
+	return s
+}
+
+func (b *Builder) GetStatsJson() ([]byte, error) {
+	return json.Marshal(b.Stats())
+}
+
+func sortedRoutes(routes map[string]*entry) []*Route {
+	if len(routes) == 0 {
+		return nil
+	}
+	networks := make([]string, 0, len(routes))
+	for network := range routes {
+		networks = append(networks, network)
+	}
+	sort.Strings(networks)
+	sorted := make([]*Route, 0, len(routes))
+	for _, network := range networks {
+		sorted = append(sorted, routes[network].route)
+	}
+	// The stable sort keeps routes without a valid prefix in the order of their network keys.
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].Prefix, sorted[j].Prefix
+		if c := a.Addr().Compare(b.Addr()); c != 0 {
+			return c < 0
+		}
+		return a.Bits() < b.Bits()
+	})
+
+	return sorted
+}
@@ -0,0 +1,238 @@
+package rib_builder
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/sbezverk/tools/telemetry_feeder/decoder"
+	ipv6_route "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/ipv6_routes/route"
+	route "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/routes/route"
+	"github.com/sbezverk/tools/telemetry_feeder/proto/telemetry"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	routePath     = "Cisco-IOS-XR-ip-rib-ipv4-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/routes/route"
+	ipv6RoutePath = "Cisco-IOS-XR-ip-rib-ipv6-oper:ipv6-rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/routes/route"
+)
+
+// ipv4Row returns the row of prefix in vrf, a nil nextHop makes a delete row.
+func ipv4Row(t *testing.T, vrf, prefix string, nextHop *string) *telemetry.TelemetryRowGPB {
+	t.Helper()
+	p := netip.MustParsePrefix(prefix)
+	keys, err := proto.Marshal(&route.RibEdmRoute_KEYS{VrfName: vrf, AfName: "IPv4", SafName: "Unicast", Network: p.Addr().String()})
+	if err != nil {
+		t.Fatalf("failed to marshal keys: %v", err)
+	}
+	if nextHop == nil {
+		return &telemetry.TelemetryRowGPB{Delete: true, Keys: keys}
+	}
+	content, err := proto.Marshal(&route.RibEdmRoute{
+		Prefix:       &route.RibEdmIpPfxT{IpAddress: &route.RibEdmIpAddrT{Afi: "IPv4", Ipv4: p.Addr().String()}, PrefixLength: uint32(p.Bits())},
+		ProtocolName: "isis",
+		Distance:     115,
+		Metric:       20,
+		RoutePath: []*route.RibEdmPath{
+			{Address: &route.RibEdmIpAddrT{Afi: "IPv4", Ipv4: *nextHop}, InterfaceName: "GigabitEthernet0/0/0/0", LabelStack: []uint32{16001}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal content: %v", err)
+	}
+
+	return &telemetry.TelemetryRowGPB{Timestamp: 1700000000000, Keys: keys, Content: content}
+}
+
+func record(t *testing.T, node string, id uint64, ended bool, path string, rows ...*telemetry.TelemetryRowGPB) *decoder.Record {
+	t.Helper()
+	msg := &telemetry.Telemetry{
+		NodeId:       &telemetry.Telemetry_NodeIdStr{NodeIdStr: node},
+		Subscription: &telemetry.Telemetry_SubscriptionIdStr{SubscriptionIdStr: "rib"},
+		EncodingPath: path,
+		CollectionId: id,
+		DataGpb:      &telemetry.TelemetryGPBTable{Row: rows},
+	}
+	if ended {
+		msg.CollectionEndTime = 1700000000000
+	}
+	r, err := decoder.FromTelemetry(msg)
+	if err != nil {
+		t.Fatalf("failed to build record: %v", err)
+	}
+
+	return r
+}
+
+func kinds(events []*Event) string {
+	var k []string
+	for _, e := range events {
+		s := string(e.Kind)
+		if e.Stale {
+			s += "(stale)"
+		}
+		k = append(k, s)
+	}
+	return fmt.Sprint(k)
+}
+
+func nh(s string) *string {
+	return &s
+}
+
+func TestApply(t *testing.T) {
+	b, err := New()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, step := range []struct {
+		rows []*telemetry.TelemetryRowGPB
+		want string
+	}{
+		{rows: []*telemetry.TelemetryRowGPB{ipv4Row(t, "default", "10.0.0.0/24", nh("192.168.0.1")), ipv4Row(t, "", "10.0.1.0/24", nh("192.168.0.1"))}, want: "[add add]"},
+		// Refreshing a route without a change is not reported.
+		{rows: []*telemetry.TelemetryRowGPB{ipv4Row(t, "default", "10.0.0.0/24", nh("192.168.0.1"))}, want: "[]"},
+		{rows: []*telemetry.TelemetryRowGPB{ipv4Row(t, "default", "10.0.0.0/24", nh("192.168.0.2"))}, want: "[update]"},
+		{rows: []*telemetry.TelemetryRowGPB{ipv4Row(t, "blue", "10.0.0.0/24", nh("192.168.0.3"))}, want: "[add]"},
+		{rows: []*telemetry.TelemetryRowGPB{ipv4Row(t, "default", "10.0.1.0/24", nil), ipv4Row(t, "default", "10.0.9.0/24", nil)}, want: "[delete]"},
+	} {
+		if got := kinds(b.Apply(record(t, "r1", uint64(i+1), true, routePath, step.rows...))); got != step.want {
+			t.Fatalf("step %d: expected %s, got %s", i, step.want, got)
+		}
+	}
+
+	routes := b.Table(TableKey{NodeID: "r1", VRF: DefaultVRF, AFI: AFIIPv4})
+	if len(routes) != 1 || routes[0].Prefix != netip.MustParsePrefix("10.0.0.0/24") || routes[0].Protocol != "isis" ||
+		len(routes[0].Paths) != 1 || routes[0].Paths[0].NextHop != netip.MustParseAddr("192.168.0.2") || routes[0].Paths[0].Labels[0] != 16001 {
+		t.Fatalf("unexpected routes %+v", routes)
+	}
+	if routes[0].Updated.IsZero() {
+		t.Fatal("expected the row timestamp to be kept")
+	}
+
+	keys, _ := proto.Marshal(&ipv6_route.RibEdmRoute_KEYS{VrfName: "default", Network: "2001:db8::"})
+	content, _ := proto.Marshal(&ipv6_route.RibEdmRoute{
+		Prefix:       &ipv6_route.RibEdmIpPfxT{IpAddress: &ipv6_route.RibEdmIpAddrT{Ipv6: "2001:db8::"}, PrefixLength: 32},
+		ProtocolName: "bgp",
+		RoutePath:    []*ipv6_route.RibEdmPath{{Address: &ipv6_route.RibEdmIpAddrT{Ipv6: "fe80::1"}}},
+	})
+	events := b.Apply(record(t, "r1", 1, true, ipv6RoutePath, &telemetry.TelemetryRowGPB{Keys: keys, Content: content}))
+	if len(events) != 1 || events[0].Table.AFI != AFIIPv6 || events[0].Route.Prefix != netip.MustParsePrefix("2001:db8::/32") ||
+		events[0].Route.Paths[0].NextHop != netip.MustParseAddr("fe80::1") {
+		t.Fatalf("unexpected ipv6 events %+v", events)
+	}
+	// Records of other encoding paths are ignored.
+	b.Apply(record(t, "r1", 1, true, "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/q-routes/q-route"))
+
+	s := b.Stats()
+	if s.AddsTotal != 4 || s.UpdatesTotal != 1 || s.DeletesTotal != 1 || s.UnknownDeletesTotal != 1 ||
+		s.IgnoredTotal != 1 || s.TablesActive != 3 || s.RoutesActive != 3 || s.RowsTotal != 8 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+
+	b.now = func() time.Time { return time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC) }
+	j, err := b.GetSnapshotJson()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(j, &snapshot); err != nil {
+		t.Fatalf("failed to unmarshal snapshot %s: %v", j, err)
+	}
+	if len(snapshot.Tables) != 3 || snapshot.Tables[0].VRF != "blue" || snapshot.Tables[2].AFI != AFIIPv6 ||
+		snapshot.Tables[1].Routes[0].Paths[0].Interface != "GigabitEthernet0/0/0/0" {
+		t.Fatalf("unexpected snapshot %s", j)
+	}
+
+	events = b.ResetNode("r1")
+	if kinds(events) != "[delete(stale) delete(stale) delete(stale)]" || len(b.Snapshot().Tables) != 0 {
+		t.Fatalf("unexpected reset events %s", kinds(events))
+	}
+}
+
+func TestFullSync(t *testing.T) {
+	b, err := New(WithFullSync(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a, c, d := ipv4Row(t, "default", "10.0.0.0/24", nh("192.168.0.1")), ipv4Row(t, "default", "10.0.1.0/24", nh("192.168.0.1")),
+		ipv4Row(t, "blue", "10.0.2.0/24", nh("192.168.0.1"))
+	if got := kinds(b.Apply(record(t, "r1", 1, true, routePath, a, c, d))); got != "[add add add]" {
+		t.Fatalf("unexpected events of the first sync %s", got)
+	}
+	b.Apply(record(t, "r2", 1, true, routePath, a))
+
+	// The second collection spans two messages and no longer carries 10.0.1.0/24 and the blue VRF.
+	if got := kinds(b.Apply(record(t, "r1", 2, false, routePath))); got != "[]" {
+		t.Fatalf("unexpected events before the end of the collection %s", got)
+	}
+	events := b.Apply(record(t, "r1", 2, true, routePath, a))
+	if kinds(events) != "[delete(stale) delete(stale)]" {
+		t.Fatalf("unexpected events of the second sync %s", kinds(events))
+	}
+	// Rows apply right away, but a collection abandoned before its end does not delete anything.
+	if got := kinds(b.Apply(record(t, "r1", 3, false, routePath, c))); got != "[add]" {
+		t.Fatalf("unexpected events of the abandoned sync %s", got)
+	}
+	if got := kinds(b.Apply(record(t, "r1", 4, false, routePath))); got != "[]" {
+		t.Fatalf("unexpected events after the abandoned sync %s", got)
+	}
+	if got := kinds(b.Apply(record(t, "r1", 4, true, routePath, c))); got != "[delete(stale)]" {
+		t.Fatalf("unexpected events of the last sync %s", got)
+	}
+
+	s := b.Stats()
+	if s.FullSyncsTotal != 4 || s.IncompleteSyncsTotal != 1 || s.StaleDeletesTotal != 3 || s.RoutesActive != 2 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+	if routes := b.Table(TableKey{NodeID: "r2", VRF: DefaultVRF, AFI: AFIIPv4}); len(routes) != 1 {
+		t.Fatalf("expected the routes of r2 to be kept, got %+v", routes)
+	}
+
+	// A sync only deletes the routes of its own subscription on the same encoding path.
+	other := record(t, "r1", 1, true, routePath, d)
+	other.Subscription = "rib-blue"
+	if got := kinds(b.Apply(other)); got != "[add]" {
+		t.Fatalf("unexpected events of the other subscription %s", got)
+	}
+	if got := kinds(b.Apply(record(t, "r1", 5, true, routePath, c))); got != "[]" {
+		t.Fatalf("unexpected events of the sync of the first subscription %s", got)
+	}
+	if routes := b.Table(TableKey{NodeID: "r1", VRF: "blue", AFI: AFIIPv4}); len(routes) != 1 {
+		t.Fatalf("expected the routes of the other subscription to be kept, got %+v", routes)
+	}
+}
+
+func TestLosslessStage(t *testing.T) {
+	in := make(chan *decoder.Record, 1)
+	s, err := NewStage(in, WithLosslessEvents())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer s.Stop()
+	// More changes than the events channel holds, in a single record.
+	var rows []*telemetry.TelemetryRowGPB
+	for i := 0; i < 1500; i++ {
+		rows = append(rows, ipv4Row(t, "default", fmt.Sprintf("10.%d.%d.0/24", i/256, i%256), nh("192.168.0.1")))
+	}
+	in <- record(t, "r1", 1, true, routePath, rows...)
+	close(in)
+	select {
+	case <-s.GetRecords():
+		t.Fatal("the record was passed through before its events were read")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	n := 0
+	for range s.Events() {
+		n++
+	}
+	if n != len(rows) || s.Builder().Stats().EventsDroppedTotal != 0 {
+		t.Fatalf("expected %d events and none dropped, got %d events and %d dropped", len(rows), n, s.Builder().Stats().EventsDroppedTotal)
+	}
+	if r, ok := <-s.GetRecords(); !ok || len(r.Rows) != len(rows) {
+		t.Fatal("expected the record to be passed through")
+	}
+}
@@ -0,0 +1,35 @@
+package rib_builder
+
+import (
+	"github.com/sbezverk/tools/telemetry_feeder/decoder"
+)
+
+// Stage is a pipeline stage passing through every record of a decoder while applying them to
+// the routing tables. Events dropped because the events channel is full are counted in
+// Stats.EventsDroppedTotal, a consumer mirroring the tables then resynchronizes from
+// Builder().Snapshot(). WithLosslessEvents makes the stage wait for the consumer instead.
+type Stage struct {
+	*decoder.Stage[*Event]
+	builder *Builder
+}
+
+// NewStage starts applying the records read from the in channel, the records and events
+// channels are closed when in is closed or Stop is called.
+func NewStage(in chan *decoder.Record, opts ...Option) (*Stage, error) {
+	builder, err := New(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Stage{
+		Stage: decoder.NewStage(in, builder.Apply, decoder.StageConfig{
+			Lossless: builder.opts.losslessEvents,
+			Dropped:  &builder.eventsDropped,
+		}),
+		builder: builder,
+	}, nil
+}
+
+func (s *Stage) Builder() *Builder {
+	return s.builder
+}
@@ -0,0 +1,278 @@
+package schema_registry
+
+import (
+	backup_route "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/backup_routes/backup_route"
+	deleted_route "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/deleted_routes/deleted_route"
+	dest_best_route "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/destination_kw/dest_best_routes/dest_best_route"
+	dest_next_hop_route "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/destination_kw/dest_next_hop_routes/dest_next_hop_route"
+	dest_q_route "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/destination_kw/dest_q_routes/dest_q_route"
+	ipv6_route "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/ipv6_routes/route"
+	nexthop_damping_history "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/nexthop_damping_histories/nexthop_damping_history"
+	opaque "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/opaques/opaque"
+	protocol_application_non_as_information "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/application/non_as/information"
+	protocol_application_non_as_protocol_route "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/application/non_as/protocol_routes/protocol_route"
+	protocol_bgp_as_information "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/bgp/as/information"
+	protocol_bgp_as_protocol_route "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/bgp/as/protocol_routes/protocol_route"
+	protocol_connected_l2vpn_information "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/connected/l2vpn/information"
+	protocol_connected_non_as_information "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/connected/non_as/information"
+	protocol_connected_non_as_protocol_route "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/connected/non_as/protocol_routes/protocol_route"
+	protocol_dagr_non_as_information "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/dagr/non_as/information"
+	protocol_dagr_non_as_protocol_route "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/dagr/non_as/protocol_routes/protocol_route"
+	protocol_eigrp_as_information "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/eigrp/as/information"
+	protocol_eigrp_as_protocol_route "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/eigrp/as/protocol_routes/protocol_route"
+	protocol_iid_local_non_as_information "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/iid_local/non_as/information"
+	protocol_isis_as_information "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/isis/as/information"
+	protocol_isis_as_protocol_route "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/isis/as/protocol_routes/protocol_route"
+	protocol_local_lspv_information "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/local/lspv/information"
+	protocol_local_lspv_protocol_route "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/local/lspv/protocol_routes/protocol_route"
+	protocol_local_non_as_information "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/local/non_as/information"
+	protocol_local_non_as_protocol_route "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/local/non_as/protocol_routes/protocol_route"
+	protocol_local_smiap_information "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/local/smiap/information"
+	protocol_local_smiap_protocol_route "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/local/smiap/protocol_routes/protocol_route"
+	protocol_mobile_non_as_information "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/mobile/non_as/information"
+	protocol_mobile_non_as_protocol_route "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/mobile/non_as/protocol_routes/protocol_route"
+	protocol_ospf_as_information "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/ospf/as/information"
+	protocol_ospf_as_protocol_route "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/ospf/as/protocol_routes/protocol_route"
+	protocol_rip_non_as_information "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/rip/non_as/information"
+	protocol_rip_non_as_protocol_route "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/rip/non_as/protocol_routes/protocol_route"
+	protocol_rpl_as_information "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/rpl/as/information"
+	protocol_rpl_as_protocol_route "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/rpl/as/protocol_routes/protocol_route"
+	protocol_srv6_local_non_as_information "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/srv6_local/non_as/information"
+	protocol_static_non_as_information "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/static/non_as/information"
+	protocol_static_non_as_protocol_route "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/static/non_as/protocol_routes/protocol_route"
+	protocol_subscriber_non_as_information "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/subscriber/non_as/information"
+	protocol_subscriber_non_as_protocol_route "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/subscriber/non_as/protocol_routes/protocol_route"
+	protocol_te_client_non_as_information "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/protocol/te_client/non_as/information"
+	q_route "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/q_routes/q_route"
+	ribtph_entry "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/ribtph_entries/ribtph_entry"
+	route "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/routes/route"
+)
+
+// iosXRRIBSchemas lists the generated ios-xr-rib packages by the encoding path they were generated for.
+var iosXRRIBSchemas = []*Schema{
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/routes/route",
+		Keys:         (&route.RibEdmRoute_KEYS{}).ProtoReflect().Type(),
+		Content:      (&route.RibEdmRoute{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:ipv6-rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/routes/route",
+		Keys:         (&ipv6_route.RibEdmRoute_KEYS{}).ProtoReflect().Type(),
+		Content:      (&ipv6_route.RibEdmRoute{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/backup-routes/backup-route",
+		Keys:         (&backup_route.RibEdmRoute_KEYS{}).ProtoReflect().Type(),
+		Content:      (&backup_route.RibEdmRoute{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/deleted-routes/deleted-route",
+		Keys:         (&deleted_route.RibEdmRoute_KEYS{}).ProtoReflect().Type(),
+		Content:      (&deleted_route.RibEdmRoute{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/destination-kw/dest-best-routes/dest-best-route",
+		Keys:         (&dest_best_route.RibEdmRoute_KEYS{}).ProtoReflect().Type(),
+		Content:      (&dest_best_route.RibEdmRoute{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/destination-kw/dest-next-hop-routes/dest-next-hop-route",
+		Keys:         (&dest_next_hop_route.RibEdmRoute_KEYS{}).ProtoReflect().Type(),
+		Content:      (&dest_next_hop_route.RibEdmRoute{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/destination-kw/dest-q-routes/dest-q-route",
+		Keys:         (&dest_q_route.RibEdmRoute_KEYS{}).ProtoReflect().Type(),
+		Content:      (&dest_q_route.RibEdmRoute{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/nexthop-damping-histories/nexthop-damping-history",
+		Keys:         (&nexthop_damping_history.RibEdmNhDampHistInfo_KEYS{}).ProtoReflect().Type(),
+		Content:      (&nexthop_damping_history.RibEdmNhDampHistInfo{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/opaques/opaque",
+		Keys:         (&opaque.RibEdmOpaqueObj_KEYS{}).ProtoReflect().Type(),
+		Content:      (&opaque.RibEdmOpaqueObj{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/application/non-as/information",
+		Keys:         (&protocol_application_non_as_information.RibEdmProto_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_application_non_as_information.RibEdmProto{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/application/non-as/protocol-routes/protocol-route",
+		Keys:         (&protocol_application_non_as_protocol_route.RibEdmRoute_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_application_non_as_protocol_route.RibEdmRoute{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/bgp/as/information",
+		Keys:         (&protocol_bgp_as_information.RibEdmProto_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_bgp_as_information.RibEdmProto{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/bgp/as/protocol-routes/protocol-route",
+		Keys:         (&protocol_bgp_as_protocol_route.RibEdmRoute_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_bgp_as_protocol_route.RibEdmRoute{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/connected/l2vpn/information",
+		Keys:         (&protocol_connected_l2vpn_information.RibEdmProto_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_connected_l2vpn_information.RibEdmProto{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/connected/non-as/information",
+		Keys:         (&protocol_connected_non_as_information.RibEdmProto_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_connected_non_as_information.RibEdmProto{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/connected/non-as/protocol-routes/protocol-route",
+		Keys:         (&protocol_connected_non_as_protocol_route.RibEdmRoute_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_connected_non_as_protocol_route.RibEdmRoute{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/dagr/non-as/information",
+		Keys:         (&protocol_dagr_non_as_information.RibEdmProto_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_dagr_non_as_information.RibEdmProto{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/dagr/non-as/protocol-routes/protocol-route",
+		Keys:         (&protocol_dagr_non_as_protocol_route.RibEdmRoute_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_dagr_non_as_protocol_route.RibEdmRoute{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/eigrp/as/information",
+		Keys:         (&protocol_eigrp_as_information.RibEdmProto_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_eigrp_as_information.RibEdmProto{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/eigrp/as/protocol-routes/protocol-route",
+		Keys:         (&protocol_eigrp_as_protocol_route.RibEdmRoute_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_eigrp_as_protocol_route.RibEdmRoute{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/iid-local/non-as/information",
+		Keys:         (&protocol_iid_local_non_as_information.RibEdmProto_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_iid_local_non_as_information.RibEdmProto{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/isis/as/information",
+		Keys:         (&protocol_isis_as_information.RibEdmProto_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_isis_as_information.RibEdmProto{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/isis/as/protocol-routes/protocol-route",
+		Keys:         (&protocol_isis_as_protocol_route.RibEdmRoute_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_isis_as_protocol_route.RibEdmRoute{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/local/lspv/information",
+		Keys:         (&protocol_local_lspv_information.RibEdmProto_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_local_lspv_information.RibEdmProto{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/local/lspv/protocol-routes/protocol-route",
+		Keys:         (&protocol_local_lspv_protocol_route.RibEdmRoute_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_local_lspv_protocol_route.RibEdmRoute{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/local/non-as/information",
+		Keys:         (&protocol_local_non_as_information.RibEdmProto_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_local_non_as_information.RibEdmProto{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/local/non-as/protocol-routes/protocol-route",
+		Keys:         (&protocol_local_non_as_protocol_route.RibEdmRoute_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_local_non_as_protocol_route.RibEdmRoute{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/local/smiap/information",
+		Keys:         (&protocol_local_smiap_information.RibEdmProto_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_local_smiap_information.RibEdmProto{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/local/smiap/protocol-routes/protocol-route",
+		Keys:         (&protocol_local_smiap_protocol_route.RibEdmRoute_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_local_smiap_protocol_route.RibEdmRoute{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/mobile/non-as/information",
+		Keys:         (&protocol_mobile_non_as_information.RibEdmProto_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_mobile_non_as_information.RibEdmProto{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/mobile/non-as/protocol-routes/protocol-route",
+		Keys:         (&protocol_mobile_non_as_protocol_route.RibEdmRoute_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_mobile_non_as_protocol_route.RibEdmRoute{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/ospf/as/information",
+		Keys:         (&protocol_ospf_as_information.RibEdmProto_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_ospf_as_information.RibEdmProto{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/ospf/as/protocol-routes/protocol-route",
+		Keys:         (&protocol_ospf_as_protocol_route.RibEdmRoute_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_ospf_as_protocol_route.RibEdmRoute{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/rip/non-as/information",
+		Keys:         (&protocol_rip_non_as_information.RibEdmProto_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_rip_non_as_information.RibEdmProto{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/rip/non-as/protocol-routes/protocol-route",
+		Keys:         (&protocol_rip_non_as_protocol_route.RibEdmRoute_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_rip_non_as_protocol_route.RibEdmRoute{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/rpl/as/information",
+		Keys:         (&protocol_rpl_as_information.RibEdmProto_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_rpl_as_information.RibEdmProto{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/rpl/as/protocol-routes/protocol-route",
+		Keys:         (&protocol_rpl_as_protocol_route.RibEdmRoute_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_rpl_as_protocol_route.RibEdmRoute{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/srv6-local/non-as/information",
+		Keys:         (&protocol_srv6_local_non_as_information.RibEdmProto_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_srv6_local_non_as_information.RibEdmProto{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/static/non-as/information",
+		Keys:         (&protocol_static_non_as_information.RibEdmProto_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_static_non_as_information.RibEdmProto{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/static/non-as/protocol-routes/protocol-route",
+		Keys:         (&protocol_static_non_as_protocol_route.RibEdmRoute_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_static_non_as_protocol_route.RibEdmRoute{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/subscriber/non-as/information",
+		Keys:         (&protocol_subscriber_non_as_information.RibEdmProto_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_subscriber_non_as_information.RibEdmProto{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/subscriber/non-as/protocol-routes/protocol-route",
+		Keys:         (&protocol_subscriber_non_as_protocol_route.RibEdmRoute_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_subscriber_non_as_protocol_route.RibEdmRoute{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/protocol/te-client/non-as/information",
+		Keys:         (&protocol_te_client_non_as_information.RibEdmProto_KEYS{}).ProtoReflect().Type(),
+		Content:      (&protocol_te_client_non_as_information.RibEdmProto{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/q-routes/q-route",
+		Keys:         (&q_route.RibEdmRoute_KEYS{}).ProtoReflect().Type(),
+		Content:      (&q_route.RibEdmRoute{}).ProtoReflect().Type(),
+	},
+	{
+		EncodingPath: "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/ribtph-entries/ribtph-entry",
+		Keys:         (&ribtph_entry.RibEdmTphDbEntry_KEYS{}).ProtoReflect().Type(),
+		Content:      (&ribtph_entry.RibEdmTphDbEntry{}).ProtoReflect().Type(),
+	},
+}
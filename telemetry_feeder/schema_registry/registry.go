@@ -0,0 +1,169 @@
+package schema_registry
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sbezverk/tools/telemetry_feeder/decoder"
+	"github.com/sbezverk/tools/telemetry_feeder/proto/telemetry"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var (
+	// ErrAlreadyRegistered error returns when Register attempts to add a schema for an already known encoding path
+	ErrAlreadyRegistered = errors.New("schema already registered")
+	// ErrUnknownEncodingPath error returns when no schema is registered for the encoding path
+	ErrUnknownEncodingPath = errors.New("no schema registered for encoding path")
+)
+
+// Schema binds an encoding path to the generated message types of its compact GPB rows.
+type Schema struct {
+	EncodingPath string
+	Keys         protoreflect.MessageType
+	Content      protoreflect.MessageType
+}
+
+// Row is a compact GPB row with keys and content unmarshalled into the schema types,
+// Content is nil for delete rows which carry no content.
+type Row struct {
+	Timestamp time.Time
+	Delete    bool
+	Keys      proto.Message
+	Content   proto.Message
+}
+
+// Registry maps encoding paths to schemas, it is safe for concurrent use.
+type Registry struct {
+	mu sync.RWMutex
+	// schemas is keyed by the full encoding path, module name included.
+	schemas map[string]*Schema
+	// byPath is keyed by the encoding path without the YANG module prefix, XR releases
+	// publish the same tree under different module names, e.g. Cisco-IOS-XR-ip-rib-oper
+	// and Cisco-IOS-XR-ip-rib-ipv4-oper.
+	byPath map[string]*Schema
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		schemas: make(map[string]*Schema),
+		byPath:  make(map[string]*Schema),
+	}
+}
+
+// NewIOSXRRIBRegistry returns a registry prepopulated with the ios-xr-rib schemas shipped
+// under telemetry_feeder/proto/ios-xr-rib.
+func NewIOSXRRIBRegistry() *Registry {
+	r := NewRegistry()
+	for _, s := range iosXRRIBSchemas {
+		if err := r.Register(s); err != nil {
+			panic(fmt.Sprintf("failed to register built-in schema %s: %+v", s.EncodingPath, err))
+		}
+	}
+
+	return r
+}
+
+// Register adds a schema to the registry, applications use it to teach the registry
+// about encoding paths beyond the built-in ones.
+func (r *Registry) Register(s *Schema) error {
+	if s == nil || s.EncodingPath == "" {
+		return fmt.Errorf("schema must have an encoding path")
+	}
+	if s.Keys == nil || s.Content == nil {
+		return fmt.Errorf("schema for %s must have both keys and content message types", s.EncodingPath)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.schemas[s.EncodingPath]; ok {
+		return fmt.Errorf("%w: %s", ErrAlreadyRegistered, s.EncodingPath)
+	}
+	r.schemas[s.EncodingPath] = s
+	if _, ok := r.byPath[stripModule(s.EncodingPath)]; !ok {
+		r.byPath[stripModule(s.EncodingPath)] = s
+	}
+
+	return nil
+}
+
+// Lookup returns the schema for the encoding path, an exact match is preferred, otherwise
+// the path is matched ignoring the YANG module prefix.
+func (r *Registry) Lookup(encodingPath string) (*Schema, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if s, ok := r.schemas[encodingPath]; ok {
+		return s, true
+	}
+	s, ok := r.byPath[stripModule(encodingPath)]
+
+	return s, ok
+}
+
+// EncodingPaths returns the full encoding paths of all registered schemas.
+func (r *Registry) EncodingPaths() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	paths := make([]string, 0, len(r.schemas))
+	for p := range r.schemas {
+		paths = append(paths, p)
+	}
+
+	return paths
+}
+
+// DecodeRow unmarshals keys and content of a compact GPB row using the schema of the encoding path.
+func (r *Registry) DecodeRow(encodingPath string, row *telemetry.TelemetryRowGPB) (*Row, error) {
+	return r.decode(encodingPath, decoder.MillisToTime(row.GetTimestamp()), row.GetDelete(), row.GetKeys(), row.GetContent())
+}
+
+// DecodeRecord unmarshals all rows of a decoded compact GPB record.
+func (r *Registry) DecodeRecord(rec *decoder.Record) ([]*Row, error) {
+	if rec.Format != decoder.FormatGPB {
+		return nil, fmt.Errorf("record for %s is not in the compact GPB format: %q", rec.EncodingPath, rec.Format)
+	}
+	rows := make([]*Row, 0, len(rec.Rows))
+	for i, row := range rec.Rows {
+		dr, err := r.decode(rec.EncodingPath, row.Timestamp, row.Delete, row.Keys, row.Content)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+		rows = append(rows, dr)
+	}
+
+	return rows, nil
+}
+
+func (r *Registry) decode(encodingPath string, ts time.Time, del bool, keys, content []byte) (*Row, error) {
+	s, ok := r.Lookup(encodingPath)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEncodingPath, encodingPath)
+	}
+	row := &Row{
+		Timestamp: ts,
+		Delete:    del,
+		Keys:      s.Keys.New().Interface(),
+	}
+	if err := proto.Unmarshal(keys, row.Keys); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal keys of %s: %w", encodingPath, err)
+	}
+	if del && len(content) == 0 {
+		return row, nil
+	}
+	row.Content = s.Content.New().Interface()
+	if err := proto.Unmarshal(content, row.Content); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal content of %s: %w", encodingPath, err)
+	}
+
+	return row, nil
+}
+
+func stripModule(encodingPath string) string {
+	if i := strings.Index(encodingPath, ":"); i >= 0 {
+		return encodingPath[i+1:]
+	}
+	return encodingPath
+}
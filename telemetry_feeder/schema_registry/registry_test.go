@@ -0,0 +1,116 @@
+package schema_registry
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sbezverk/tools/telemetry_feeder/decoder"
+	route "github.com/sbezverk/tools/telemetry_feeder/proto/ios-xr-rib/routes/route"
+	"github.com/sbezverk/tools/telemetry_feeder/proto/telemetry"
+	"google.golang.org/protobuf/proto"
+)
+
+const routePath = "Cisco-IOS-XR-ip-rib-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/routes/route"
+
+func TestBuiltInSchemas(t *testing.T) {
+	r := NewIOSXRRIBRegistry()
+
+	if got := len(r.EncodingPaths()); got != len(iosXRRIBSchemas) {
+		t.Fatalf("expected %d registered paths, got %d", len(iosXRRIBSchemas), got)
+	}
+	s, ok := r.Lookup(routePath)
+	if !ok {
+		t.Fatalf("schema for %s is not registered", routePath)
+	}
+	if s.Keys.Descriptor().FullName() != (&route.RibEdmRoute_KEYS{}).ProtoReflect().Descriptor().FullName() {
+		t.Fatalf("unexpected keys type %s", s.Keys.Descriptor().FullName())
+	}
+	// Newer XR releases publish the same tree under the ipv4 specific module name.
+	if _, ok := r.Lookup("Cisco-IOS-XR-ip-rib-ipv4-oper:rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/routes/route"); !ok {
+		t.Fatal("expected lookup to match ignoring the module prefix")
+	}
+	if _, ok := r.Lookup("Cisco-IOS-XR-ip-rib-ipv6-oper:ipv6-rib/vrfs/vrf/afs/af/safs/saf/ip-rib-route-table-names/ip-rib-route-table-name/routes/route"); !ok {
+		t.Fatal("expected ipv6 routes schema to be registered")
+	}
+}
+
+func TestDecodeRecord(t *testing.T) {
+	r := NewIOSXRRIBRegistry()
+
+	keys, err := proto.Marshal(&route.RibEdmRoute_KEYS{VrfName: "default", Network: "10.0.0.0"})
+	if err != nil {
+		t.Fatalf("failed to marshal keys: %v", err)
+	}
+	content, err := proto.Marshal(&route.RibEdmRoute{ProtocolName: "bgp", RouteVersion: 7})
+	if err != nil {
+		t.Fatalf("failed to marshal content: %v", err)
+	}
+	rec, err := decoder.FromTelemetry(&telemetry.Telemetry{
+		EncodingPath: routePath,
+		DataGpb: &telemetry.TelemetryGPBTable{Row: []*telemetry.TelemetryRowGPB{
+			{Timestamp: 1700000000000, Keys: keys, Content: content},
+			{Delete: true, Keys: keys},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("failed to build record: %v", err)
+	}
+
+	rows, err := r.DecodeRecord(rec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(rows))
+	}
+	k, ok := rows[0].Keys.(*route.RibEdmRoute_KEYS)
+	if !ok {
+		t.Fatalf("unexpected keys type %T", rows[0].Keys)
+	}
+	if k.GetVrfName() != "default" || k.GetNetwork() != "10.0.0.0" {
+		t.Fatalf("unexpected keys %+v", k)
+	}
+	c, ok := rows[0].Content.(*route.RibEdmRoute)
+	if !ok {
+		t.Fatalf("unexpected content type %T", rows[0].Content)
+	}
+	if c.GetProtocolName() != "bgp" || c.GetRouteVersion() != 7 {
+		t.Fatalf("unexpected content %+v", c)
+	}
+	if rows[0].Timestamp.IsZero() {
+		t.Fatal("expected row timestamp to be preserved")
+	}
+	if !rows[1].Delete || rows[1].Content != nil {
+		t.Fatalf("expected delete row without content, got %+v", rows[1])
+	}
+}
+
+func TestRegisterCustomSchema(t *testing.T) {
+	r := NewRegistry()
+	s := &Schema{
+		EncodingPath: "Example-oper:custom/path",
+		Keys:         (&telemetry.TelemetryRowGPB{}).ProtoReflect().Type(),
+		Content:      (&telemetry.TelemetryField{}).ProtoReflect().Type(),
+	}
+	if err := r.Register(s); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.Register(s); !errors.Is(err, ErrAlreadyRegistered) {
+		t.Fatalf("expected ErrAlreadyRegistered, got %v", err)
+	}
+	if err := r.Register(&Schema{EncodingPath: "Example-oper:incomplete"}); err == nil {
+		t.Fatal("expected error for schema without message types")
+	}
+
+	content, _ := proto.Marshal(&telemetry.TelemetryField{Name: "leaf"})
+	row, err := r.DecodeRow("Example-oper:custom/path", &telemetry.TelemetryRowGPB{Content: content})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if row.Content.(*telemetry.TelemetryField).GetName() != "leaf" {
+		t.Fatalf("unexpected content %+v", row.Content)
+	}
+	if _, err := r.DecodeRow("Example-oper:unknown", &telemetry.TelemetryRowGPB{}); !errors.Is(err, ErrUnknownEncodingPath) {
+		t.Fatalf("expected ErrUnknownEncodingPath, got %v", err)
+	}
+}
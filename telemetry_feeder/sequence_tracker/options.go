@@ -0,0 +1,86 @@
+package sequence_tracker
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultReorderWindow is the number of collections a missing collection may arrive late
+	// and still be reported as out of order rather than as a stream reset.
+	DefaultReorderWindow = 64
+	// DefaultLateThreshold is how long after its timestamp a message may arrive before it is
+	// reported as late.
+	DefaultLateThreshold = 30 * time.Second
+	DefaultIdleTimeout   = 15 * time.Minute
+	DefaultMaxStreams    = 10000
+)
+
+type options struct {
+	reorderWindow uint64
+	lateThreshold time.Duration
+	idleTimeout   time.Duration
+	maxStreams    int
+}
+
+// Option customizes the tracker created by New.
+type Option func(*options)
+
+func defaultOptions() options {
+	return options{
+		reorderWindow: DefaultReorderWindow,
+		lateThreshold: DefaultLateThreshold,
+		idleTimeout:   DefaultIdleTimeout,
+		maxStreams:    DefaultMaxStreams,
+	}
+}
+
+// WithReorderWindow sets how many collections behind the newest one a collection id is still
+// matched against the gaps of the stream. An id further behind is taken as a stream reset,
+// for example after the router restarted.
+func WithReorderWindow(n uint64) Option {
+	return func(o *options) {
+		o.reorderWindow = n
+	}
+}
+
+// WithLateThreshold sets the delay between the timestamp of a message and its receive time
+// above which the message is reported late, 0 disables late detection.
+func WithLateThreshold(d time.Duration) Option {
+	return func(o *options) {
+		o.lateThreshold = d
+	}
+}
+
+// WithIdleTimeout sets how long a stream is kept without messages, a negative timeout keeps
+// streams forever.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.idleTimeout = d
+	}
+}
+
+// WithMaxStreams limits the number of tracked streams, the least recently seen stream is
+// evicted to make room for a new one. A negative limit disables it.
+func WithMaxStreams(n int) Option {
+	return func(o *options) {
+		o.maxStreams = n
+	}
+}
+
+func (o *options) validate() error {
+	if o.reorderWindow == 0 {
+		return fmt.Errorf("reorder window must be positive")
+	}
+	if o.lateThreshold < 0 {
+		return fmt.Errorf("late threshold cannot be negative")
+	}
+	if o.idleTimeout == 0 {
+		return fmt.Errorf("idle timeout cannot be 0")
+	}
+	if o.maxStreams == 0 {
+		return fmt.Errorf("max streams cannot be 0")
+	}
+
+	return nil
+}
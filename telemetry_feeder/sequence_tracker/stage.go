@@ -0,0 +1,31 @@
+package sequence_tracker
+
+import (
+	"github.com/sbezverk/tools/telemetry_feeder/decoder"
+)
+
+// Stage is a pipeline stage passing through every record of a decoder while tracking their
+// sequence, events which do not fit into the events channel are counted in
+// Stats.EventsDroppedTotal.
+type Stage struct {
+	*decoder.Stage[*Event]
+	tracker *Tracker
+}
+
+// NewStage starts tracking the records read from the in channel, the records and events
+// channels are closed when in is closed or Stop is called.
+func NewStage(in chan *decoder.Record, opts ...Option) (*Stage, error) {
+	tracker, err := New(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Stage{
+		Stage:   decoder.NewStage(in, tracker.Observe, decoder.StageConfig{Dropped: &tracker.eventsDropped}),
+		tracker: tracker,
+	}, nil
+}
+
+func (s *Stage) Tracker() *Tracker {
+	return s.tracker
+}
@@ -0,0 +1,319 @@
+package sequence_tracker
+
+import (
+	"encoding/json"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sbezverk/tools/telemetry_feeder/decoder"
+)
+
+// Kind is the kind of a sequence event.
+type Kind string
+
+const (
+	// KindGap reports collections skipped by the stream, Missing tells how many.
+	KindGap Kind = "gap"
+	// KindDuplicate reports a message of a collection which was already completed, or which is
+	// older than the newest collection and was not missing.
+	KindDuplicate Kind = "duplicate"
+	// KindOutOfOrder reports the first message of a missing collection arriving after newer
+	// ones, the collection is no longer missing.
+	KindOutOfOrder Kind = "out-of-order"
+	// KindLate reports a message received more than the late threshold after its timestamp.
+	KindLate Kind = "late"
+	// KindReset reports a collection id further behind the newest one than the reorder window,
+	// typically the producer restarted its collection ids. Tracking starts over.
+	KindReset Kind = "reset"
+)
+
+// StreamKey identifies a stream of collections, a node numbers the collections of every
+// subscription and encoding path on its own.
+type StreamKey struct {
+	NodeID       string
+	Subscription string
+	EncodingPath string
+}
+
+// Event is a sequence anomaly of a stream.
+type Event struct {
+	Kind         Kind
+	Stream       StreamKey
+	ProducerAddr net.Addr
+	CollectionID uint64
+	// Expected is the collection id following the newest one, set for KindGap and KindReset.
+	Expected uint64
+	// Missing is the number of skipped collections, set for KindGap.
+	Missing uint64
+	// Lateness is the delay between the timestamp of the message and its receive time, set for
+	// KindLate.
+	Lateness time.Duration
+	Time     time.Time
+}
+
+// Counters are the sequence counters of a stream, or of all streams in Stats.
+type Counters struct {
+	MessagesTotal           int64 `json:"messages_total"`
+	CollectionsTotal        int64 `json:"collections_total"`
+	GapsTotal               int64 `json:"gaps_total"`
+	MissingCollectionsTotal int64 `json:"missing_collections_total"`
+	DuplicatesTotal         int64 `json:"duplicates_total"`
+	OutOfOrderTotal         int64 `json:"out_of_order_total"`
+	LateTotal               int64 `json:"late_total"`
+	ResetsTotal             int64 `json:"resets_total"`
+}
+
+func (c *Counters) count(e *Event) {
+	switch e.Kind {
+	case KindGap:
+		c.GapsTotal++
+		c.MissingCollectionsTotal += int64(e.Missing)
+	case KindDuplicate:
+		c.DuplicatesTotal++
+	case KindOutOfOrder:
+		c.OutOfOrderTotal++
+	case KindLate:
+		c.LateTotal++
+	case KindReset:
+		c.ResetsTotal++
+	}
+}
+
+type StreamStats struct {
+	NodeID       string `json:"node_id"`
+	Subscription string `json:"subscription"`
+	EncodingPath string `json:"encoding_path"`
+	Counters
+	LastCollectionID uint64    `json:"last_collection_id"`
+	LastSeen         time.Time `json:"last_seen"`
+}
+
+// Stats are the counters of all streams, evicted ones included, and of every tracked stream.
+// Records with an error or without a collection id are counted in UntrackedTotal only.
+type Stats struct {
+	Counters
+	UntrackedTotal      int64         `json:"untracked_total"`
+	StreamsActive       int64         `json:"streams_active"`
+	StreamsEvictedTotal int64         `json:"streams_evicted_total"`
+	EventsDroppedTotal  int64         `json:"events_dropped_total"`
+	Streams             []StreamStats `json:"streams,omitempty"`
+}
+
+type stream struct {
+	counters Counters
+	// last is the newest collection id, lastEnded tells whether its end was seen.
+	last      uint64
+	lastEnded bool
+	// missing holds the ids skipped by gaps within the reorder window, the value is true once
+	// the collection arrived out of order and its end is awaited.
+	missing  map[uint64]bool
+	lastSeen time.Time
+}
+
+func (s *stream) start(id uint64, ended bool) {
+	s.last, s.lastEnded = id, ended
+	s.missing = nil
+	s.counters.CollectionsTotal++
+}
+
+// Tracker detects gaps, duplicates, out of order collections and late messages in the
+// streams of decoded records. Collections are tracked by Telemetry.collection_id per node,
+// subscription and encoding path. A collection may span several messages, it is completed by
+// the message carrying collection_end_time. Tracker is safe for concurrent use.
+type Tracker struct {
+	opts          options
+	mu            sync.Mutex
+	streams       map[StreamKey]*stream
+	totals        Counters
+	untracked     int64
+	evicted       int64
+	lastSweep     time.Time
+	eventsDropped atomic.Int64
+	now           func() time.Time
+}
+
+// New creates a tracker, records are passed to Observe. NewStage runs it as a pipeline stage.
+func New(opts ...Option) (*Tracker, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+
+	return &Tracker{
+		opts:    o,
+		streams: make(map[StreamKey]*stream),
+		now:     time.Now,
+	}, nil
+}
+
+// Observe accounts the record to its stream and returns the anomalies it revealed, if any.
+func (t *Tracker) Observe(r *decoder.Record) []*Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if r == nil || r.Err != nil || r.CollectionID == 0 {
+		t.untracked++
+		return nil
+	}
+	now := t.now()
+	key := StreamKey{NodeID: r.NodeID, Subscription: r.Subscription, EncodingPath: r.EncodingPath}
+	s, ok := t.streams[key]
+	if !ok {
+		t.sweep(now)
+		if t.opts.maxStreams > 0 && len(t.streams) >= t.opts.maxStreams {
+			t.evictLeastRecentlySeen()
+		}
+		s = &stream{}
+		t.streams[key] = s
+	}
+	s.lastSeen = now
+	s.counters.MessagesTotal++
+	t.totals.MessagesTotal++
+
+	var events []*Event
+	emit := func(e *Event) {
+		e.Stream = key
+		e.ProducerAddr = r.ProducerAddr
+		e.CollectionID = r.CollectionID
+		e.Time = now
+		s.counters.count(e)
+		t.totals.count(e)
+		events = append(events, e)
+	}
+	id, ended := r.CollectionID, !r.CollectionEndTime.IsZero()
+	collections := s.counters.CollectionsTotal
+	switch {
+	case !ok:
+		s.start(id, ended)
+	case id == s.last:
+		if s.lastEnded {
+			emit(&Event{Kind: KindDuplicate})
+		}
+		s.lastEnded = s.lastEnded || ended
+	case id > s.last:
+		if id-s.last > 1 {
+			emit(&Event{Kind: KindGap, Expected: s.last + 1, Missing: id - s.last - 1})
+			if s.missing == nil {
+				s.missing = make(map[uint64]bool)
+			}
+			for m := max(s.last+1, id-min(id, t.opts.reorderWindow)); m < id; m++ {
+				s.missing[m] = false
+			}
+		}
+		// Gaps falling out of the reorder window cannot be filled anymore.
+		for m := range s.missing {
+			if id-m > t.opts.reorderWindow {
+				delete(s.missing, m)
+			}
+		}
+		s.last, s.lastEnded = id, ended
+		s.counters.CollectionsTotal++
+	case s.last-id > t.opts.reorderWindow:
+		emit(&Event{Kind: KindReset, Expected: s.last + 1})
+		s.start(id, ended)
+	default:
+		arrived, missing := s.missing[id]
+		switch {
+		case !missing:
+			emit(&Event{Kind: KindDuplicate})
+		case !arrived:
+			emit(&Event{Kind: KindOutOfOrder})
+			s.counters.CollectionsTotal++
+			s.missing[id] = true
+		}
+		if missing && ended {
+			delete(s.missing, id)
+		}
+	}
+	t.totals.CollectionsTotal += s.counters.CollectionsTotal - collections
+
+	if t.opts.lateThreshold > 0 {
+		ts := r.MsgTimestamp
+		if ts.IsZero() {
+			ts = r.CollectionStartTime
+		}
+		received := r.ReceivedAt
+		if received.IsZero() {
+			received = now
+		}
+		if lateness := received.Sub(ts); !ts.IsZero() && lateness > t.opts.lateThreshold {
+			emit(&Event{Kind: KindLate, Lateness: lateness})
+		}
+	}
+
+	return events
+}
+
+// sweep evicts idle streams, at most once per second since it walks the whole map. Must be
+// called with the lock held.
+func (t *Tracker) sweep(now time.Time) {
+	if t.opts.idleTimeout < 0 || now.Sub(t.lastSweep) < time.Second {
+		return
+	}
+	t.lastSweep = now
+	for key, s := range t.streams {
+		if now.Sub(s.lastSeen) >= t.opts.idleTimeout {
+			delete(t.streams, key)
+			t.evicted++
+		}
+	}
+}
+
+// evictLeastRecentlySeen must be called with the lock held.
+func (t *Tracker) evictLeastRecentlySeen() {
+	var oldestKey StreamKey
+	var oldest *stream
+	for key, s := range t.streams {
+		if oldest == nil || s.lastSeen.Before(oldest.lastSeen) {
+			oldestKey, oldest = key, s
+		}
+	}
+	if oldest != nil {
+		delete(t.streams, oldestKey)
+		t.evicted++
+	}
+}
+
+func (t *Tracker) Stats() Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := Stats{
+		Counters:            t.totals,
+		UntrackedTotal:      t.untracked,
+		StreamsActive:       int64(len(t.streams)),
+		StreamsEvictedTotal: t.evicted,
+		EventsDroppedTotal:  t.eventsDropped.Load(),
+		Streams:             make([]StreamStats, 0, len(t.streams)),
+	}
+	for key, st := range t.streams {
+		s.Streams = append(s.Streams, StreamStats{
+			NodeID:           key.NodeID,
+			Subscription:     key.Subscription,
+			EncodingPath:     key.EncodingPath,
+			Counters:         st.counters,
+			LastCollectionID: st.last,
+			LastSeen:         st.lastSeen.UTC(),
+		})
+	}
+	sort.Slice(s.Streams, func(i, j int) bool {
+		a, b := s.Streams[i], s.Streams[j]
+		if a.NodeID != b.NodeID {
+			return a.NodeID < b.NodeID
+		}
+		if a.Subscription != b.Subscription {
+			return a.Subscription < b.Subscription
+		}
+		return a.EncodingPath < b.EncodingPath
+	})
+
+	return s
+}
+
+func (t *Tracker) GetStatsJson() ([]byte, error) {
+	return json.Marshal(t.Stats())
+}
@@ -0,0 +1,140 @@
+package sequence_tracker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sbezverk/tools/telemetry_feeder/decoder"
+)
+
+var start = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// record builds a message of collection id of the rib stream of node, ended marks the message
+// completing the collection.
+func record(node string, id uint64, ended bool) *decoder.Record {
+	r := &decoder.Record{
+		NodeID:       node,
+		Subscription: "rib",
+		EncodingPath: "Cisco-IOS-XR-ip-rib-ipv4-oper:rib",
+		CollectionID: id,
+		MsgTimestamp: start,
+		ReceivedAt:   start.Add(time.Second),
+	}
+	if ended {
+		r.CollectionEndTime = start
+	}
+	return r
+}
+
+func kinds(events []*Event) string {
+	var k []Kind
+	for _, e := range events {
+		k = append(k, e.Kind)
+	}
+	return fmt.Sprint(k)
+}
+
+func TestObserve(t *testing.T) {
+	tr, err := New(WithReorderWindow(3))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, step := range []struct {
+		id    uint64
+		ended bool
+		want  string
+	}{
+		// Collection 1 spans two messages and is completed by the end marker.
+		{id: 1, want: "[]"},
+		{id: 1, want: "[]"},
+		{id: 1, ended: true, want: "[]"},
+		{id: 1, want: "[duplicate]"},
+		{id: 2, ended: true, want: "[]"},
+		// Collections 3 and 4 are skipped, 4 shows up late, 3 never does.
+		{id: 5, ended: true, want: "[gap]"},
+		{id: 4, want: "[out-of-order]"},
+		{id: 4, ended: true, want: "[]"},
+		{id: 4, want: "[duplicate]"},
+		{id: 2, want: "[duplicate]"},
+		// The producer restarted numbering its collections.
+		{id: 1, ended: true, want: "[reset]"},
+		{id: 2, ended: true, want: "[]"},
+	} {
+		if got := kinds(tr.Observe(record("r1", step.id, step.ended))); got != step.want {
+			t.Fatalf("step %d, collection %d: expected %s, got %s", i, step.id, step.want, got)
+		}
+	}
+
+	events := tr.Observe(record("r1", 10, true))
+	if len(events) != 1 || events[0].Expected != 3 || events[0].Missing != 7 || events[0].Stream.NodeID != "r1" {
+		t.Fatalf("unexpected gap events %+v", events)
+	}
+	// Streams are tracked independently and records without a collection id are not tracked.
+	if events := tr.Observe(record("r2", 7, true)); len(events) != 0 {
+		t.Fatalf("unexpected events of a new stream %+v", events)
+	}
+	tr.Observe(record("r2", 0, false))
+	tr.Observe(&decoder.Record{Err: errors.New("failed to decode")})
+
+	s := tr.Stats()
+	if s.MessagesTotal != 14 || s.CollectionsTotal != 8 || s.GapsTotal != 2 || s.MissingCollectionsTotal != 9 ||
+		s.DuplicatesTotal != 3 || s.OutOfOrderTotal != 1 || s.ResetsTotal != 1 || s.UntrackedTotal != 2 || s.StreamsActive != 2 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+	if len(s.Streams) != 2 || s.Streams[0].NodeID != "r1" || s.Streams[0].LastCollectionID != 10 || s.Streams[1].CollectionsTotal != 1 {
+		t.Fatalf("unexpected stream stats %+v", s.Streams)
+	}
+	b, err := tr.GetStatsJson()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(b, &decoded); err != nil || decoded["gaps_total"] != float64(2) {
+		t.Fatalf("unexpected stats JSON %s: %v", b, err)
+	}
+}
+
+func TestRepeatedCollection(t *testing.T) {
+	tr, err := New()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// A completed collection stays completed when its messages are sent again.
+	var events []*Event
+	for _, ended := range []bool{false, true, false, true, false} {
+		events = append(events, tr.Observe(record("r1", 1, ended))...)
+	}
+	if got := kinds(events); got != "[duplicate duplicate duplicate]" {
+		t.Fatalf("unexpected events %s", got)
+	}
+	if s := tr.Stats(); s.DuplicatesTotal != 3 || s.CollectionsTotal != 1 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+}
+
+func TestLateAndEviction(t *testing.T) {
+	tr, err := New(WithLateThreshold(time.Minute), WithMaxStreams(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r := record("r1", 1, true)
+	r.ReceivedAt = start.Add(2 * time.Minute)
+	events := tr.Observe(r)
+	if len(events) != 1 || events[0].Kind != KindLate || events[0].Lateness != 2*time.Minute {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	tr.Observe(record("r2", 1, true))
+	if s := tr.Stats(); s.StreamsActive != 1 || s.StreamsEvictedTotal != 1 || s.LateTotal != 1 || s.Streams[0].NodeID != "r2" {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+
+	if _, err := New(WithReorderWindow(0)); err == nil {
+		t.Fatal("expected an empty reorder window to be rejected")
+	}
+	if _, err := New(WithLateThreshold(-time.Second)); err == nil {
+		t.Fatal("expected a negative late threshold to be rejected")
+	}
+}
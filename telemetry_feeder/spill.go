@@ -0,0 +1,265 @@
+package telemetry_feeder
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StoredAddr is a net.Addr restored from its network and string form, it is used for feeds
+// read back from disk.
+type StoredAddr struct {
+	Net     string
+	Address string
+}
+
+func (a *StoredAddr) Network() string {
+	return a.Net
+}
+
+func (a *StoredAddr) String() string {
+	return a.Address
+}
+
+const (
+	spillFlagErr byte = 1 << iota
+	spillFlagPayload
+	spillFlagAddr
+)
+
+// Spill is a file backed FIFO of feed items used by the spill-to-disk overflow policy.
+// Items read back from the spill carry their error as a plain error string and their
+// producer address as *StoredAddr.
+type Spill struct {
+	mu       sync.Mutex
+	file     *os.File
+	writeOff int64
+	readOff  int64
+	// pending counts items pushed and not yet acknowledged with Done, it includes an item
+	// popped but still being delivered, which keeps new items behind it in order.
+	pending atomic.Int64
+	notify  chan struct{}
+}
+
+// NewSpill creates the spill file in dir, the system temporary directory is used when dir is empty.
+func NewSpill(dir string) (*Spill, error) {
+	f, err := os.CreateTemp(dir, "feed-spill-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spill file: %w", err)
+	}
+	return &Spill{
+		file:   f,
+		notify: make(chan struct{}, 1),
+	}, nil
+}
+
+// Pending returns the number of items pushed and not yet delivered.
+func (s *Spill) Pending() int64 {
+	return s.pending.Load()
+}
+
+func (s *Spill) Push(f *Feed) error {
+	b := encodeSpillRecord(f)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return os.ErrClosed
+	}
+	if _, err := s.file.WriteAt(b, s.writeOff); err != nil {
+		return err
+	}
+	s.writeOff += int64(len(b))
+	s.pending.Add(1)
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Pop returns the oldest item, false is returned when the spill is empty. Every popped item
+// must be acknowledged with Done once delivered.
+func (s *Spill) Pop() (*Feed, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil, false, os.ErrClosed
+	}
+	if s.readOff == s.writeOff {
+		// Fully drained, reclaim the disk space.
+		if s.readOff != 0 {
+			if err := s.file.Truncate(0); err != nil {
+				return nil, false, err
+			}
+			s.readOff, s.writeOff = 0, 0
+		}
+		return nil, false, nil
+	}
+	lb := make([]byte, 4)
+	if _, err := s.file.ReadAt(lb, s.readOff); err != nil {
+		return nil, false, s.discard(err)
+	}
+	b := make([]byte, binary.BigEndian.Uint32(lb))
+	if _, err := s.file.ReadAt(b, s.readOff+4); err != nil {
+		return nil, false, s.discard(err)
+	}
+	s.readOff += int64(4 + len(b))
+	f, err := decodeSpillRecord(b)
+	if err != nil {
+		return nil, true, err
+	}
+
+	return f, true, nil
+}
+
+// discard drops everything in the spill after a read failure, since record boundaries
+// cannot be trusted anymore. Must be called with the lock held and no popped item in flight.
+func (s *Spill) discard(err error) error {
+	s.readOff, s.writeOff = 0, 0
+	lost := s.pending.Swap(0)
+	return errors.Join(fmt.Errorf("failed to read spill file, %d items lost: %w", lost, err), s.file.Truncate(0))
+}
+
+// Done acknowledges delivery of a popped item.
+func (s *Spill) Done() {
+	s.pending.Add(-1)
+}
+
+// Close closes and removes the spill file, items still in the spill are lost.
+func (s *Spill) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	name := s.file.Name()
+	err := s.file.Close()
+	s.file = nil
+
+	return errors.Join(err, os.Remove(name))
+}
+
+// Drain delivers spilled items into out until stopCh is closed, the spill is closed on return.
+// delivered is invoked after every item is placed into out, failed with the number of items
+// lost when the spill cannot be read back.
+func (s *Spill) Drain(out chan *Feed, stopCh <-chan struct{}, delivered func(*Feed), failed func(int64, error)) {
+	defer s.Close()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-s.notify:
+		}
+		for {
+			pending := s.Pending()
+			f, ok, err := s.Pop()
+			if err != nil {
+				if ok {
+					// Only this record is corrupted, carry on with the next one.
+					s.Done()
+					failed(1, err)
+					continue
+				}
+				failed(pending, err)
+				break
+			}
+			if !ok {
+				break
+			}
+			select {
+			case <-stopCh:
+				return
+			case out <- f:
+				s.Done()
+				delivered(f)
+			}
+		}
+	}
+}
+
+func encodeSpillRecord(f *Feed) []byte {
+	var flags byte
+	var errStr, network, address string
+	if f.Err != nil {
+		flags |= spillFlagErr
+		errStr = f.Err.Error()
+	}
+	if f.TelemetryMsg != nil {
+		flags |= spillFlagPayload
+	}
+	if f.ProducerAddr != nil {
+		flags |= spillFlagAddr
+		network = f.ProducerAddr.Network()
+		address = f.ProducerAddr.String()
+	}
+	var receivedAt []byte
+	if !f.ReceivedAt.IsZero() {
+		receivedAt = binary.BigEndian.AppendUint64(nil, uint64(f.ReceivedAt.UnixNano()))
+	}
+	fields := [][]byte{
+		[]byte(network), []byte(address), []byte(errStr),
+		[]byte(f.Transport), []byte(f.Encoding), []byte(f.Framing), []byte(f.Source),
+		f.TelemetryMsg, []byte(f.PeerSubject), receivedAt,
+	}
+	l := 1
+	for _, field := range fields {
+		l += 4 + len(field)
+	}
+	b := make([]byte, 4, 4+l)
+	binary.BigEndian.PutUint32(b, uint32(l))
+	b = append(b, flags)
+	for _, field := range fields {
+		b = binary.BigEndian.AppendUint32(b, uint32(len(field)))
+		b = append(b, field...)
+	}
+
+	return b
+}
+
+func decodeSpillRecord(b []byte) (*Feed, error) {
+	if len(b) < 1 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	flags := b[0]
+	b = b[1:]
+	fields := make([][]byte, 10)
+	for i := range fields {
+		if len(b) < 4 {
+			return nil, fmt.Errorf("corrupted spill record: %w", io.ErrUnexpectedEOF)
+		}
+		l := binary.BigEndian.Uint32(b)
+		b = b[4:]
+		if uint64(l) > uint64(len(b)) {
+			return nil, fmt.Errorf("corrupted spill record: %w", io.ErrUnexpectedEOF)
+		}
+		fields[i] = b[:l]
+		b = b[l:]
+	}
+	f := &Feed{
+		Transport:   Transport(fields[3]),
+		Encoding:    PayloadEncoding(fields[4]),
+		Framing:     Framing(fields[5]),
+		Source:      string(fields[6]),
+		PeerSubject: string(fields[8]),
+	}
+	if flags&spillFlagAddr != 0 {
+		f.ProducerAddr = &StoredAddr{Net: string(fields[0]), Address: string(fields[1])}
+	}
+	if flags&spillFlagErr != 0 {
+		f.Err = errors.New(string(fields[2]))
+	}
+	if len(fields[9]) == 8 {
+		f.ReceivedAt = time.Unix(0, int64(binary.BigEndian.Uint64(fields[9])))
+	}
+	if flags&spillFlagPayload != 0 {
+		f.TelemetryMsg = fields[7]
+	}
+
+	return f, nil
+}
@@ -0,0 +1,85 @@
+package tcp_feeder
+
+import (
+	"fmt"
+
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+)
+
+type options struct {
+	queueCapacity int
+	maxMsgSize    int
+	overflow      feeder.OverflowConfig
+	producers     feeder.ProducerStatsConfig
+	pool          *feeder.BufferPool
+	logger        feeder.Logger
+}
+
+// Option customizes the TCP feeder created by NewWithOptions.
+type Option func(*options)
+
+func defaultOptions() options {
+	return options{
+		queueCapacity: feedQueueCapacity,
+		maxMsgSize:    MaxRcvMsgSize,
+		overflow:      feeder.OverflowConfig{Policy: feeder.OverflowBlock},
+		logger:        feeder.GlogLogger(),
+	}
+}
+
+// WithQueueCapacity sets the number of items the feed channel buffers.
+func WithQueueCapacity(n int) Option {
+	return func(o *options) {
+		o.queueCapacity = n
+	}
+}
+
+// WithMaxMsgSize sets the size of the receive buffer, longer frames terminate the connection.
+func WithMaxMsgSize(n int) Option {
+	return func(o *options) {
+		o.maxMsgSize = n
+	}
+}
+
+// WithOverflow sets the feed queue overflow policy.
+func WithOverflow(cfg feeder.OverflowConfig) Option {
+	return func(o *options) {
+		o.overflow = cfg
+	}
+}
+
+// WithProducerStats sets the eviction policy of the per-producer stats.
+func WithProducerStats(cfg feeder.ProducerStatsConfig) Option {
+	return func(o *options) {
+		o.producers = cfg
+	}
+}
+
+// WithBufferPool backs the payload of every feed with a buffer of pool, consumers return it
+// with Feed.Release once done with the payload.
+func WithBufferPool(pool *feeder.BufferPool) Option {
+	return func(o *options) {
+		o.pool = pool
+	}
+}
+
+// WithLogger sets the logger, glog is used by default.
+func WithLogger(l feeder.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+func (o *options) validate() error {
+	if o.queueCapacity < 0 {
+		return fmt.Errorf("invalid feed queue capacity %d", o.queueCapacity)
+	}
+	if o.maxMsgSize <= 0 {
+		return fmt.Errorf("invalid maximum message size %d", o.maxMsgSize)
+	}
+	if o.logger == nil {
+		o.logger = feeder.NopLogger()
+	}
+
+	return nil
+}
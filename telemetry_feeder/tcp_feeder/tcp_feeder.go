@@ -0,0 +1,377 @@
+package tcp_feeder
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+)
+
+const (
+	MaxRcvMsgSize     = 1024 * 1024 * 4
+	feedQueueCapacity = 1024 * 10
+	connReadBufSize   = 1024 * 64
+)
+
+type tcpFeeder struct {
+	listener                    net.Listener
+	stopCh                      chan struct{}
+	stopOnce                    sync.Once
+	drainCh                     chan struct{}
+	drainOnce                   sync.Once
+	workers                     sync.WaitGroup
+	queue                       *feeder.FeedQueue
+	events                      *feeder.EventQueue
+	producers                   *feeder.ProducerTracker
+	pool                        *feeder.BufferPool
+	logger                      feeder.Logger
+	maxMsgSize                  int
+	mu                          sync.Mutex
+	conns                       map[net.Conn]struct{}
+	startTime                   time.Time
+	messagesReceivedTotal       atomic.Int64
+	payloadBytesReceivedTotal   atomic.Int64
+	transportBytesReceivedTotal atomic.Int64
+	receiveErrorsTotal          atomic.Int64
+	receiveTimeoutErrorsTotal   atomic.Int64
+	receiveClosedTotal          atomic.Int64
+	receiveOtherErrorsTotal     atomic.Int64
+}
+
+func (srv *tcpFeeder) GetFeed() chan *feeder.Feed {
+	return srv.queue.Feed()
+}
+
+func (srv *tcpFeeder) Events() <-chan *feeder.Event {
+	return srv.events.Events()
+}
+
+// Stopped is closed once the feeder is stopped, by Stop or when the listener is closed under it.
+func (srv *tcpFeeder) Stopped() <-chan struct{} {
+	return srv.stopCh
+}
+
+func (srv *tcpFeeder) Stop() {
+	srv.stopOnce.Do(func() {
+		close(srv.stopCh)
+		srv.listener.Close()
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		for conn := range srv.conns {
+			conn.Close()
+		}
+	})
+}
+
+// StopGracefully closes the listener and the connections, frames already read are published
+// and the consumer is given until ctx is done to take the queued items before the feed channel
+// is closed.
+func (srv *tcpFeeder) StopGracefully(ctx context.Context) error {
+	srv.drainOnce.Do(func() {
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		close(srv.drainCh)
+		srv.listener.Close()
+		for conn := range srv.conns {
+			conn.Close()
+		}
+	})
+	err := feeder.WaitContext(ctx, &srv.workers)
+	if err == nil {
+		err = srv.queue.Drain(ctx)
+	}
+	srv.Stop()
+	srv.workers.Wait()
+	srv.queue.Close()
+	srv.events.Close()
+
+	return err
+}
+
+func (srv *tcpFeeder) statsSnapshot() feeder.StatsSnapshot {
+	snapshot := feeder.StatsSnapshot{
+		Transport:                   "tcp",
+		StartTime:                   srv.startTime.UTC(),
+		UptimeSeconds:               int64(time.Since(srv.startTime).Seconds()),
+		MessagesReceivedTotal:       srv.messagesReceivedTotal.Load(),
+		PayloadBytesReceivedTotal:   srv.payloadBytesReceivedTotal.Load(),
+		TransportBytesReceivedTotal: srv.transportBytesReceivedTotal.Load(),
+		ReceiveErrorsTotal:          srv.receiveErrorsTotal.Load(),
+		ReceiveTimeoutErrorsTotal:   srv.receiveTimeoutErrorsTotal.Load(),
+		ReceiveClosedTotal:          srv.receiveClosedTotal.Load(),
+		ReceiveOtherErrorsTotal:     srv.receiveOtherErrorsTotal.Load(),
+	}
+	srv.queue.FillStats(&snapshot)
+	srv.producers.FillStats(&snapshot)
+	srv.events.FillStats(&snapshot)
+
+	return snapshot
+}
+
+func (srv *tcpFeeder) GetStatsJson() ([]byte, error) {
+	snapshot := srv.statsSnapshot()
+	return json.Marshal(snapshot)
+}
+
+func classifyReceiveError(err error) string {
+	if err == nil {
+		return "none"
+	}
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return "timeout"
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+		return "closed"
+	}
+	return "other"
+}
+
+func (srv *tcpFeeder) publishFeed(item *feeder.Feed) bool {
+	return srv.queue.Publish(item)
+}
+
+// New starts a TCP listener accepting Cisco XR dial-out connections, every connection carries
+// a stream of messages framed with the 12 bytes XR streaming telemetry header.
+func New(addr string) (feeder.Feeder, error) {
+	return NewWithOptions(addr)
+}
+
+// NewWithOverflow creates TCP feeder with the feed queue overflow policy defined by cfg.
+func NewWithOverflow(addr string, cfg feeder.OverflowConfig) (feeder.Feeder, error) {
+	return NewWithOptions(addr, WithOverflow(cfg))
+}
+
+// NewWithOptions creates TCP feeder, settings not customized by opts keep the defaults used by New.
+func NewWithOptions(addr string, opts ...Option) (feeder.Feeder, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	stopCh := make(chan struct{})
+	queue, err := feeder.NewFeedQueue(o.queueCapacity, stopCh, o.overflow)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	events := feeder.NewEventQueue(feeder.TransportTCP)
+	queue.SetEvents(events)
+	srv := &tcpFeeder{
+		listener:   listener,
+		stopCh:     stopCh,
+		drainCh:    make(chan struct{}),
+		queue:      queue,
+		events:     events,
+		producers:  feeder.NewProducerTracker(o.producers),
+		pool:       o.pool,
+		logger:     o.logger,
+		maxMsgSize: o.maxMsgSize,
+		conns:      make(map[net.Conn]struct{}),
+		startTime:  time.Now(),
+	}
+
+	srv.workers.Add(1)
+	go srv.acceptor()
+
+	return srv, nil
+}
+
+func (srv *tcpFeeder) acceptor() {
+	defer srv.workers.Done()
+	for {
+		conn, err := srv.listener.Accept()
+		if err != nil {
+			select {
+			case <-srv.stopCh:
+				return
+			case <-srv.drainCh:
+				return
+			default:
+			}
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				continue
+			}
+			if errors.Is(err, net.ErrClosed) {
+				srv.logger.Errorf("TCP listener %s has been closed, stop accepting connections", srv.listener.Addr())
+				srv.Stop()
+				return
+			}
+			srv.publishFeed(&feeder.Feed{
+				ProducerAddr: srv.listener.Addr(),
+				Err:          fmt.Errorf("failed to accept connection: %w", err),
+				Transport:    feeder.TransportTCP,
+				Framing:      feeder.FramingCiscoXRST,
+			})
+			continue
+		}
+		if !srv.trackConn(conn) {
+			conn.Close()
+			return
+		}
+		go srv.worker(conn)
+	}
+}
+
+// trackConn registers the connection so Stop can close it, false is returned when the feeder
+// is already stopped or stopping gracefully.
+func (srv *tcpFeeder) trackConn(conn net.Conn) bool {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	select {
+	case <-srv.stopCh:
+		return false
+	case <-srv.drainCh:
+		return false
+	default:
+	}
+	srv.conns[conn] = struct{}{}
+	srv.workers.Add(1)
+	return true
+}
+
+func (srv *tcpFeeder) untrackConn(conn net.Conn) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	delete(srv.conns, conn)
+}
+
+func (srv *tcpFeeder) worker(conn net.Conn) {
+	defer srv.workers.Done()
+	defer func() {
+		srv.untrackConn(conn)
+		conn.Close()
+	}()
+	producer := conn.RemoteAddr()
+	srv.logger.Infof("TCP dial-out connection from %s accepted", producer)
+	srv.producers.SessionStarted(producer)
+	defer srv.producers.SessionEnded(producer)
+	srv.events.ProducerConnected(producer)
+	reason := feeder.ErrFeederStopped
+	defer func() {
+		srv.events.ProducerDisconnected(producer, reason)
+	}()
+	// bufio takes care of several frames arriving in a single segment, io.ReadFull of a frame
+	// spread over several segments.
+	r := bufio.NewReaderSize(conn, connReadBufSize)
+	hdr := make([]byte, feeder.XRSTHeaderLength)
+	for {
+		f, err := srv.readFrame(r, hdr, producer)
+		if err != nil {
+			errClass := classifyReceiveError(err)
+			switch errClass {
+			case "timeout":
+				srv.receiveErrorsTotal.Add(1)
+				srv.receiveTimeoutErrorsTotal.Add(1)
+			case "closed":
+				srv.receiveErrorsTotal.Add(1)
+				srv.receiveClosedTotal.Add(1)
+			case "other":
+				srv.receiveErrorsTotal.Add(1)
+				srv.receiveOtherErrorsTotal.Add(1)
+			}
+			select {
+			case <-srv.stopCh:
+				return
+			case <-srv.drainCh:
+				return
+			default:
+			}
+			// A connection closed by the producer on a frame boundary is not an error of it.
+			if errors.Is(err, io.EOF) {
+				err = fmt.Errorf("connection with peer %s has been closed cleanly: %w", producer.String(), io.EOF)
+			} else {
+				srv.producers.Error(producer)
+				err = fmt.Errorf("connection with peer %s has been terminated with the error: %w", producer.String(), err)
+			}
+			srv.logger.Infof("TCP dial-out connection from %s closed: %v", producer, err)
+			reason = err
+			srv.publishFeed(&feeder.Feed{
+				ProducerAddr: producer,
+				Err:          err,
+				Transport:    feeder.TransportTCP,
+				Framing:      feeder.FramingCiscoXRST,
+			})
+			return
+		}
+		if f == nil {
+			// Heartbeat, nothing to publish
+			continue
+		}
+		if !srv.publishFeed(f) {
+			return
+		}
+	}
+}
+
+// readFrame reads a single XR ST framed message from the stream, nil Feed is returned for
+// heartbeat messages. Any error is fatal for the connection since after it the stream cannot be
+// resynchronized on a frame boundary.
+func (srv *tcpFeeder) readFrame(r io.Reader, hdr []byte, producer net.Addr) (*feeder.Feed, error) {
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, err
+	}
+	h, err := feeder.ParseXRSTHeader(hdr)
+	if err != nil {
+		srv.events.FramingError(producer, err)
+		return nil, err
+	}
+	if uint64(h.Length) > uint64(srv.maxMsgSize) {
+		err := fmt.Errorf("Cisco XR ST framed message length %d exceeds maximum %d", h.Length, srv.maxMsgSize)
+		srv.events.FramingError(producer, err)
+		return nil, err
+	}
+	f := &feeder.Feed{}
+	f.AllocTelemetryMsg(int(h.Length), srv.pool)
+	payload := f.TelemetryMsg
+	if _, err := io.ReadFull(r, payload); err != nil {
+		f.Release()
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	receivedAt := time.Now()
+	srv.transportBytesReceivedTotal.Add(int64(feeder.XRSTHeaderLength + len(payload)))
+	if h.MsgType == feeder.XRSTMsgTypeHeartbeat {
+		f.Release()
+		return nil, nil
+	}
+	srv.messagesReceivedTotal.Add(1)
+	encoding, err := h.PayloadEncoding(payload)
+	if err != nil {
+		// The frame boundary is still known, report the message and carry on with the stream.
+		srv.producers.Received(producer, 0, feeder.XRSTHeaderLength+len(payload))
+		srv.producers.Error(producer)
+		srv.events.FramingError(producer, err)
+		f.Release()
+		return &feeder.Feed{
+			ProducerAddr: producer,
+			Err:          err,
+			Transport:    feeder.TransportTCP,
+			Framing:      feeder.FramingCiscoXRST,
+		}, nil
+	}
+	srv.payloadBytesReceivedTotal.Add(int64(len(payload)))
+	srv.producers.Received(producer, len(payload), feeder.XRSTHeaderLength+len(payload))
+	f.ProducerAddr = producer
+	f.Transport = feeder.TransportTCP
+	f.Encoding = encoding
+	f.Framing = feeder.FramingCiscoXRST
+	f.ReceivedAt = receivedAt
+
+	return f, nil
+}
@@ -0,0 +1,353 @@
+package tcp_feeder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+)
+
+func TestFramesSplitAcrossWrites(t *testing.T) {
+	f, addr := newTestFeeder(t)
+	defer f.Stop()
+
+	conn := newTCPClient(t, addr)
+	defer conn.Close()
+
+	payload := []byte(`{"encoding_path":"rib","data":[{"vrfName":"default"}]}`)
+	frame := makeFrame(feeder.XRSTMsgTypeTelemetryData, feeder.XRSTEncodingJSON, payload)
+	for _, chunk := range [][]byte{frame[:5], frame[5:20], frame[20:]} {
+		if _, err := conn.Write(chunk); err != nil {
+			t.Fatalf("failed to write frame chunk: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	got := receiveFeed(t, f)
+	if got.Err != nil {
+		t.Fatalf("expected nil error, got %v", got.Err)
+	}
+	if !bytes.Equal(got.TelemetryMsg, payload) {
+		t.Fatalf("payload mismatch, want %q got %q", payload, got.TelemetryMsg)
+	}
+	if got.Encoding != feeder.EncodingJSON {
+		t.Fatalf("encoding mismatch, want %q got %q", feeder.EncodingJSON, got.Encoding)
+	}
+	if got.Framing != feeder.FramingCiscoXRST {
+		t.Fatalf("framing mismatch, want %q got %q", feeder.FramingCiscoXRST, got.Framing)
+	}
+	if got.Transport != feeder.TransportTCP {
+		t.Fatalf("transport mismatch, want %q got %q", feeder.TransportTCP, got.Transport)
+	}
+	if got.ProducerAddr == nil {
+		t.Fatal("expected producer address to be populated")
+	}
+}
+
+func TestMultipleFramesInSingleWrite(t *testing.T) {
+	f, addr := newTestFeeder(t)
+	defer f.Stop()
+
+	conn := newTCPClient(t, addr)
+	defer conn.Close()
+
+	gpbPayload := []byte{0x0a, 0x03, 'r', 't', 'r'}
+	jsonPayload := []byte(`{"encoding_path":"rib"}`)
+	var b []byte
+	b = append(b, makeFrame(feeder.XRSTMsgTypeTelemetryData, feeder.XRSTEncodingGPBKV, gpbPayload)...)
+	b = append(b, makeFrame(feeder.XRSTMsgTypeHeartbeat, feeder.XRSTEncodingUnset, nil)...)
+	b = append(b, makeFrame(feeder.XRSTMsgTypeTelemetryData, feeder.XRSTEncodingJSON, jsonPayload)...)
+	if _, err := conn.Write(b); err != nil {
+		t.Fatalf("failed to write frames: %v", err)
+	}
+
+	first := receiveFeed(t, f)
+	if first.Err != nil || first.Encoding != feeder.EncodingGPB || !bytes.Equal(first.TelemetryMsg, gpbPayload) {
+		t.Fatalf("unexpected first feed: %+v", first)
+	}
+	second := receiveFeed(t, f)
+	if second.Err != nil || second.Encoding != feeder.EncodingJSON || !bytes.Equal(second.TelemetryMsg, jsonPayload) {
+		t.Fatalf("unexpected second feed: %+v", second)
+	}
+
+	stats := f.statsSnapshot()
+	if stats.MessagesReceivedTotal != 2 {
+		t.Fatalf("messages_received_total mismatch, want 2 got %d", stats.MessagesReceivedTotal)
+	}
+	if stats.PayloadBytesReceivedTotal != int64(len(gpbPayload)+len(jsonPayload)) {
+		t.Fatalf("payload_bytes_received_total mismatch, want %d got %d", len(gpbPayload)+len(jsonPayload), stats.PayloadBytesReceivedTotal)
+	}
+	if stats.TransportBytesReceivedTotal != int64(len(b)) {
+		t.Fatalf("transport_bytes_received_total mismatch, want %d got %d", len(b), stats.TransportBytesReceivedTotal)
+	}
+}
+
+func TestConcurrentConnections(t *testing.T) {
+	f, addr := newTestFeeder(t)
+	defer f.Stop()
+
+	const producers = 5
+	for i := 0; i < producers; i++ {
+		conn := newTCPClient(t, addr)
+		defer conn.Close()
+		payload := []byte(`{"producer":` + string(rune('0'+i)) + `}`)
+		if _, err := conn.Write(makeFrame(feeder.XRSTMsgTypeTelemetryData, feeder.XRSTEncodingJSON, payload)); err != nil {
+			t.Fatalf("failed to write frame: %v", err)
+		}
+	}
+	seen := make(map[string]bool)
+	for i := 0; i < producers; i++ {
+		got := receiveFeed(t, f)
+		if got.Err != nil {
+			t.Fatalf("expected nil error, got %v", got.Err)
+		}
+		seen[got.ProducerAddr.String()] = true
+	}
+	if len(seen) != producers {
+		t.Fatalf("expected feeds from %d producers, got %d", producers, len(seen))
+	}
+}
+
+func TestPeerCloseAndTruncatedFrame(t *testing.T) {
+	f, addr := newTestFeeder(t)
+	defer f.Stop()
+
+	conn := newTCPClient(t, addr)
+	clean := conn.LocalAddr().String()
+	conn.Close()
+	got := receiveFeed(t, f)
+	if !errors.Is(got.Err, io.EOF) {
+		t.Fatalf("expected io.EOF error feed, got %v", got.Err)
+	}
+
+	conn = newTCPClient(t, addr)
+	truncated := conn.LocalAddr().String()
+	frame := makeFrame(feeder.XRSTMsgTypeTelemetryData, feeder.XRSTEncodingJSON, []byte(`{"truncated":true}`))
+	if _, err := conn.Write(frame[:len(frame)-3]); err != nil {
+		t.Fatalf("failed to write frame: %v", err)
+	}
+	conn.Close()
+	got = receiveFeed(t, f)
+	if !errors.Is(got.Err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected io.ErrUnexpectedEOF error feed, got %v", got.Err)
+	}
+
+	stats := f.statsSnapshot()
+	if stats.ReceiveClosedTotal != 1 || stats.ReceiveOtherErrorsTotal != 1 {
+		t.Fatalf("unexpected receive error counters: closed %d other %d", stats.ReceiveClosedTotal, stats.ReceiveOtherErrorsTotal)
+	}
+	// A clean close is not an error of the producer.
+	if p, ok := stats.Producers[clean]; !ok || p.ReceiveErrorsTotal != 0 || stats.Producers[truncated].ReceiveErrorsTotal != 1 {
+		t.Fatalf("unexpected per-producer stats %+v", stats.Producers)
+	}
+}
+
+func TestOversizedFrameTerminatesConnection(t *testing.T) {
+	f, addr := newTestFeeder(t)
+	defer f.Stop()
+
+	conn := newTCPClient(t, addr)
+	defer conn.Close()
+	hdr := make([]byte, feeder.XRSTHeaderLength)
+	binary.BigEndian.PutUint16(hdr[0:2], uint16(feeder.XRSTMsgTypeTelemetryData))
+	binary.BigEndian.PutUint32(hdr[8:12], MaxRcvMsgSize+1)
+	if _, err := conn.Write(hdr); err != nil {
+		t.Fatalf("failed to write header: %v", err)
+	}
+	got := receiveFeed(t, f)
+	if got.Err == nil {
+		t.Fatal("expected error feed for oversized frame")
+	}
+}
+
+func TestEvents(t *testing.T) {
+	f, addr := newTestFeeder(t)
+	defer f.Stop()
+
+	conn := newTCPClient(t, addr)
+	defer conn.Close()
+	hdr := make([]byte, feeder.XRSTHeaderLength)
+	binary.BigEndian.PutUint16(hdr[0:2], uint16(feeder.XRSTMsgTypeTelemetryData))
+	binary.BigEndian.PutUint32(hdr[8:12], MaxRcvMsgSize+1)
+	if _, err := conn.Write(hdr); err != nil {
+		t.Fatalf("failed to write header: %v", err)
+	}
+	// A second producer disconnecting cleanly.
+	clean := newTCPClient(t, addr)
+	clean.Close()
+
+	events := map[string][]*feeder.Event{}
+	for n := 0; n < 5; n++ {
+		select {
+		case e := <-f.Events():
+			if e.Transport != feeder.TransportTCP || e.Time.IsZero() {
+				t.Fatalf("unexpected event %+v", e)
+			}
+			key := e.ProducerAddr.String()
+			events[key] = append(events[key], e)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out after %d events", n)
+		}
+	}
+	framed := events[conn.LocalAddr().String()]
+	if len(framed) != 3 || framed[0].Type != feeder.EventProducerConnected || framed[1].Type != feeder.EventFramingError ||
+		framed[2].Type != feeder.EventProducerDisconnected || !strings.Contains(framed[2].Err.Error(), "exceeds maximum") {
+		t.Fatalf("unexpected events of the oversized frame producer %+v", framed)
+	}
+	closed := events[clean.LocalAddr().String()]
+	if len(closed) != 2 || closed[0].Type != feeder.EventProducerConnected || closed[1].Type != feeder.EventProducerDisconnected ||
+		!errors.Is(closed[1].Err, io.EOF) {
+		t.Fatalf("unexpected events of the clean producer %+v", closed)
+	}
+}
+
+func TestMaxMsgSizeOption(t *testing.T) {
+	f, addr := newTestFeeder(t, WithMaxMsgSize(16), WithQueueCapacity(3), WithLogger(feeder.NopLogger()))
+	defer f.Stop()
+
+	if stats := f.statsSnapshot(); stats.FeedQueueCapacity != 3 {
+		t.Fatalf("feed_queue_capacity mismatch, want 3 got %d", stats.FeedQueueCapacity)
+	}
+	conn := newTCPClient(t, addr)
+	defer conn.Close()
+	payload := []byte(`{"encoding_path":"rib"}`)
+	if _, err := conn.Write(makeFrame(feeder.XRSTMsgTypeTelemetryData, feeder.XRSTEncodingJSON, payload)); err != nil {
+		t.Fatalf("failed to write frame: %v", err)
+	}
+	got := receiveFeed(t, f)
+	if got.Err == nil || !strings.Contains(got.Err.Error(), "exceeds maximum 16") {
+		t.Fatalf("expected oversized frame error, got %v", got.Err)
+	}
+}
+
+func TestStatsJson(t *testing.T) {
+	f, _ := newTestFeeder(t)
+	defer f.Stop()
+
+	b, err := f.GetStatsJson()
+	if err != nil {
+		t.Fatalf("failed to marshal stats: %v", err)
+	}
+	var stats feeder.StatsSnapshot
+	if err := json.Unmarshal(b, &stats); err != nil {
+		t.Fatalf("failed to unmarshal stats: %v", err)
+	}
+	if stats.Transport != "tcp" {
+		t.Fatalf("transport mismatch, want tcp got %q", stats.Transport)
+	}
+	if stats.FeedQueueCapacity != feedQueueCapacity {
+		t.Fatalf("feed_queue_capacity mismatch, want %d got %d", feedQueueCapacity, stats.FeedQueueCapacity)
+	}
+}
+
+func TestStopClosesConnections(t *testing.T) {
+	f, addr := newTestFeeder(t)
+
+	conn := newTCPClient(t, addr)
+	defer conn.Close()
+	// Make sure the connection has been accepted before stopping.
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		f.mu.Lock()
+		n := len(f.conns)
+		f.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		f.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop did not return in time")
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Fatal("expected connection to be closed by Stop")
+	}
+	select {
+	case got := <-f.GetFeed():
+		t.Fatalf("expected no feed items after stop, got %+v", got)
+	case <-time.After(200 * time.Millisecond):
+	}
+	f.workers.Wait()
+	if p, ok := f.statsSnapshot().Producers[conn.LocalAddr().String()]; !ok || p.ReceiveErrorsTotal != 0 || p.ActiveSessions != 0 {
+		t.Fatalf("expected no producer error after stop, got %+v", p)
+	}
+}
+
+func makeFrame(msgType feeder.XRSTMsgType, encoding feeder.XRSTEncoding, payload []byte) []byte {
+	b := make([]byte, feeder.XRSTHeaderLength+len(payload))
+	binary.BigEndian.PutUint16(b[0:2], uint16(msgType))
+	binary.BigEndian.PutUint16(b[2:4], uint16(encoding))
+	binary.BigEndian.PutUint16(b[4:6], 1)
+	binary.BigEndian.PutUint32(b[8:12], uint32(len(payload)))
+	copy(b[feeder.XRSTHeaderLength:], payload)
+	return b
+}
+
+func receiveFeed(t *testing.T, f *tcpFeeder) *feeder.Feed {
+	t.Helper()
+
+	select {
+	case got := <-f.GetFeed():
+		if got == nil {
+			t.Fatal("expected feed item, got nil")
+		}
+		return got
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for feed")
+	}
+	return nil
+}
+
+func newTestFeeder(t *testing.T, opts ...Option) (*tcpFeeder, string) {
+	t.Helper()
+
+	fdr, err := NewWithOptions("127.0.0.1:0", opts...)
+	if err != nil {
+		if isSocketPermissionError(err) {
+			t.Skipf("tcp sockets are not available in this environment: %v", err)
+		}
+		t.Fatalf("failed to create feeder: %v", err)
+	}
+	f, ok := fdr.(*tcpFeeder)
+	if !ok {
+		t.Fatalf("unexpected feeder type %T", fdr)
+	}
+
+	return f, f.listener.Addr().String()
+}
+
+func newTCPClient(t *testing.T, addr string) net.Conn {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		if isSocketPermissionError(err) {
+			t.Skipf("tcp sockets are not available in this environment: %v", err)
+		}
+		t.Fatalf("failed to connect to feeder: %v", err)
+	}
+
+	return conn
+}
+
+func isSocketPermissionError(err error) bool {
+	return os.IsPermission(err) || strings.Contains(err.Error(), "operation not permitted")
+}
@@ -0,0 +1,122 @@
+package udp_feeder
+
+import (
+	"fmt"
+
+	feeder "github.com/sbezverk/tools/telemetry_feeder"
+)
+
+type options struct {
+	queueCapacity int
+	maxMsgSize    int
+	readBuffer    int
+	readers       int
+	batchSize     int
+	overflow      feeder.OverflowConfig
+	producers     feeder.ProducerStatsConfig
+	pool          *feeder.BufferPool
+	logger        feeder.Logger
+}
+
+// Option customizes the UDP feeder created by NewWithOptions.
+type Option func(*options)
+
+func defaultOptions() options {
+	return options{
+		queueCapacity: feedQueueCapacity,
+		maxMsgSize:    MaxRcvMsgSize,
+		readers:       1,
+		batchSize:     1,
+		overflow:      feeder.OverflowConfig{Policy: feeder.OverflowBlock},
+		logger:        feeder.GlogLogger(),
+	}
+}
+
+// WithQueueCapacity sets the number of items the feed channel buffers.
+func WithQueueCapacity(n int) Option {
+	return func(o *options) {
+		o.queueCapacity = n
+	}
+}
+
+// WithMaxMsgSize sets the size of the receive buffer, longer datagrams are truncated.
+func WithMaxMsgSize(n int) Option {
+	return func(o *options) {
+		o.maxMsgSize = n
+	}
+}
+
+// WithReadBuffer sets SO_RCVBUF of the socket, the kernel default is used when not set.
+func WithReadBuffer(bytes int) Option {
+	return func(o *options) {
+		o.readBuffer = bytes
+	}
+}
+
+// WithReaders sets the number of reader goroutines. Each gets its own socket bound with
+// SO_REUSEPORT where the platform supports it, otherwise all share one socket.
+func WithReaders(n int) Option {
+	return func(o *options) {
+		o.readers = n
+	}
+}
+
+// WithBatchSize sets the number of datagrams a reader receives per system call, recvmmsg is
+// used on Linux. Each reader allocates n buffers of up to 64 KB.
+func WithBatchSize(n int) Option {
+	return func(o *options) {
+		o.batchSize = n
+	}
+}
+
+// WithOverflow sets the feed queue overflow policy.
+func WithOverflow(cfg feeder.OverflowConfig) Option {
+	return func(o *options) {
+		o.overflow = cfg
+	}
+}
+
+// WithProducerStats sets the eviction policy of the per-producer stats.
+func WithProducerStats(cfg feeder.ProducerStatsConfig) Option {
+	return func(o *options) {
+		o.producers = cfg
+	}
+}
+
+// WithBufferPool backs the payload of every feed with a buffer of pool, consumers return it
+// with Feed.Release once done with the payload.
+func WithBufferPool(pool *feeder.BufferPool) Option {
+	return func(o *options) {
+		o.pool = pool
+	}
+}
+
+// WithLogger sets the logger, glog is used by default.
+func WithLogger(l feeder.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+func (o *options) validate() error {
+	if o.queueCapacity < 0 {
+		return fmt.Errorf("invalid feed queue capacity %d", o.queueCapacity)
+	}
+	if o.maxMsgSize <= 0 {
+		return fmt.Errorf("invalid maximum message size %d", o.maxMsgSize)
+	}
+	if o.readBuffer < 0 {
+		return fmt.Errorf("invalid socket read buffer size %d", o.readBuffer)
+	}
+	if o.readers <= 0 {
+		return fmt.Errorf("invalid number of readers %d", o.readers)
+	}
+	if o.batchSize <= 0 {
+		return fmt.Errorf("invalid batch size %d", o.batchSize)
+	}
+	if o.logger == nil {
+		o.logger = feeder.NopLogger()
+	}
+
+	return nil
+}
@@ -1,33 +1,46 @@
 package udp_feeder
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	feeder "github.com/sbezverk/tools/telemetry_feeder"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
 )
 
 const (
 	MaxRcvMsgSize     = 1024 * 1024 * 4
 	feedQueueCapacity = 1024 * 25
+	// maxDatagramSize is the largest UDP payload, batch buffers need not be larger.
+	maxDatagramSize = 0xffff
 )
 
 type udpFeeder struct {
-	conn                        *net.UDPConn
+	conns                       []*net.UDPConn
+	readers                     int
+	batchSize                   int
 	stopCh                      chan struct{}
-	feed                        chan *feeder.Feed
+	stopOnce                    sync.Once
+	drainCh                     chan struct{}
+	drainOnce                   sync.Once
+	workers                     sync.WaitGroup
+	queue                       *feeder.FeedQueue
+	events                      *feeder.EventQueue
+	producers                   *feeder.ProducerTracker
+	pool                        *feeder.BufferPool
+	logger                      feeder.Logger
+	maxMsgSize                  int
 	startTime                   time.Time
 	messagesReceivedTotal       atomic.Int64
 	payloadBytesReceivedTotal   atomic.Int64
 	transportBytesReceivedTotal atomic.Int64
-	feedItemsEnqueuedTotal      atomic.Int64
-	feedErrorItemsEnqueuedTotal atomic.Int64
-	feedQueueDepthMax           atomic.Int64
-	feedPublishBlockNanosTotal  atomic.Int64
-	feedPublishBlockNanosMax    atomic.Int64
 	receiveErrorsTotal          atomic.Int64
 	receiveTimeoutErrorsTotal   atomic.Int64
 	receiveClosedTotal          atomic.Int64
@@ -35,34 +48,69 @@ type udpFeeder struct {
 }
 
 func (srv *udpFeeder) GetFeed() chan *feeder.Feed {
-	return srv.feed
+	return srv.queue.Feed()
+}
+
+// Events delivers framing errors and queue overflows, UDP has no producer sessions.
+func (srv *udpFeeder) Events() <-chan *feeder.Event {
+	return srv.events.Events()
+}
+
+// Stopped is closed once the feeder is stopped, by Stop or when a socket is closed under it.
+func (srv *udpFeeder) Stopped() <-chan struct{} {
+	return srv.stopCh
 }
 
 func (srv *udpFeeder) Stop() {
-	close(srv.stopCh)
-	srv.conn.Close()
+	srv.stopOnce.Do(func() {
+		close(srv.stopCh)
+		srv.closeConns()
+	})
+}
+
+// StopGracefully closes the sockets, datagrams already read are published and the consumer is
+// given until ctx is done to take the queued items before the feed channel is closed.
+func (srv *udpFeeder) StopGracefully(ctx context.Context) error {
+	srv.drainOnce.Do(func() {
+		close(srv.drainCh)
+		srv.closeConns()
+	})
+	err := feeder.WaitContext(ctx, &srv.workers)
+	if err == nil {
+		err = srv.queue.Drain(ctx)
+	}
+	srv.Stop()
+	srv.workers.Wait()
+	srv.queue.Close()
+	srv.events.Close()
+
+	return err
+}
+
+func (srv *udpFeeder) closeConns() {
+	for _, conn := range srv.conns {
+		conn.Close()
+	}
 }
 
 func (srv *udpFeeder) statsSnapshot() feeder.StatsSnapshot {
-	return feeder.StatsSnapshot{
+	snapshot := feeder.StatsSnapshot{
 		Transport:                   "udp",
 		StartTime:                   srv.startTime.UTC(),
 		UptimeSeconds:               int64(time.Since(srv.startTime).Seconds()),
 		MessagesReceivedTotal:       srv.messagesReceivedTotal.Load(),
 		PayloadBytesReceivedTotal:   srv.payloadBytesReceivedTotal.Load(),
 		TransportBytesReceivedTotal: srv.transportBytesReceivedTotal.Load(),
-		FeedItemsEnqueuedTotal:      srv.feedItemsEnqueuedTotal.Load(),
-		FeedErrorItemsEnqueuedTotal: srv.feedErrorItemsEnqueuedTotal.Load(),
-		FeedQueueDepth:              int64(len(srv.feed)),
-		FeedQueueDepthMax:           srv.feedQueueDepthMax.Load(),
-		FeedQueueCapacity:           int64(cap(srv.feed)),
-		FeedPublishBlockNanosTotal:  srv.feedPublishBlockNanosTotal.Load(),
-		FeedPublishBlockNanosMax:    srv.feedPublishBlockNanosMax.Load(),
 		ReceiveErrorsTotal:          srv.receiveErrorsTotal.Load(),
 		ReceiveTimeoutErrorsTotal:   srv.receiveTimeoutErrorsTotal.Load(),
 		ReceiveClosedTotal:          srv.receiveClosedTotal.Load(),
 		ReceiveOtherErrorsTotal:     srv.receiveOtherErrorsTotal.Load(),
 	}
+	srv.queue.FillStats(&snapshot)
+	srv.producers.FillStats(&snapshot)
+	srv.events.FillStats(&snapshot)
+
+	return snapshot
 }
 
 func (srv *udpFeeder) GetStatsJson() ([]byte, error) {
@@ -86,131 +134,233 @@ func classifyReceiveError(err error) string {
 	return "other"
 }
 
-func updateMax(max *atomic.Int64, value int64) {
-	for {
-		current := max.Load()
-		if value <= current || max.CompareAndSwap(current, value) {
-			return
-		}
-	}
-}
-
 func (srv *udpFeeder) publishFeed(item *feeder.Feed) bool {
-	// If stopCh is already closed, prevent publishing (even if the send would not block).
-	select {
-	case <-srv.stopCh:
-		return false
-	default:
-	}
-	queueDepthAfterSend := int64(len(srv.feed) + 1)
-	if queueDepthAfterSend > int64(cap(srv.feed)) {
-		queueDepthAfterSend = int64(cap(srv.feed))
-	}
-	started := time.Now()
-	select {
-	case <-srv.stopCh:
-		return false
-	case srv.feed <- item:
-		blocked := time.Since(started).Nanoseconds()
-		srv.feedItemsEnqueuedTotal.Add(1)
-		if item.Err != nil {
-			srv.feedErrorItemsEnqueuedTotal.Add(1)
-		}
-		srv.feedPublishBlockNanosTotal.Add(blocked)
-		updateMax(&srv.feedPublishBlockNanosMax, blocked)
-		updateMax(&srv.feedQueueDepthMax, queueDepthAfterSend)
-		return true
-	}
+	return srv.queue.Publish(item)
 }
 
 func New(addr string) (feeder.Feeder, error) {
+	return NewWithOptions(addr)
+}
+
+// NewWithOverflow creates UDP feeder with the feed queue overflow policy defined by cfg.
+func NewWithOverflow(addr string, cfg feeder.OverflowConfig) (feeder.Feeder, error) {
+	return NewWithOptions(addr, WithOverflow(cfg))
+}
+
+// NewWithOptions creates UDP feeder, settings not customized by opts keep the defaults used by New.
+func NewWithOptions(addr string, opts ...Option) (feeder.Feeder, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
 	// Need to open UDP socket to listen for incoming telemetry messages
 	srvAddr, err := net.ResolveUDPAddr("udp", addr)
 	if err != nil {
 		return nil, err
 	}
-	conn, err := net.ListenUDP("udp", srvAddr)
+	conns, err := listen(srvAddr, o.readers)
 	if err != nil {
 		return nil, err
 	}
+	closeAll := func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}
+	if o.readBuffer > 0 {
+		for _, conn := range conns {
+			if err := conn.SetReadBuffer(o.readBuffer); err != nil {
+				closeAll()
+				return nil, fmt.Errorf("failed to set socket read buffer to %d bytes: %w", o.readBuffer, err)
+			}
+		}
+	}
+	stopCh := make(chan struct{})
+	queue, err := feeder.NewFeedQueue(o.queueCapacity, stopCh, o.overflow)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	events := feeder.NewEventQueue(feeder.TransportUDP)
+	queue.SetEvents(events)
 	srv := &udpFeeder{
-		conn:      conn,
-		stopCh:    make(chan struct{}),
-		feed:      make(chan *feeder.Feed, feedQueueCapacity),
-		startTime: time.Now(),
+		conns:      conns,
+		readers:    o.readers,
+		batchSize:  o.batchSize,
+		stopCh:     stopCh,
+		drainCh:    make(chan struct{}),
+		queue:      queue,
+		events:     events,
+		producers:  feeder.NewProducerTracker(o.producers),
+		pool:       o.pool,
+		logger:     o.logger,
+		maxMsgSize: o.maxMsgSize,
+		startTime:  time.Now(),
+	}
+	// Without SO_REUSEPORT all readers share the single socket.
+	for i := 0; i < o.readers; i++ {
+		srv.workers.Add(1)
+		go srv.worker(conns[i%len(conns)])
 	}
-
-	go srv.worker()
 
 	return srv, nil
 }
 
-func (srv *udpFeeder) worker() error {
-	buf := make([]byte, MaxRcvMsgSize)
+// listen opens n sockets bound to addr with SO_REUSEPORT, the kernel then spreads the
+// producers over them. A single socket is opened when n is 1 or the platform does not support
+// SO_REUSEPORT.
+func listen(addr *net.UDPAddr, n int) ([]*net.UDPConn, error) {
+	if n == 1 || !reusePortSupported {
+		conn, err := net.ListenUDP("udp", addr)
+		if err != nil {
+			return nil, err
+		}
+		return []*net.UDPConn{conn}, nil
+	}
+	lc := net.ListenConfig{Control: setReusePort}
+	conns := make([]*net.UDPConn, 0, n)
+	bind := addr.String()
+	for i := 0; i < n; i++ {
+		pc, err := lc.ListenPacket(context.Background(), "udp", bind)
+		if err != nil {
+			for _, conn := range conns {
+				conn.Close()
+			}
+			return nil, fmt.Errorf("failed to open socket %d of %d with SO_REUSEPORT: %w", i+1, n, err)
+		}
+		conn := pc.(*net.UDPConn)
+		conns = append(conns, conn)
+		// An ephemeral port is chosen by the first socket, the others join it.
+		bind = conn.LocalAddr().String()
+	}
+
+	return conns, nil
+}
+
+func (srv *udpFeeder) worker(conn *net.UDPConn) {
+	defer srv.workers.Done()
+	if srv.batchSize > 1 {
+		srv.batchWorker(conn)
+		return
+	}
+	buf := make([]byte, srv.maxMsgSize)
 	for {
 		select {
 		case <-srv.stopCh:
-			return nil
+			return
 		default:
-			n, producerAddr, err := srv.conn.ReadFrom(buf)
-			if err != nil {
-				errClass := classifyReceiveError(err)
-				switch errClass {
-				case "timeout":
-					srv.receiveErrorsTotal.Add(1)
-					srv.receiveTimeoutErrorsTotal.Add(1)
-				case "closed":
-					srv.receiveErrorsTotal.Add(1)
-					srv.receiveClosedTotal.Add(1)
-				case "other":
-					srv.receiveErrorsTotal.Add(1)
-					srv.receiveOtherErrorsTotal.Add(1)
-				}
-				if errClass == "closed" {
-					select {
-					case <-srv.stopCh:
-						return nil
-					default:
-					}
-				}
-				if !srv.publishFeed(&feeder.Feed{
-					ProducerAddr: producerAddr,
-					TelemetryMsg: nil,
-					Err:          err,
-					Transport:    feeder.TransportUDP,
-					Encoding:     feeder.EncodingJSON,
-					Framing:      feeder.FramingNone,
-				}) {
-					return nil
-				}
-				// Need to check the error, if local socket is closed, there is no point to continue receiving messages, just return
-				if errClass == "closed" {
-					return nil
-				}
-				continue
-			} else {
-				srv.messagesReceivedTotal.Add(1)
-				srv.transportBytesReceivedTotal.Add(int64(n))
-				feedMsg, err := feeder.MakeFeederMsgFromJson(buf[:n], n, feeder.TransportUDP)
-				if err != nil {
-					// payloadBytesReceivedTotal counts decoded payload bytes; malformed datagrams contribute 0.
-					if !srv.publishFeed(&feeder.Feed{
-						ProducerAddr: producerAddr,
-						Err:          err,
-						Transport:    feeder.TransportUDP,
-						Encoding:     feeder.EncodingJSON,
-						Framing:      feeder.FramingNone,
-					}) {
-						return nil
-					}
-					continue
-				}
-				srv.payloadBytesReceivedTotal.Add(int64(len(feedMsg.TelemetryMsg)))
-				feedMsg.ProducerAddr = producerAddr
-				if !srv.publishFeed(&feedMsg) {
-					return nil
-				}
+			n, producerAddr, err := conn.ReadFrom(buf)
+			if !srv.receive(conn, buf[:n], producerAddr, time.Now(), err) {
+				return
 			}
 		}
 	}
 }
+
+// batchWorker reads up to batchSize datagrams per system call, recvmmsg is used on Linux, other
+// platforms read a single datagram per call.
+func (srv *udpFeeder) batchWorker(conn *net.UDPConn) {
+	var r interface {
+		ReadBatch([]ipv4.Message, int) (int, error)
+	}
+	if local, ok := conn.LocalAddr().(*net.UDPAddr); ok && local.IP.To4() == nil {
+		r = ipv6.NewPacketConn(conn)
+	} else {
+		r = ipv4.NewPacketConn(conn)
+	}
+	ms := make([]ipv4.Message, srv.batchSize)
+	for i := range ms {
+		ms[i].Buffers = [][]byte{make([]byte, min(srv.maxMsgSize, maxDatagramSize))}
+	}
+	for {
+		select {
+		case <-srv.stopCh:
+			return
+		default:
+		}
+		n, err := r.ReadBatch(ms, 0)
+		receivedAt := time.Now()
+		if err != nil {
+			if !srv.receive(conn, nil, nil, receivedAt, err) {
+				return
+			}
+			continue
+		}
+		for _, m := range ms[:n] {
+			if !srv.receive(conn, m.Buffers[0][:m.N], m.Addr, receivedAt, nil) {
+				return
+			}
+		}
+	}
+}
+
+// receive processes the outcome of a single read, false is returned when the reader has to stop.
+func (srv *udpFeeder) receive(conn *net.UDPConn, b []byte, producerAddr net.Addr, receivedAt time.Time, err error) bool {
+	n := len(b)
+	if err != nil {
+		errClass := classifyReceiveError(err)
+		switch errClass {
+		case "timeout":
+			srv.receiveErrorsTotal.Add(1)
+			srv.receiveTimeoutErrorsTotal.Add(1)
+		case "closed":
+			srv.receiveErrorsTotal.Add(1)
+			srv.receiveClosedTotal.Add(1)
+		case "other":
+			srv.receiveErrorsTotal.Add(1)
+			srv.receiveOtherErrorsTotal.Add(1)
+		}
+		if errClass == "closed" {
+			select {
+			case <-srv.stopCh:
+				return false
+			case <-srv.drainCh:
+				return false
+			default:
+			}
+		}
+		if !srv.publishFeed(&feeder.Feed{
+			ProducerAddr: producerAddr,
+			TelemetryMsg: nil,
+			Err:          err,
+			Transport:    feeder.TransportUDP,
+			Encoding:     feeder.EncodingJSON,
+			Framing:      feeder.FramingNone,
+		}) {
+			return false
+		}
+		// Need to check the error, if local socket is closed, there is no point to continue receiving messages, just return
+		if errClass == "closed" {
+			srv.logger.Warningf("UDP socket %s has been closed, stop receiving: %v", conn.LocalAddr(), err)
+			// The datagrams steered to this socket would be lost, the feeder stops as a whole.
+			srv.Stop()
+			return false
+		}
+		return true
+	}
+	srv.messagesReceivedTotal.Add(1)
+	srv.transportBytesReceivedTotal.Add(int64(n))
+	feedMsg, err := feeder.MakeFeederMsgWithPool(b, n, feeder.TransportUDP, srv.pool)
+	if err != nil {
+		srv.producers.Received(producerAddr, 0, n)
+		srv.producers.Error(producerAddr)
+		srv.events.FramingError(producerAddr, err)
+		// payloadBytesReceivedTotal counts decoded payload bytes; malformed datagrams contribute 0.
+		return srv.publishFeed(&feeder.Feed{
+			ProducerAddr: producerAddr,
+			Err:          err,
+			Transport:    feeder.TransportUDP,
+			Encoding:     feeder.EncodingJSON,
+			Framing:      feeder.FramingNone,
+		})
+	}
+	srv.payloadBytesReceivedTotal.Add(int64(len(feedMsg.TelemetryMsg)))
+	srv.producers.Received(producerAddr, len(feedMsg.TelemetryMsg), n)
+	feedMsg.ProducerAddr = producerAddr
+	feedMsg.ReceivedAt = receivedAt
+
+	return srv.publishFeed(&feedMsg)
+}
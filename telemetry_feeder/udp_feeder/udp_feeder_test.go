@@ -2,7 +2,10 @@ package udp_feeder
 
 import (
 	"bytes"
+	"context"
+	"encoding/binary"
 	"encoding/json"
+	"fmt"
 	"net"
 	"os"
 	"strings"
@@ -43,6 +46,36 @@ func TestNewReceivesDatagram(t *testing.T) {
 	}
 }
 
+func TestReceivesXRSTFramedGPB(t *testing.T) {
+	f, addr := newTestFeeder(t)
+	defer f.Stop()
+
+	conn := newUDPClient(t)
+	defer conn.Close()
+
+	payload := []byte{0x0a, 0x02, 'r', '1', 0x12, 0x00}
+	msg := make([]byte, feeder.XRSTHeaderLength, feeder.XRSTHeaderLength+len(payload))
+	binary.BigEndian.PutUint16(msg[0:2], uint16(feeder.XRSTMsgTypeTelemetryData))
+	binary.BigEndian.PutUint16(msg[2:4], uint16(feeder.XRSTEncodingGPBKV))
+	binary.BigEndian.PutUint32(msg[8:12], uint32(len(payload)))
+	msg = append(msg, payload...)
+	if _, err := conn.WriteToUDP(msg, addr); err != nil {
+		t.Fatalf("failed to send test datagram: %v", err)
+	}
+
+	select {
+	case got := <-f.GetFeed():
+		if got.Err != nil {
+			t.Fatalf("expected nil error, got %v", got.Err)
+		}
+		if got.Encoding != feeder.EncodingGPB || got.Framing != feeder.FramingCiscoXRST || !bytes.Equal(got.TelemetryMsg, payload) {
+			t.Fatalf("unexpected feed %+v", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for datagram")
+	}
+}
+
 func TestQueuedDatagramsKeepIndependentPayloads(t *testing.T) {
 	f, addr := newTestFeeder(t)
 	defer f.Stop()
@@ -184,6 +217,40 @@ func TestStatsAfterDatagram(t *testing.T) {
 	if stats.FeedQueueCapacity != feedQueueCapacity {
 		t.Fatalf("feed_queue_capacity mismatch, want %d got %d", feedQueueCapacity, stats.FeedQueueCapacity)
 	}
+	producer, ok := stats.Producers[conn.LocalAddr().String()]
+	if !ok || producer.MessagesReceivedTotal != 1 || producer.PayloadBytesReceivedTotal != int64(len(payload)) {
+		t.Fatalf("unexpected per-producer stats %+v", stats.Producers)
+	}
+}
+
+func TestNewWithOptions(t *testing.T) {
+	f, addr := newTestFeeder(t, WithQueueCapacity(5), WithMaxMsgSize(8), WithReadBuffer(1024*1024), WithLogger(feeder.NopLogger()))
+	defer f.Stop()
+
+	if stats := f.statsSnapshot(); stats.FeedQueueCapacity != 5 {
+		t.Fatalf("feed_queue_capacity mismatch, want 5 got %d", stats.FeedQueueCapacity)
+	}
+
+	conn := newUDPClient(t)
+	defer conn.Close()
+	if _, err := conn.WriteToUDP([]byte(`{"encoding_path":"rib"}`), addr); err != nil {
+		t.Fatalf("failed to send test datagram: %v", err)
+	}
+	select {
+	case got := <-f.GetFeed():
+		// Datagram longer than the maximum message size is truncated.
+		if got.Err != nil || len(got.TelemetryMsg) != 8 {
+			t.Fatalf("expected datagram truncated to 8 bytes, got %q error %v", got.TelemetryMsg, got.Err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for datagram")
+	}
+
+	for _, opt := range []Option{WithQueueCapacity(-1), WithMaxMsgSize(0), WithReadBuffer(-1)} {
+		if _, err := NewWithOptions("127.0.0.1:0", opt); err == nil {
+			t.Fatal("expected error for invalid option")
+		}
+	}
 }
 
 func TestStopUnblocksWorker(t *testing.T) {
@@ -202,6 +269,45 @@ func TestStopUnblocksWorker(t *testing.T) {
 	}
 }
 
+func TestStopGracefully(t *testing.T) {
+	f, addr := newTestFeeder(t)
+
+	conn := newUDPClient(t)
+	defer conn.Close()
+	for i := 0; i < 3; i++ {
+		if _, err := conn.WriteToUDP([]byte(fmt.Sprintf(`{"seq":%d}`, i)), addr); err != nil {
+			t.Fatalf("failed to send test datagram: %v", err)
+		}
+	}
+	for deadline := time.Now().Add(2 * time.Second); len(f.GetFeed()) < 3; {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for datagrams")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- f.StopGracefully(ctx)
+	}()
+	var got []string
+	for item := range f.GetFeed() {
+		if item.Err != nil {
+			t.Fatalf("unexpected error item: %v", item.Err)
+		}
+		got = append(got, string(item.TelemetryMsg))
+	}
+	if err := <-stopped; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `[{"seq":0} {"seq":1} {"seq":2}]`; fmt.Sprint(got) != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+	f.Stop()
+}
+
 func TestReceiveAfterStopDoesNotPublish(t *testing.T) {
 	f, addr := newTestFeeder(t)
 	f.Stop()
@@ -221,10 +327,10 @@ func TestReceiveAfterStopDoesNotPublish(t *testing.T) {
 	}
 }
 
-func newTestFeeder(t *testing.T) (*udpFeeder, *net.UDPAddr) {
+func newTestFeeder(t *testing.T, opts ...Option) (*udpFeeder, *net.UDPAddr) {
 	t.Helper()
 
-	fdr, err := New("127.0.0.1:0")
+	fdr, err := NewWithOptions("127.0.0.1:0", opts...)
 	if err != nil {
 		if isSocketPermissionError(err) {
 			t.Skipf("udp sockets are not available in this environment: %v", err)
@@ -237,9 +343,9 @@ func newTestFeeder(t *testing.T) (*udpFeeder, *net.UDPAddr) {
 		t.Fatalf("unexpected feeder type %T", fdr)
 	}
 
-	addr, ok := f.conn.LocalAddr().(*net.UDPAddr)
+	addr, ok := f.conns[0].LocalAddr().(*net.UDPAddr)
 	if !ok {
-		t.Fatalf("unexpected local address type %T", f.conn.LocalAddr())
+		t.Fatalf("unexpected local address type %T", f.conns[0].LocalAddr())
 	}
 
 	return f, addr
@@ -262,3 +368,36 @@ func newUDPClient(t *testing.T) *net.UDPConn {
 func isSocketPermissionError(err error) bool {
 	return os.IsPermission(err) || strings.Contains(err.Error(), "operation not permitted")
 }
+
+func TestMultipleReadersWithBatches(t *testing.T) {
+	f, addr := newTestFeeder(t, WithReaders(4), WithBatchSize(8))
+	defer f.Stop()
+	if reusePortSupported && len(f.conns) != 4 {
+		t.Fatalf("expected 4 sockets, got %d", len(f.conns))
+	}
+
+	// Several producers so that SO_REUSEPORT spreads them over the sockets.
+	const producers, perProducer = 8, 10
+	for i := 0; i < producers; i++ {
+		conn := newUDPClient(t)
+		defer conn.Close()
+		for j := 0; j < perProducer; j++ {
+			if _, err := conn.WriteToUDP([]byte(`{"producer":true}`), addr); err != nil {
+				t.Fatalf("failed to send test datagram: %v", err)
+			}
+		}
+	}
+	for i := 0; i < producers*perProducer; i++ {
+		select {
+		case got := <-f.GetFeed():
+			if got.Err != nil || string(got.TelemetryMsg) != `{"producer":true}` || got.ProducerAddr == nil {
+				t.Fatalf("unexpected feed %+v", got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out after %d datagrams", i)
+		}
+	}
+	if got := f.statsSnapshot().MessagesReceivedTotal; got != producers*perProducer {
+		t.Fatalf("messages_received_total mismatch, want %d got %d", producers*perProducer, got)
+	}
+}
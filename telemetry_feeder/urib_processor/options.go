@@ -0,0 +1,66 @@
+package urib_processor
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultDownloadTimeout is how long a download may go without DOWNLOAD_DONE before it is
+	// abandoned.
+	DefaultDownloadTimeout = 5 * time.Minute
+)
+
+type options struct {
+	encodingPaths   map[string]bool
+	downloadTimeout time.Duration
+	losslessEvents  bool
+}
+
+// Option customizes the processor created by New.
+type Option func(*options)
+
+func defaultOptions() options {
+	return options{
+		downloadTimeout: DefaultDownloadTimeout,
+	}
+}
+
+// WithEncodingPaths restricts the processor to the records of the encoding paths, by default
+// the rows of every compact GPB record are taken for URIB routes.
+func WithEncodingPaths(paths ...string) Option {
+	return func(o *options) {
+		o.encodingPaths = make(map[string]bool, len(paths))
+		for _, p := range paths {
+			o.encodingPaths[p] = true
+		}
+	}
+}
+
+// WithDownloadTimeout sets how long after its first DOWNLOAD route a download of a node is
+// abandoned when DOWNLOAD_DONE does not arrive, a negative timeout waits forever.
+func WithDownloadTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.downloadTimeout = d
+	}
+}
+
+// WithLosslessEvents makes the stage created by NewStage hold back the records while its
+// events channel is full, so a consumer mirroring the tables sees every change, including the
+// resynchronization of a large download. By default the events which do not fit are dropped.
+func WithLosslessEvents() Option {
+	return func(o *options) {
+		o.losslessEvents = true
+	}
+}
+
+func (o *options) validate() error {
+	if o.encodingPaths != nil && len(o.encodingPaths) == 0 {
+		return fmt.Errorf("at least one encoding path must be given")
+	}
+	if o.downloadTimeout == 0 {
+		return fmt.Errorf("download timeout cannot be 0")
+	}
+
+	return nil
+}
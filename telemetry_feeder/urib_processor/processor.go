@@ -0,0 +1,492 @@
+package urib_processor
+
+import (
+	"encoding/json"
+	"net/netip"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sbezverk/tools/telemetry_feeder/decoder"
+	"github.com/sbezverk/tools/telemetry_feeder/proto/urib"
+	"google.golang.org/protobuf/proto"
+)
+
+// DefaultVRF is the VRF of routes which do not name one.
+const DefaultVRF = "default"
+
+// Kind is the kind of a route change.
+type Kind string
+
+const (
+	KindAdd    Kind = "add"
+	KindUpdate Kind = "update"
+	KindDelete Kind = "delete"
+)
+
+// TableKey identifies the route table of a VRF of a node.
+type TableKey struct {
+	NodeID string `json:"node_id"`
+	VRF    string `json:"vrf"`
+}
+
+// NextHop is a next hop of a route.
+type NextHop struct {
+	Address    netip.Addr `json:"address"`
+	Interface  string     `json:"interface,omitempty"`
+	VRF        string     `json:"vrf,omitempty"`
+	Owner      string     `json:"owner,omitempty"`
+	Preference uint32     `json:"preference"`
+	Metric     uint32     `json:"metric"`
+	Tag        uint32     `json:"tag,omitempty"`
+	SegmentID  uint32     `json:"segment_id,omitempty"`
+	TunnelID   uint32     `json:"tunnel_id,omitempty"`
+	Encap      string     `json:"encap,omitempty"`
+	TypeFlags  uint32     `json:"type_flags"`
+}
+
+// Route is the state of a prefix in a route table. Routes are replaced rather than modified
+// on changes, the routes returned by the processor and carried by events must not be modified.
+type Route struct {
+	Prefix   netip.Prefix `json:"prefix"`
+	NextHops []NextHop    `json:"next_hops"`
+	// Updated is the timestamp of the message which last changed the route.
+	Updated time.Time `json:"updated"`
+}
+
+// equal compares the routing state of the routes, Updated is ignored.
+func (r *Route) equal(o *Route) bool {
+	return r.Prefix == o.Prefix && slices.Equal(r.NextHops, o.NextHops)
+}
+
+// Event is a change of a route table.
+type Event struct {
+	Kind  Kind
+	Table TableKey
+	// Route is the new state of the route, nil for KindDelete.
+	Route *Route
+	// Previous is the replaced or deleted route, nil for KindAdd.
+	Previous *Route
+	// Resync is set for the changes a completed download made to the table, deletes of routes
+	// the download did not carry among them.
+	Resync bool
+	Time   time.Time
+}
+
+// Table is the exported state of a route table, routes are sorted by prefix.
+type Table struct {
+	TableKey
+	Routes []*Route `json:"routes"`
+}
+
+// Snapshot is the exported state of all route tables, sorted by node and VRF.
+type Snapshot struct {
+	Time   time.Time `json:"time"`
+	Tables []Table   `json:"tables"`
+}
+
+type Stats struct {
+	RecordsTotal int64 `json:"records_total"`
+	RoutesTotal  int64 `json:"routes_total"`
+	// IgnoredTotal counts records with an error, of other encoding paths or not in the compact
+	// GPB format, and NO_EVENT routes.
+	IgnoredTotal int64 `json:"ignored_total"`
+	// InvalidTotal counts rows which are not a NxL3RouteProto or carry no valid prefix.
+	InvalidTotal int64 `json:"invalid_total"`
+	AddsTotal    int64 `json:"adds_total"`
+	UpdatesTotal int64 `json:"updates_total"`
+	DeletesTotal int64 `json:"deletes_total"`
+	// UnknownDeletesTotal counts DELETE events of routes not in the table.
+	UnknownDeletesTotal int64 `json:"unknown_deletes_total"`
+	DownloadsTotal      int64 `json:"downloads_total"`
+	// DownloadsAbandonedTotal counts downloads which timed out or were cut short by ResetNode.
+	DownloadsAbandonedTotal int64 `json:"downloads_abandoned_total"`
+	// UnmatchedDownloadDoneTotal counts DOWNLOAD_DONE events without a download in progress.
+	UnmatchedDownloadDoneTotal int64 `json:"unmatched_download_done_total"`
+	StaleDeletesTotal          int64 `json:"stale_deletes_total"`
+	DownloadsActive            int64 `json:"downloads_active"`
+	TablesActive               int64 `json:"tables_active"`
+	RoutesActive               int64 `json:"routes_active"`
+	EventsDroppedTotal         int64 `json:"events_dropped_total"`
+}
+
+// download is a bulk download of the routes of a node in progress, the routes are staged
+// until DOWNLOAD_DONE. vrfs holds the VRFs the DOWNLOAD events carried routes of, the tables
+// also reflect the changes made during the download.
+type download struct {
+	started time.Time
+	tables  map[string]map[netip.Prefix]*Route
+	vrfs    map[string]bool
+}
+
+func (d *download) set(vrf string, rt *Route) {
+	if d.tables[vrf] == nil {
+		d.tables[vrf] = make(map[netip.Prefix]*Route)
+	}
+	d.tables[vrf][rt.Prefix] = rt
+}
+
+// Processor maintains the route tables of NX-OS nodes from their URIB events. ADD, UPDATE and
+// DELETE events change the table right away. DOWNLOAD events stage their routes in a download
+// of the node which starts empty, on DOWNLOAD_DONE the tables of the VRFs it carried routes of
+// are replaced by it atomically and the tables of other VRFs are kept.
+// Processor is safe for concurrent use.
+type Processor struct {
+	opts          options
+	mu            sync.Mutex
+	tables        map[TableKey]map[netip.Prefix]*Route
+	downloads     map[string]*download
+	stats         Stats
+	eventsDropped atomic.Int64
+	now           func() time.Time
+}
+
+// New creates a processor, records are passed to Process. NewStage runs it as a pipeline stage.
+func New(opts ...Option) (*Processor, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+
+	return &Processor{
+		opts:      o,
+		tables:    make(map[TableKey]map[netip.Prefix]*Route),
+		downloads: make(map[string]*download),
+		now:       time.Now,
+	}, nil
+}
+
+// Process applies the URIB routes carried by the rows of the record to the tables of its node
+// and returns the resulting changes.
+func (p *Processor) Process(r *decoder.Record) []*Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stats.RecordsTotal++
+	if r == nil || r.Err != nil || r.Format != decoder.FormatGPB ||
+		(p.opts.encodingPaths != nil && !p.opts.encodingPaths[r.EncodingPath]) {
+		p.stats.IgnoredTotal++
+		return nil
+	}
+	var events []*Event
+	for _, row := range r.Rows {
+		rt := &urib.NxL3RouteProto{}
+		if err := proto.Unmarshal(row.Content, rt); err != nil {
+			p.stats.InvalidTotal++
+			continue
+		}
+		ts := row.Timestamp
+		if ts.IsZero() {
+			ts = r.MsgTimestamp
+		}
+		events = append(events, p.apply(r.NodeID, rt, ts)...)
+	}
+
+	return events
+}
+
+// ProcessRoute applies a URIB route of the node decoded by the caller, ts is the time of the
+// event and defaults to the current time.
+func (p *Processor) ProcessRoute(nodeID string, rt *urib.NxL3RouteProto, ts time.Time) []*Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.apply(nodeID, rt, ts)
+}
+
+// apply must be called with the lock held.
+func (p *Processor) apply(nodeID string, rt *urib.NxL3RouteProto, ts time.Time) []*Event {
+	now := p.now()
+	if ts.IsZero() {
+		ts = now
+	}
+	p.stats.RoutesTotal++
+	d, ok := p.downloads[nodeID]
+	if ok && p.opts.downloadTimeout > 0 && now.Sub(d.started) > p.opts.downloadTimeout {
+		delete(p.downloads, nodeID)
+		p.stats.DownloadsAbandonedTotal++
+		d, ok = nil, false
+	}
+	vrf := rt.GetVrfName()
+	if vrf == "" {
+		vrf = DefaultVRF
+	}
+	key := TableKey{NodeID: nodeID, VRF: vrf}
+
+	switch rt.GetEventType() {
+	case urib.UribEventType_URIB_EVENT_TYPE_DOWNLOAD:
+		if !ok {
+			d = &download{started: now, tables: make(map[string]map[netip.Prefix]*Route), vrfs: make(map[string]bool)}
+			p.downloads[nodeID] = d
+		}
+		// A DOWNLOAD event without a prefix only marks the start of the download.
+		if rt.GetAddress() == "" {
+			return nil
+		}
+		route, valid := convertRoute(rt, ts)
+		if !valid {
+			p.stats.InvalidTotal++
+			return nil
+		}
+		d.set(vrf, route)
+		d.vrfs[vrf] = true
+		return nil
+	case urib.UribEventType_URIB_EVENT_TYPE_DOWNLOAD_DONE:
+		if !ok {
+			p.stats.UnmatchedDownloadDoneTotal++
+			return nil
+		}
+		delete(p.downloads, nodeID)
+		p.stats.DownloadsTotal++
+		return p.resync(nodeID, d, now)
+	case urib.UribEventType_URIB_EVENT_TYPE_ADD, urib.UribEventType_URIB_EVENT_TYPE_UPDATE:
+		route, valid := convertRoute(rt, ts)
+		if !valid {
+			p.stats.InvalidTotal++
+			return nil
+		}
+		// Changes made during a download are newer than the routes downloaded before them.
+		if ok {
+			d.set(vrf, route)
+		}
+		if e := p.set(key, route, false, now); e != nil {
+			return []*Event{e}
+		}
+		return nil
+	case urib.UribEventType_URIB_EVENT_TYPE_DELETE:
+		prefix, valid := parsePrefix(rt)
+		if !valid {
+			p.stats.InvalidTotal++
+			return nil
+		}
+		if ok {
+			delete(d.tables[vrf], prefix)
+		}
+		if e := p.delete(key, prefix, false, now); e != nil {
+			return []*Event{e}
+		}
+		p.stats.UnknownDeletesTotal++
+		return nil
+	default:
+		p.stats.IgnoredTotal++
+		return nil
+	}
+}
+
+// resync applies the completed download to the tables of the node, must be called with the
+// lock held. Only the tables of the VRFs the download carried are replaced, the routes of
+// other VRFs are kept.
+func (p *Processor) resync(nodeID string, d *download, now time.Time) []*Event {
+	var events []*Event
+	vrfs := make([]string, 0, len(d.tables))
+	for vrf := range d.tables {
+		vrfs = append(vrfs, vrf)
+	}
+	sort.Strings(vrfs)
+	for _, vrf := range vrfs {
+		for _, rt := range sortedRoutes(d.tables[vrf]) {
+			if e := p.set(TableKey{NodeID: nodeID, VRF: vrf}, rt, true, now); e != nil {
+				events = append(events, e)
+			}
+		}
+	}
+	var keys []TableKey
+	for key := range p.tables {
+		if key.NodeID == nodeID && d.vrfs[key.VRF] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].VRF < keys[j].VRF })
+	for _, key := range keys {
+		for _, rt := range sortedRoutes(p.tables[key]) {
+			if _, ok := d.tables[key.VRF][rt.Prefix]; !ok {
+				events = append(events, p.delete(key, rt.Prefix, true, now))
+			}
+		}
+	}
+
+	return events
+}
+
+// set must be called with the lock held.
+func (p *Processor) set(key TableKey, rt *Route, resync bool, now time.Time) *Event {
+	routes, ok := p.tables[key]
+	if !ok {
+		routes = make(map[netip.Prefix]*Route)
+		p.tables[key] = routes
+	}
+	prev, ok := routes[rt.Prefix]
+	if ok && prev.equal(rt) {
+		return nil
+	}
+	routes[rt.Prefix] = rt
+	if !ok {
+		p.stats.AddsTotal++
+		return &Event{Kind: KindAdd, Table: key, Route: rt, Resync: resync, Time: now}
+	}
+	p.stats.UpdatesTotal++
+
+	return &Event{Kind: KindUpdate, Table: key, Route: rt, Previous: prev, Resync: resync, Time: now}
+}
+
+// delete returns nil when the route is not in the table, must be called with the lock held.
+func (p *Processor) delete(key TableKey, prefix netip.Prefix, resync bool, now time.Time) *Event {
+	prev, ok := p.tables[key][prefix]
+	if !ok {
+		return nil
+	}
+	delete(p.tables[key], prefix)
+	if len(p.tables[key]) == 0 {
+		delete(p.tables, key)
+	}
+	p.stats.DeletesTotal++
+	if resync {
+		p.stats.StaleDeletesTotal++
+	}
+
+	return &Event{Kind: KindDelete, Table: key, Previous: prev, Resync: resync, Time: now}
+}
+
+// ResetNode deletes all routes of the node and abandons its download in progress, for
+// example when its producer disconnected.
+func (p *Processor) ResetNode(nodeID string) []*Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	if _, ok := p.downloads[nodeID]; ok {
+		delete(p.downloads, nodeID)
+		p.stats.DownloadsAbandonedTotal++
+	}
+	var events []*Event
+	for key, routes := range p.tables {
+		if key.NodeID != nodeID {
+			continue
+		}
+		for prefix := range routes {
+			events = append(events, p.delete(key, prefix, false, now))
+		}
+	}
+
+	return events
+}
+
+// Table returns the routes of the table sorted by prefix, nil when the table is empty.
+func (p *Processor) Table(key TableKey) []*Route {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return sortedRoutes(p.tables[key])
+}
+
+// Snapshot returns the state of all route tables. Routes of downloads in progress are not
+// part of it.
+func (p *Processor) Snapshot() *Snapshot {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := &Snapshot{
+		Time:   p.now().UTC(),
+		Tables: make([]Table, 0, len(p.tables)),
+	}
+	for key, routes := range p.tables {
+		s.Tables = append(s.Tables, Table{TableKey: key, Routes: sortedRoutes(routes)})
+	}
+	sort.Slice(s.Tables, func(i, j int) bool {
+		a, b := s.Tables[i], s.Tables[j]
+		if a.NodeID != b.NodeID {
+			return a.NodeID < b.NodeID
+		}
+		return a.VRF < b.VRF
+	})
+
+	return s
+}
+
+func (p *Processor) GetSnapshotJson() ([]byte, error) {
+	return json.Marshal(p.Snapshot())
+}
+
+func (p *Processor) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.stats
+	s.DownloadsActive = int64(len(p.downloads))
+	s.TablesActive = int64(len(p.tables))
+	for _, routes := range p.tables {
+		s.RoutesActive += int64(len(routes))
+	}
+	s.EventsDroppedTotal = p.eventsDropped.Load()
+
+	return s
+}
+
+func (p *Processor) GetStatsJson() ([]byte, error) {
+	return json.Marshal(p.Stats())
+}
+
+func parsePrefix(rt *urib.NxL3RouteProto) (netip.Prefix, bool) {
+	a, err := netip.ParseAddr(rt.GetAddress())
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	prefix, err := a.Prefix(int(rt.GetMaskLen()))
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+
+	return prefix, true
+}
+
+func convertRoute(rt *urib.NxL3RouteProto, ts time.Time) (*Route, bool) {
+	prefix, ok := parsePrefix(rt)
+	if !ok {
+		return nil, false
+	}
+	route := &Route{
+		Prefix:   prefix,
+		NextHops: make([]NextHop, 0, len(rt.GetNextHop())),
+		Updated:  ts,
+	}
+	for _, nh := range rt.GetNextHop() {
+		addr, _ := netip.ParseAddr(nh.GetAddress())
+		var encap string
+		if nh.GetEncapType() != urib.EncapType_ENCAP_TYPE_NONE {
+			encap = nh.GetEncapType().String()
+		}
+		route.NextHops = append(route.NextHops, NextHop{
+			Address:    addr,
+			Interface:  nh.GetOutInterface(),
+			VRF:        nh.GetVrfName(),
+			Owner:      nh.GetOwner(),
+			Preference: nh.GetPreference(),
+			Metric:     nh.GetMetric(),
+			Tag:        nh.GetTag(),
+			SegmentID:  nh.GetSegmentId(),
+			TunnelID:   nh.GetTunnelId(),
+			Encap:      encap,
+			TypeFlags:  nh.GetNhTypeFlags(),
+		})
+	}
+
+	return route, true
+}
+
+func sortedRoutes(routes map[netip.Prefix]*Route) []*Route {
+	if len(routes) == 0 {
+		return nil
+	}
+	sorted := make([]*Route, 0, len(routes))
+	for _, rt := range routes {
+		sorted = append(sorted, rt)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i].Prefix, sorted[j].Prefix
+		if c := a.Addr().Compare(b.Addr()); c != 0 {
+			return c < 0
+		}
+		return a.Bits() < b.Bits()
+	})
+
+	return sorted
+}
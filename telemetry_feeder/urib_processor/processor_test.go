@@ -0,0 +1,226 @@
+package urib_processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/sbezverk/tools/telemetry_feeder/decoder"
+	"github.com/sbezverk/tools/telemetry_feeder/proto/telemetry"
+	"github.com/sbezverk/tools/telemetry_feeder/proto/urib"
+	"google.golang.org/protobuf/proto"
+)
+
+const uribPath = "urib"
+
+// route returns an event of the prefix in vrf, the next hops are given by their addresses.
+func route(event urib.UribEventType, vrf, prefix string, nextHops ...string) *urib.NxL3RouteProto {
+	rt := &urib.NxL3RouteProto{VrfName: vrf, EventType: event}
+	if prefix != "" {
+		p := netip.MustParsePrefix(prefix)
+		rt.Address, rt.MaskLen = p.Addr().String(), uint32(p.Bits())
+	}
+	for _, nh := range nextHops {
+		rt.NextHop = append(rt.NextHop, &urib.NxL3NextHopProto{Address: nh, OutInterface: "Ethernet1/1", Preference: 110, Metric: 41})
+	}
+	rt.L3NextHopCount = uint32(len(rt.NextHop))
+
+	return rt
+}
+
+func record(t *testing.T, node string, routes ...*urib.NxL3RouteProto) *decoder.Record {
+	t.Helper()
+	msg := &telemetry.Telemetry{
+		NodeId:       &telemetry.Telemetry_NodeIdStr{NodeIdStr: node},
+		EncodingPath: uribPath,
+		DataGpb:      &telemetry.TelemetryGPBTable{},
+	}
+	for _, rt := range routes {
+		content, err := proto.Marshal(rt)
+		if err != nil {
+			t.Fatalf("failed to marshal route: %v", err)
+		}
+		msg.DataGpb.Row = append(msg.DataGpb.Row, &telemetry.TelemetryRowGPB{Timestamp: 1700000000000, Content: content})
+	}
+	r, err := decoder.FromTelemetry(msg)
+	if err != nil {
+		t.Fatalf("failed to build record: %v", err)
+	}
+
+	return r
+}
+
+func kinds(events []*Event) string {
+	var k []string
+	for _, e := range events {
+		s := string(e.Kind)
+		if e.Resync {
+			s += "(resync)"
+		}
+		k = append(k, s)
+	}
+	return fmt.Sprint(k)
+}
+
+const (
+	add           = urib.UribEventType_URIB_EVENT_TYPE_ADD
+	del           = urib.UribEventType_URIB_EVENT_TYPE_DELETE
+	update        = urib.UribEventType_URIB_EVENT_TYPE_UPDATE
+	downloadStart = urib.UribEventType_URIB_EVENT_TYPE_DOWNLOAD
+	downloadDone  = urib.UribEventType_URIB_EVENT_TYPE_DOWNLOAD_DONE
+)
+
+func TestProcess(t *testing.T) {
+	p, err := New(WithEncodingPaths(uribPath))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, step := range []struct {
+		routes []*urib.NxL3RouteProto
+		want   string
+	}{
+		{routes: []*urib.NxL3RouteProto{route(add, "default", "10.0.0.0/24", "192.168.0.1"), route(add, "", "10.0.1.0/24", "192.168.0.1")}, want: "[add add]"},
+		// An ADD of a known route replaces it, unchanged routes are not reported.
+		{routes: []*urib.NxL3RouteProto{route(add, "default", "10.0.0.0/24", "192.168.0.1", "192.168.0.2")}, want: "[update]"},
+		{routes: []*urib.NxL3RouteProto{route(update, "default", "10.0.0.0/24", "192.168.0.1", "192.168.0.2")}, want: "[]"},
+		{routes: []*urib.NxL3RouteProto{route(update, "blue", "10.0.0.0/24", "192.168.0.3")}, want: "[add]"},
+		{routes: []*urib.NxL3RouteProto{route(del, "default", "10.0.1.0/24"), route(del, "default", "10.0.9.0/24")}, want: "[delete]"},
+		{routes: []*urib.NxL3RouteProto{route(add, "default", ""), route(urib.UribEventType_URIB_EVENT_TYPE_NO_EVENT, "default", "10.0.2.0/24")}, want: "[]"},
+		// DOWNLOAD_DONE without a download in progress changes nothing.
+		{routes: []*urib.NxL3RouteProto{route(downloadDone, "", "")}, want: "[]"},
+	} {
+		if got := kinds(p.Process(record(t, "n1", step.routes...))); got != step.want {
+			t.Fatalf("step %d: expected %s, got %s", i, step.want, got)
+		}
+	}
+	// Records of other encoding paths are ignored.
+	other := record(t, "n1", route(add, "default", "10.0.3.0/24", "192.168.0.1"))
+	other.EncodingPath = "adj"
+	p.Process(other)
+
+	routes := p.Table(TableKey{NodeID: "n1", VRF: DefaultVRF})
+	if len(routes) != 1 || routes[0].Prefix != netip.MustParsePrefix("10.0.0.0/24") || len(routes[0].NextHops) != 2 ||
+		routes[0].NextHops[1].Address != netip.MustParseAddr("192.168.0.2") || routes[0].NextHops[1].Interface != "Ethernet1/1" {
+		t.Fatalf("unexpected routes %+v", routes)
+	}
+	s := p.Stats()
+	if s.AddsTotal != 3 || s.UpdatesTotal != 1 || s.DeletesTotal != 1 || s.UnknownDeletesTotal != 1 || s.InvalidTotal != 1 ||
+		s.IgnoredTotal != 2 || s.UnmatchedDownloadDoneTotal != 1 || s.TablesActive != 2 || s.RoutesActive != 2 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+
+	p.now = func() time.Time { return time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC) }
+	j, err := p.GetSnapshotJson()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(j, &snapshot); err != nil {
+		t.Fatalf("failed to unmarshal snapshot %s: %v", j, err)
+	}
+	if len(snapshot.Tables) != 2 || snapshot.Tables[0].VRF != "blue" || snapshot.Tables[1].Routes[0].NextHops[0].Metric != 41 {
+		t.Fatalf("unexpected snapshot %s", j)
+	}
+	if events := p.ResetNode("n1"); kinds(events) != "[delete delete]" || len(p.Snapshot().Tables) != 0 {
+		t.Fatalf("unexpected reset events %s", kinds(events))
+	}
+}
+
+func TestDownload(t *testing.T) {
+	p, err := New()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p.Process(record(t, "n1",
+		route(add, "default", "10.0.0.0/24", "192.168.0.1"),
+		route(add, "default", "10.0.1.0/24", "192.168.0.1"),
+		route(add, "default", "10.0.3.0/24", "192.168.0.1"),
+		route(add, "blue", "10.0.2.0/24", "192.168.0.1"),
+	))
+	p.Process(record(t, "n2", route(add, "default", "10.0.0.0/24", "192.168.0.1")))
+
+	// The download spans several messages, its routes do not show until it is done.
+	for i, step := range []struct {
+		routes []*urib.NxL3RouteProto
+		want   string
+	}{
+		{routes: []*urib.NxL3RouteProto{route(downloadStart, "", ""), route(downloadStart, "default", "10.0.0.0/24", "192.168.0.9")}, want: "[]"},
+		{routes: []*urib.NxL3RouteProto{route(downloadStart, "default", "10.0.1.0/24", "192.168.0.1"), route(downloadStart, "red", "10.0.4.0/24", "192.168.0.1")}, want: "[]"},
+		// Changes during the download apply right away and are not undone by it, they do not
+		// add their VRF to the download.
+		{routes: []*urib.NxL3RouteProto{route(add, "default", "10.0.5.0/24", "192.168.0.1"), route(del, "default", "10.0.1.0/24"),
+			route(add, "green", "10.0.6.0/24", "192.168.0.1")}, want: "[add delete add]"},
+		{routes: []*urib.NxL3RouteProto{route(downloadDone, "", "")}, want: "[update(resync) add(resync) delete(resync)]"},
+	} {
+		if got := kinds(p.Process(record(t, "n1", step.routes...))); got != step.want {
+			t.Fatalf("step %d: expected %s, got %s", i, step.want, got)
+		}
+	}
+
+	var prefixes []string
+	for _, tbl := range p.Snapshot().Tables {
+		for _, rt := range tbl.Routes {
+			prefixes = append(prefixes, tbl.NodeID+"/"+tbl.VRF+"/"+rt.Prefix.String())
+		}
+	}
+	// Only the VRFs the download carried are replaced, the blue and green routes are kept.
+	if got := fmt.Sprint(prefixes); got != "[n1/blue/10.0.2.0/24 n1/default/10.0.0.0/24 n1/default/10.0.5.0/24 n1/green/10.0.6.0/24 n1/red/10.0.4.0/24 n2/default/10.0.0.0/24]" {
+		t.Fatalf("unexpected routes after the download %s", got)
+	}
+
+	// A download which does not complete in time is abandoned.
+	p.opts.downloadTimeout = time.Minute
+	now := time.Now()
+	p.now = func() time.Time { return now }
+	p.Process(record(t, "n1", route(downloadStart, "", "")))
+	now = now.Add(2 * time.Minute)
+	if got := kinds(p.Process(record(t, "n1", route(downloadDone, "", "")))); got != "[]" {
+		t.Fatalf("unexpected events of the abandoned download %s", got)
+	}
+	s := p.Stats()
+	if s.DownloadsTotal != 1 || s.DownloadsAbandonedTotal != 1 || s.UnmatchedDownloadDoneTotal != 1 || s.StaleDeletesTotal != 1 ||
+		s.DownloadsActive != 0 || s.RoutesActive != 6 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+	if _, err := New(WithDownloadTimeout(0)); err == nil {
+		t.Fatal("expected a 0 download timeout to be rejected")
+	}
+}
+
+func TestLosslessStage(t *testing.T) {
+	in := make(chan *decoder.Record, 2)
+	s, err := NewStage(in, WithLosslessEvents())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer s.Stop()
+	// A download resynchronizing more routes than the events channel holds.
+	routes := []*urib.NxL3RouteProto{route(downloadStart, "", "")}
+	for i := 0; i < 1500; i++ {
+		routes = append(routes, route(downloadStart, "default", fmt.Sprintf("10.%d.%d.0/24", i/256, i%256), "192.168.0.1"))
+	}
+	in <- record(t, "n1", routes...)
+	in <- record(t, "n1", route(downloadDone, "", ""))
+	close(in)
+	if r, ok := <-s.GetRecords(); !ok || len(r.Rows) != len(routes) {
+		t.Fatal("expected the download record to be passed through")
+	}
+	select {
+	case <-s.GetRecords():
+		t.Fatal("the record was passed through before its events were read")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	n := 0
+	for range s.Events() {
+		n++
+	}
+	if n != len(routes)-1 || s.Processor().Stats().EventsDroppedTotal != 0 {
+		t.Fatalf("expected %d events and none dropped, got %d events and %d dropped", len(routes)-1, n, s.Processor().Stats().EventsDroppedTotal)
+	}
+	if _, ok := <-s.GetRecords(); !ok {
+		t.Fatal("expected the DOWNLOAD_DONE record to be passed through")
+	}
+}
@@ -0,0 +1,35 @@
+package urib_processor
+
+import (
+	"github.com/sbezverk/tools/telemetry_feeder/decoder"
+)
+
+// Stage is a pipeline stage passing through every record of a decoder while processing their
+// URIB events. Dropped events are counted in Stats.EventsDroppedTotal, Processor().Snapshot()
+// returns the tables to resynchronize from. WithLosslessEvents makes the stage wait for the
+// consumer instead.
+type Stage struct {
+	*decoder.Stage[*Event]
+	processor *Processor
+}
+
+// NewStage starts processing the records read from the in channel, the records and events
+// channels are closed when in is closed or Stop is called.
+func NewStage(in chan *decoder.Record, opts ...Option) (*Stage, error) {
+	processor, err := New(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Stage{
+		Stage: decoder.NewStage(in, processor.Process, decoder.StageConfig{
+			Lossless: processor.opts.losslessEvents,
+			Dropped:  &processor.eventsDropped,
+		}),
+		processor: processor,
+	}, nil
+}
+
+func (s *Stage) Processor() *Processor {
+	return s.processor
+}
@@ -0,0 +1,91 @@
+package telemetry_feeder
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultDrainTimeout bounds the graceful stop of a FeederV2 created without a drain timeout.
+const DefaultDrainTimeout = 10 * time.Second
+
+// FeederV2 is the context based feeder contract. A FeederV2 runs until the context it was
+// created with is done or the feeder stops on its own, it then stops gracefully and closes both
+// channels. Producer sessions, framing errors and queue overflows are reported on Events, so
+// tracking them does not depend on the error items of the feed.
+type FeederV2 interface {
+	Feed() <-chan *Feed
+	Events() <-chan *Event
+	GetStatsJson() ([]byte, error)
+	// Done is closed once the feeder has stopped and both channels are closed.
+	Done() <-chan struct{}
+	// Err returns the error of the graceful stop once Done is closed, nil when the consumer
+	// took every queued item.
+	Err() error
+}
+
+type feederV2 struct {
+	f      Feeder
+	events <-chan *Event
+	done   chan struct{}
+	err    error
+}
+
+var _ FeederV2 = &feederV2{}
+
+// NewV2 runs f until ctx is done, or until f stops on its own when it implements Terminator.
+// It then stops f with StopGracefully, giving the consumer drainTimeout, or DefaultDrainTimeout
+// when it is not positive, to take the queued items.
+//
+// Wrapping a started feeder is enough for ctx to control its whole lifetime: the constructors
+// only bind local sockets or open a file, and the gNMI and EMS feeders dial their targets in
+// workers which StopGracefully cancels right away. A ctx which is already done stops f at once.
+func NewV2(ctx context.Context, f Feeder, drainTimeout time.Duration) FeederV2 {
+	if drainTimeout <= 0 {
+		drainTimeout = DefaultDrainTimeout
+	}
+	v := &feederV2{
+		f:      f,
+		events: EventsOf(f),
+		done:   make(chan struct{}),
+	}
+	stopped := StoppedOf(f)
+	go func() {
+		defer close(v.done)
+		select {
+		case <-ctx.Done():
+		case <-stopped:
+		}
+		// A feeder which stopped on its own still hands over its queued items and closes the
+		// channels.
+		drainCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+		defer cancel()
+		v.err = f.StopGracefully(drainCtx)
+	}()
+
+	return v
+}
+
+func (v *feederV2) Feed() <-chan *Feed {
+	return v.f.GetFeed()
+}
+
+func (v *feederV2) Events() <-chan *Event {
+	return v.events
+}
+
+func (v *feederV2) GetStatsJson() ([]byte, error) {
+	return v.f.GetStatsJson()
+}
+
+func (v *feederV2) Done() <-chan struct{} {
+	return v.done
+}
+
+func (v *feederV2) Err() error {
+	select {
+	case <-v.done:
+		return v.err
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,128 @@
+package telemetry_feeder
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+type fakeFeeder struct {
+	feed   chan *Feed
+	events *EventQueue
+}
+
+// stoppingFeeder stops on its own once stopped is closed.
+type stoppingFeeder struct {
+	fakeFeeder
+	stopped chan struct{}
+}
+
+func (f *stoppingFeeder) Stopped() <-chan struct{} {
+	return f.stopped
+}
+
+func (f *fakeFeeder) GetStatsJson() ([]byte, error) {
+	return []byte(`{"transport":"fake"}`), nil
+}
+
+func (f *fakeFeeder) GetFeed() chan *Feed {
+	return f.feed
+}
+
+func (f *fakeFeeder) Events() <-chan *Event {
+	return f.events.Events()
+}
+
+func (f *fakeFeeder) Stop() {}
+
+func (f *fakeFeeder) StopGracefully(ctx context.Context) error {
+	close(f.feed)
+	f.events.Close()
+	return nil
+}
+
+func TestNewV2(t *testing.T) {
+	src := &fakeFeeder{feed: make(chan *Feed, 1), events: NewEventQueue(TransportTCP)}
+	ctx, cancel := context.WithCancel(context.Background())
+	f := NewV2(ctx, src, 0)
+	producer := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 57400}
+	src.events.ProducerConnected(producer)
+	src.feed <- &Feed{TelemetryMsg: []byte("data")}
+
+	if e := <-f.Events(); e.Type != EventProducerConnected || e.Transport != TransportTCP || e.ProducerAddr != producer {
+		t.Fatalf("unexpected event %+v", e)
+	}
+	if item := <-f.Feed(); string(item.TelemetryMsg) != "data" {
+		t.Fatalf("unexpected item %+v", item)
+	}
+	select {
+	case <-f.Done():
+		t.Fatal("feeder stopped before its context was canceled")
+	default:
+	}
+	cancel()
+	select {
+	case <-f.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("feeder did not stop after its context was canceled")
+	}
+	if _, ok := <-f.Feed(); ok {
+		t.Fatal("expected the feed channel to be closed")
+	}
+	if _, ok := <-f.Events(); ok {
+		t.Fatal("expected the events channel to be closed")
+	}
+	if err := f.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestNewV2StoppedOnItsOwn(t *testing.T) {
+	src := &stoppingFeeder{
+		fakeFeeder: fakeFeeder{feed: make(chan *Feed, 1), events: NewEventQueue(TransportUDP)},
+		stopped:    make(chan struct{}),
+	}
+	f := NewV2(context.Background(), src, time.Second)
+	src.feed <- &Feed{TelemetryMsg: []byte("data")}
+	close(src.stopped)
+	select {
+	case <-f.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("feeder did not stop after the wrapped feeder stopped")
+	}
+	// The items queued before the wrapped feeder stopped are still delivered.
+	if item, ok := <-f.Feed(); !ok || string(item.TelemetryMsg) != "data" {
+		t.Fatalf("unexpected item %+v", item)
+	}
+	if _, ok := <-f.Feed(); ok {
+		t.Fatal("expected the feed channel to be closed")
+	}
+	if _, ok := <-f.Events(); ok {
+		t.Fatal("expected the events channel to be closed")
+	}
+}
+
+func TestEventQueue(t *testing.T) {
+	q := NewEventQueue(TransportUDP)
+	for i := 0; i < eventQueueCapacity+2; i++ {
+		q.FramingError(nil, errors.New("bad frame"))
+	}
+	var s StatsSnapshot
+	q.FillStats(&s)
+	if s.EventsDroppedTotal != 2 {
+		t.Fatalf("expected 2 dropped events, got %d", s.EventsDroppedTotal)
+	}
+	q.Close()
+	q.Close()
+	// Emitting after Close is discarded rather than panicking.
+	q.ProducerDisconnected(nil, ErrFeederStopped)
+	n := 0
+	for range q.Events() {
+		n++
+	}
+	if n != eventQueueCapacity {
+		t.Fatalf("expected %d events, got %d", eventQueueCapacity, n)
+	}
+}
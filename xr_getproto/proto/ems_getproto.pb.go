@@ -132,6 +132,190 @@ func (x *GetProtoFileReply) GetErrors() string {
 	return ""
 }
 
+type QOSMarking struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Marking       uint32                 `protobuf:"varint,1,opt,name=marking,proto3" json:"marking,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QOSMarking) Reset() {
+	*x = QOSMarking{}
+	mi := &file_ems_getproto_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QOSMarking) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QOSMarking) ProtoMessage() {}
+
+func (x *QOSMarking) ProtoReflect() protoreflect.Message {
+	mi := &file_ems_getproto_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QOSMarking.ProtoReflect.Descriptor instead.
+func (*QOSMarking) Descriptor() ([]byte, []int) {
+	return file_ems_getproto_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *QOSMarking) GetMarking() uint32 {
+	if x != nil {
+		return x.Marking
+	}
+	return 0
+}
+
+// CreateSubsArgs requests the telemetry subscriptions configured on the router by name.
+// encode is 2 for GPB, 3 for self-describing GPB (GPB-KV) and 4 for JSON.
+type CreateSubsArgs struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ReqId         int64                  `protobuf:"varint,1,opt,name=ReqId,proto3" json:"ReqId,omitempty"`
+	Encode        int64                  `protobuf:"varint,2,opt,name=encode,proto3" json:"encode,omitempty"`
+	Subidstr      string                 `protobuf:"bytes,3,opt,name=subidstr,proto3" json:"subidstr,omitempty"`
+	Qos           *QOSMarking            `protobuf:"bytes,4,opt,name=qos,proto3" json:"qos,omitempty"`
+	Subscriptions []string               `protobuf:"bytes,5,rep,name=Subscriptions,proto3" json:"Subscriptions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateSubsArgs) Reset() {
+	*x = CreateSubsArgs{}
+	mi := &file_ems_getproto_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateSubsArgs) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateSubsArgs) ProtoMessage() {}
+
+func (x *CreateSubsArgs) ProtoReflect() protoreflect.Message {
+	mi := &file_ems_getproto_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateSubsArgs.ProtoReflect.Descriptor instead.
+func (*CreateSubsArgs) Descriptor() ([]byte, []int) {
+	return file_ems_getproto_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CreateSubsArgs) GetReqId() int64 {
+	if x != nil {
+		return x.ReqId
+	}
+	return 0
+}
+
+func (x *CreateSubsArgs) GetEncode() int64 {
+	if x != nil {
+		return x.Encode
+	}
+	return 0
+}
+
+func (x *CreateSubsArgs) GetSubidstr() string {
+	if x != nil {
+		return x.Subidstr
+	}
+	return ""
+}
+
+func (x *CreateSubsArgs) GetQos() *QOSMarking {
+	if x != nil {
+		return x.Qos
+	}
+	return nil
+}
+
+func (x *CreateSubsArgs) GetSubscriptions() []string {
+	if x != nil {
+		return x.Subscriptions
+	}
+	return nil
+}
+
+// CreateSubsReply carries a telemetry message in data, or the reason the subscription failed
+// in errors.
+type CreateSubsReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ResReqId      int64                  `protobuf:"varint,1,opt,name=ResReqId,proto3" json:"ResReqId,omitempty"`
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	Errors        string                 `protobuf:"bytes,3,opt,name=errors,proto3" json:"errors,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateSubsReply) Reset() {
+	*x = CreateSubsReply{}
+	mi := &file_ems_getproto_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateSubsReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateSubsReply) ProtoMessage() {}
+
+func (x *CreateSubsReply) ProtoReflect() protoreflect.Message {
+	mi := &file_ems_getproto_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateSubsReply.ProtoReflect.Descriptor instead.
+func (*CreateSubsReply) Descriptor() ([]byte, []int) {
+	return file_ems_getproto_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CreateSubsReply) GetResReqId() int64 {
+	if x != nil {
+		return x.ResReqId
+	}
+	return 0
+}
+
+func (x *CreateSubsReply) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *CreateSubsReply) GetErrors() string {
+	if x != nil {
+		return x.Errors
+	}
+	return ""
+}
+
 var File_ems_getproto_proto protoreflect.FileDescriptor
 
 var file_ems_getproto_proto_rawDesc = []byte{
@@ -149,20 +333,48 @@ var file_ems_getproto_proto_rawDesc = []byte{
 	0x74, 0x6f, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
 	0x0c, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x12, 0x16, 0x0a,
 	0x06, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x65,
-	0x72, 0x72, 0x6f, 0x72, 0x73, 0x32, 0x91, 0x01, 0x0a, 0x0e, 0x67, 0x52, 0x50, 0x43, 0x43, 0x6f,
-	0x6e, 0x66, 0x69, 0x67, 0x4f, 0x70, 0x65, 0x72, 0x12, 0x7f, 0x0a, 0x0c, 0x47, 0x65, 0x74, 0x50,
-	0x72, 0x6f, 0x74, 0x6f, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x34, 0x2e, 0x49, 0x4f, 0x53, 0x58, 0x52,
-	0x45, 0x78, 0x74, 0x65, 0x6e, 0x73, 0x69, 0x62, 0x6c, 0x65, 0x4d, 0x61, 0x6e, 0x61, 0x67, 0x61,
-	0x62, 0x69, 0x6c, 0x69, 0x74, 0x79, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x47, 0x65,
-	0x74, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x46, 0x69, 0x6c, 0x65, 0x41, 0x72, 0x67, 0x73, 0x1a, 0x35,
-	0x2e, 0x49, 0x4f, 0x53, 0x58, 0x52, 0x45, 0x78, 0x74, 0x65, 0x6e, 0x73, 0x69, 0x62, 0x6c, 0x65,
-	0x4d, 0x61, 0x6e, 0x61, 0x67, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x79, 0x53, 0x65, 0x72, 0x76,
-	0x69, 0x63, 0x65, 0x2e, 0x47, 0x65, 0x74, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x46, 0x69, 0x6c, 0x65,
-	0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x30, 0x01, 0x42, 0x39, 0x5a, 0x37, 0x67, 0x69, 0x74,
-	0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x73, 0x62, 0x65, 0x7a, 0x76, 0x65, 0x72, 0x6b,
-	0x2f, 0x74, 0x6f, 0x6f, 0x6c, 0x73, 0x2f, 0x78, 0x72, 0x5f, 0x67, 0x65, 0x74, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x3b, 0x65, 0x6d, 0x73, 0x67, 0x65, 0x74, 0x70,
-	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x72, 0x72, 0x6f, 0x72, 0x73, 0x22, 0x26, 0x0a, 0x0a, 0x51, 0x4f, 0x53, 0x4d, 0x61, 0x72, 0x6b,
+	0x69, 0x6e, 0x67, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x61, 0x72, 0x6b, 0x69, 0x6e, 0x67, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x6d, 0x61, 0x72, 0x6b, 0x69, 0x6e, 0x67, 0x22, 0xc2, 0x01,
+	0x0a, 0x0e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x53, 0x75, 0x62, 0x73, 0x41, 0x72, 0x67, 0x73,
+	0x12, 0x14, 0x0a, 0x05, 0x52, 0x65, 0x71, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x05, 0x52, 0x65, 0x71, 0x49, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x1a,
+	0x0a, 0x08, 0x73, 0x75, 0x62, 0x69, 0x64, 0x73, 0x74, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x08, 0x73, 0x75, 0x62, 0x69, 0x64, 0x73, 0x74, 0x72, 0x12, 0x40, 0x0a, 0x03, 0x71, 0x6f,
+	0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x2e, 0x2e, 0x49, 0x4f, 0x53, 0x58, 0x52, 0x45,
+	0x78, 0x74, 0x65, 0x6e, 0x73, 0x69, 0x62, 0x6c, 0x65, 0x4d, 0x61, 0x6e, 0x61, 0x67, 0x61, 0x62,
+	0x69, 0x6c, 0x69, 0x74, 0x79, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x51, 0x4f, 0x53,
+	0x4d, 0x61, 0x72, 0x6b, 0x69, 0x6e, 0x67, 0x52, 0x03, 0x71, 0x6f, 0x73, 0x12, 0x24, 0x0a, 0x0d,
+	0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x05, 0x20,
+	0x03, 0x28, 0x09, 0x52, 0x0d, 0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f,
+	0x6e, 0x73, 0x22, 0x59, 0x0a, 0x0f, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x53, 0x75, 0x62, 0x73,
+	0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x1a, 0x0a, 0x08, 0x52, 0x65, 0x73, 0x52, 0x65, 0x71, 0x49,
+	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x52, 0x65, 0x73, 0x52, 0x65, 0x71, 0x49,
+	0x64, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x04, 0x64, 0x61, 0x74, 0x61, 0x12, 0x16, 0x0a, 0x06, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x32, 0x8c, 0x02,
+	0x0a, 0x0e, 0x67, 0x52, 0x50, 0x43, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x4f, 0x70, 0x65, 0x72,
+	0x12, 0x7f, 0x0a, 0x0c, 0x47, 0x65, 0x74, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x46, 0x69, 0x6c, 0x65,
+	0x12, 0x34, 0x2e, 0x49, 0x4f, 0x53, 0x58, 0x52, 0x45, 0x78, 0x74, 0x65, 0x6e, 0x73, 0x69, 0x62,
+	0x6c, 0x65, 0x4d, 0x61, 0x6e, 0x61, 0x67, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x79, 0x53, 0x65,
+	0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x47, 0x65, 0x74, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x46, 0x69,
+	0x6c, 0x65, 0x41, 0x72, 0x67, 0x73, 0x1a, 0x35, 0x2e, 0x49, 0x4f, 0x53, 0x58, 0x52, 0x45, 0x78,
+	0x74, 0x65, 0x6e, 0x73, 0x69, 0x62, 0x6c, 0x65, 0x4d, 0x61, 0x6e, 0x61, 0x67, 0x61, 0x62, 0x69,
+	0x6c, 0x69, 0x74, 0x79, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x47, 0x65, 0x74, 0x50,
+	0x72, 0x6f, 0x74, 0x6f, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x30,
+	0x01, 0x12, 0x79, 0x0a, 0x0a, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x53, 0x75, 0x62, 0x73, 0x12,
+	0x32, 0x2e, 0x49, 0x4f, 0x53, 0x58, 0x52, 0x45, 0x78, 0x74, 0x65, 0x6e, 0x73, 0x69, 0x62, 0x6c,
+	0x65, 0x4d, 0x61, 0x6e, 0x61, 0x67, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x79, 0x53, 0x65, 0x72,
+	0x76, 0x69, 0x63, 0x65, 0x2e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x53, 0x75, 0x62, 0x73, 0x41,
+	0x72, 0x67, 0x73, 0x1a, 0x33, 0x2e, 0x49, 0x4f, 0x53, 0x58, 0x52, 0x45, 0x78, 0x74, 0x65, 0x6e,
+	0x73, 0x69, 0x62, 0x6c, 0x65, 0x4d, 0x61, 0x6e, 0x61, 0x67, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74,
+	0x79, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x53,
+	0x75, 0x62, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x30, 0x01, 0x42, 0x39, 0x5a, 0x37,
+	0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x73, 0x62, 0x65, 0x7a, 0x76,
+	0x65, 0x72, 0x6b, 0x2f, 0x74, 0x6f, 0x6f, 0x6c, 0x73, 0x2f, 0x78, 0x72, 0x5f, 0x67, 0x65, 0x74,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x3b, 0x65, 0x6d, 0x73, 0x67,
+	0x65, 0x74, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -177,19 +389,25 @@ func file_ems_getproto_proto_rawDescGZIP() []byte {
 	return file_ems_getproto_proto_rawDescData
 }
 
-var file_ems_getproto_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_ems_getproto_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
 var file_ems_getproto_proto_goTypes = []any{
 	(*GetProtoFileArgs)(nil),  // 0: IOSXRExtensibleManagabilityService.GetProtoFileArgs
 	(*GetProtoFileReply)(nil), // 1: IOSXRExtensibleManagabilityService.GetProtoFileReply
+	(*QOSMarking)(nil),        // 2: IOSXRExtensibleManagabilityService.QOSMarking
+	(*CreateSubsArgs)(nil),    // 3: IOSXRExtensibleManagabilityService.CreateSubsArgs
+	(*CreateSubsReply)(nil),   // 4: IOSXRExtensibleManagabilityService.CreateSubsReply
 }
 var file_ems_getproto_proto_depIdxs = []int32{
-	0, // 0: IOSXRExtensibleManagabilityService.gRPCConfigOper.GetProtoFile:input_type -> IOSXRExtensibleManagabilityService.GetProtoFileArgs
-	1, // 1: IOSXRExtensibleManagabilityService.gRPCConfigOper.GetProtoFile:output_type -> IOSXRExtensibleManagabilityService.GetProtoFileReply
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	2, // 0: IOSXRExtensibleManagabilityService.CreateSubsArgs.qos:type_name -> IOSXRExtensibleManagabilityService.QOSMarking
+	0, // 1: IOSXRExtensibleManagabilityService.gRPCConfigOper.GetProtoFile:input_type -> IOSXRExtensibleManagabilityService.GetProtoFileArgs
+	3, // 2: IOSXRExtensibleManagabilityService.gRPCConfigOper.CreateSubs:input_type -> IOSXRExtensibleManagabilityService.CreateSubsArgs
+	1, // 3: IOSXRExtensibleManagabilityService.gRPCConfigOper.GetProtoFile:output_type -> IOSXRExtensibleManagabilityService.GetProtoFileReply
+	4, // 4: IOSXRExtensibleManagabilityService.gRPCConfigOper.CreateSubs:output_type -> IOSXRExtensibleManagabilityService.CreateSubsReply
+	3, // [3:5] is the sub-list for method output_type
+	1, // [1:3] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
 }
 
 func init() { file_ems_getproto_proto_init() }
@@ -203,7 +421,7 @@ func file_ems_getproto_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_ems_getproto_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   5,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -4,14 +4,20 @@ import (
 	"context"
 
 	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
 )
 
 const _ = grpc.SupportPackageIsVersion7
 
-const GRPCConfigOper_GetProtoFile_FullMethodName = "/IOSXRExtensibleManagabilityService.gRPCConfigOper/GetProtoFile"
+const (
+	GRPCConfigOper_GetProtoFile_FullMethodName = "/IOSXRExtensibleManagabilityService.gRPCConfigOper/GetProtoFile"
+	GRPCConfigOper_CreateSubs_FullMethodName   = "/IOSXRExtensibleManagabilityService.gRPCConfigOper/CreateSubs"
+)
 
 type GRPCConfigOperClient interface {
 	GetProtoFile(ctx context.Context, in *GetProtoFileArgs, opts ...grpc.CallOption) (GRPCConfigOper_GetProtoFileClient, error)
+	CreateSubs(ctx context.Context, in *CreateSubsArgs, opts ...grpc.CallOption) (GRPCConfigOper_CreateSubsClient, error)
 }
 
 type gRPCConfigOperClient struct {
@@ -54,13 +60,115 @@ func (x *gRPCConfigOperGetProtoFileClient) Recv() (*GetProtoFileReply, error) {
 	return m, nil
 }
 
+func (c *gRPCConfigOperClient) CreateSubs(ctx context.Context, in *CreateSubsArgs, opts ...grpc.CallOption) (GRPCConfigOper_CreateSubsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &GRPCConfigOper_ServiceDesc.Streams[1], GRPCConfigOper_CreateSubs_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &gRPCConfigOperCreateSubsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type GRPCConfigOper_CreateSubsClient interface {
+	Recv() (*CreateSubsReply, error)
+	grpc.ClientStream
+}
+
+type gRPCConfigOperCreateSubsClient struct {
+	grpc.ClientStream
+}
+
+func (x *gRPCConfigOperCreateSubsClient) Recv() (*CreateSubsReply, error) {
+	m := new(CreateSubsReply)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// GRPCConfigOperServer is the server API of the service, it is implemented by fake routers in
+// tests.
+type GRPCConfigOperServer interface {
+	GetProtoFile(*GetProtoFileArgs, GRPCConfigOper_GetProtoFileServer) error
+	CreateSubs(*CreateSubsArgs, GRPCConfigOper_CreateSubsServer) error
+}
+
+// UnimplementedGRPCConfigOperServer can be embedded to implement only some of the RPCs.
+type UnimplementedGRPCConfigOperServer struct{}
+
+func (UnimplementedGRPCConfigOperServer) GetProtoFile(*GetProtoFileArgs, GRPCConfigOper_GetProtoFileServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetProtoFile not implemented")
+}
+
+func (UnimplementedGRPCConfigOperServer) CreateSubs(*CreateSubsArgs, GRPCConfigOper_CreateSubsServer) error {
+	return status.Errorf(codes.Unimplemented, "method CreateSubs not implemented")
+}
+
+func RegisterGRPCConfigOperServer(s grpc.ServiceRegistrar, srv GRPCConfigOperServer) {
+	s.RegisterService(&GRPCConfigOper_ServiceDesc, srv)
+}
+
+func _GRPCConfigOper_GetProtoFile_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetProtoFileArgs)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GRPCConfigOperServer).GetProtoFile(m, &gRPCConfigOperGetProtoFileServer{stream})
+}
+
+type GRPCConfigOper_GetProtoFileServer interface {
+	Send(*GetProtoFileReply) error
+	grpc.ServerStream
+}
+
+type gRPCConfigOperGetProtoFileServer struct {
+	grpc.ServerStream
+}
+
+func (x *gRPCConfigOperGetProtoFileServer) Send(m *GetProtoFileReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _GRPCConfigOper_CreateSubs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(CreateSubsArgs)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GRPCConfigOperServer).CreateSubs(m, &gRPCConfigOperCreateSubsServer{stream})
+}
+
+type GRPCConfigOper_CreateSubsServer interface {
+	Send(*CreateSubsReply) error
+	grpc.ServerStream
+}
+
+type gRPCConfigOperCreateSubsServer struct {
+	grpc.ServerStream
+}
+
+func (x *gRPCConfigOperCreateSubsServer) Send(m *CreateSubsReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 var GRPCConfigOper_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "IOSXRExtensibleManagabilityService.gRPCConfigOper",
-	HandlerType: (*interface{})(nil),
+	HandlerType: (*GRPCConfigOperServer)(nil),
 	Methods:     []grpc.MethodDesc{},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "GetProtoFile",
+			Handler:       _GRPCConfigOper_GetProtoFile_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "CreateSubs",
+			Handler:       _GRPCConfigOper_CreateSubs_Handler,
 			ServerStreams: true,
 		},
 	},